@@ -38,105 +38,120 @@ func (e *executor) Bind(
 	log.Info("============================================================")
 	log.Infof("ServiceInstance.ID: %s", instance.Spec.ID)
 	log.Infof("ServiceInstance.Name: %v", instance.Spec.FQName)
-	log.Infof("ServiceInstance.Image: %s", instance.Spec.Image)
+	log.Infof("ServiceInstance.Image: %s", instance.Image())
 	log.Infof("ServiceInstance.Description: %s", instance.Spec.Description)
 	log.Infof("============================================================")
 
 	go func() {
 		e.actionStarted()
-		// Create namespace name that will be used to generate a name.
-		ns := fmt.Sprintf("%s-%.4s-", instance.Spec.FQName, bindAction)
-		// Determine if we should be using the context namespace from the
-		// executor config.
-		if e.skipCreateNS {
-			ns = instance.Context.Namespace
-		}
-		// Create the podname
-		pn := fmt.Sprintf("bundle-%s", uuid.New())
-		targets := []string{instance.Context.Namespace}
-		labels := map[string]string{
-			"bundle-fqname":   instance.Spec.FQName,
-			"bundle-action":   bindAction,
-			"bundle-pod-name": pn,
-		}
-
-		serviceAccount, namespace, err := runtime.Provider.CreateSandbox(pn, ns, targets, clusterConfig.SandboxRole, labels)
-		ec := runtime.ExecutionContext{
-			BundleName: pn,
-			Targets:    targets,
-			Metadata:   labels,
-			Action:     bindAction,
-			Image:      instance.Spec.Image,
-			Account:    serviceAccount,
-			Location:   namespace,
-		}
+		snapshot := e.snapshotState(instance, bindAction)
+		err := e.bind(instance, parameters, bindingID)
 		if err != nil {
-			log.Errorf("Problem executing bundle create sandbox [%s] bind", ec.BundleName)
-			e.actionFinishedWithError(err)
-			return
-		}
-		ec, err = e.executeApb(ec, instance, parameters)
-		defer runtime.Provider.DestroySandbox(
-			ec.BundleName,
-			ec.Location,
-			ec.Targets,
-			clusterConfig.Namespace,
-			clusterConfig.KeepNamespace,
-			clusterConfig.KeepNamespaceOnError,
-		)
-		if err != nil {
-			log.Errorf("Problem executing bundle [%s] bind", ec.BundleName)
-			e.actionFinishedWithError(err)
+			e.actionFinishedWithError(e.rollbackState(snapshot, err))
 			return
 		}
+		e.actionFinishedWithSuccess()
+	}()
 
-		if instance.Spec.Runtime >= 2 {
-			err := runtime.Provider.WatchRunningBundle(ec.BundleName, ec.Location, e.updateDescription)
-			if err != nil {
-				log.Errorf("Bind action failed - %v", err)
-				e.actionFinishedWithError(err)
-				return
-			}
-		}
+	return e.statusChan
+}
 
-		// pod execution is complete so transfer state back
-		err = e.stateManager.CopyState(
-			ec.BundleName,
-			e.stateManager.MasterName(instance.ID.String()),
-			ec.Location, e.stateManager.MasterNamespace())
-		if err != nil {
-			e.actionFinishedWithError(err)
-			return
-		}
+func (e *executor) bind(instance *ServiceInstance, parameters *Parameters, bindingID string) error {
+	// Create namespace name that will be used to generate a name.
+	ns := fmt.Sprintf("%s-%.4s-", instance.Spec.FQName, bindAction)
+	// Determine if we should be using the context namespace from the
+	// executor config.
+	if e.skipCreateNS {
+		ns = instance.Context.Namespace
+	}
+	// Create the podname
+	pn := fmt.Sprintf("bundle-%s", uuid.New())
+	targets := instance.Context.Targets()
+	labels := map[string]string{
+		"bundle-fqname":      instance.Spec.FQName,
+		"bundle-action":      bindAction,
+		"bundle-pod-name":    pn,
+		"bundle-instance-id": instance.ID.String(),
+		"bundle-spec-id":     instance.Spec.ID,
+	}
 
-		credBytes, err := runtime.Provider.ExtractCredentials(
-			ec.BundleName,
-			ec.Location,
-			instance.Spec.Runtime,
-		)
-		if err != nil {
-			log.Errorf("apb::bind error occurred - %v", err)
-			e.actionFinishedWithError(err)
-			return
-		}
+	serviceAccount, namespace, err := runtime.Provider.CreateSandbox(pn, ns, targets, clusterConfig.SandboxRole, labels)
+	ec := runtime.ExecutionContext{
+		BundleName: pn,
+		Targets:    targets,
+		Metadata:   labels,
+		Action:     bindAction,
+		Image:      instance.Image(),
+		Account:    serviceAccount,
+		Location:   namespace,
+	}
+	if err != nil {
+		log.Errorf("Problem executing bundle create sandbox [%s] bind", ec.BundleName)
+		return err
+	}
+	ec, err = e.executeApb(ec, instance, parameters)
+	defer e.destroySandbox(ec)
+	if err != nil {
+		log.Errorf("Problem executing bundle [%s] bind", ec.BundleName)
+		return err
+	}
 
-		creds, err := buildExtractedCredentials(credBytes)
+	if instance.Spec.Runtime >= 2 {
+		err := runtime.Provider.WatchRunningBundle(ec.BundleName, ec.Location, e.updateDescription)
 		if err != nil {
-			log.Errorf("apb::bind error occurred - %v", err)
-			e.actionFinishedWithError(err)
-			return
+			log.Errorf("Bind action failed - %v", err)
+			return err
 		}
+	}
+
+	// pod execution is complete so transfer state back
+	err = e.stateManager.CopyState(
+		ec.BundleName,
+		e.stateManager.MasterName(instance.ID.String()),
+		ec.Location, e.stateManager.MasterNamespace())
+	if err != nil {
+		return err
+	}
+
+	credBytes, err := runtime.Provider.ExtractCredentials(
+		ec.BundleName,
+		ec.Location,
+		instance.Spec.Runtime,
+	)
+	if err != nil {
+		log.Errorf("apb::bind error occurred - %v", err)
+		return err
+	}
 
-		labels = map[string]string{"bundleAction": "bind", "bundleName": instance.Spec.FQName}
-		err = runtime.Provider.CreateExtractedCredential(bindingID, clusterConfig.Namespace, creds.Credentials, labels)
+	creds, err := buildExtractedCredentials(credBytes)
+	if err != nil {
+		log.Errorf("apb::bind error occurred - %v", err)
+		return err
+	}
+	if err := validateExtractedCredentials(instance, parameters, creds); err != nil {
+		return err
+	}
+
+	labels = map[string]string{
+		CredentialsActionLabel:   bindAction,
+		CredentialsBundleLabel:   instance.Spec.FQName,
+		CredentialsInstanceLabel: instance.ID.String(),
+	}
+	err = runtime.Provider.CreateExtractedCredential(bindingID, clusterConfig.Namespace, creds.Credentials, labels)
+	if err != nil {
+		log.Errorf("apb::%v error occurred - %v", executionMethodProvision, err)
+		return err
+	}
+	if e.bindProjection != nil {
+		err = PublishProjection(*e.bindProjection, creds, bindingID, instance.Context.Namespace, labels)
 		if err != nil {
-			log.Errorf("apb::%v error occurred - %v", executionMethodProvision, err)
-			e.actionFinishedWithError(err)
-			return
+			// The binding failed, do not leave its credentials behind.
+			if delErr := runtime.Provider.DeleteExtractedCredential(bindingID, clusterConfig.Namespace); delErr != nil {
+				log.Errorf("unable to remove the extracted credentials of %v - %v", bindingID, delErr)
+			}
+			return err
 		}
-		e.extractedCredentials = creds
-		e.actionFinishedWithSuccess()
-	}()
-
-	return e.statusChan
+	}
+	e.extractedCredentials = creds
+	return nil
 }
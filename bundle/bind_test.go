@@ -49,7 +49,7 @@ func mockCommonBind(rt *runtime.MockRuntime, e Executor) {
 	).Return(nil)
 	rt.On("DestroySandbox",
 		mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything)
+		mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	rt.On("ExtractCredentials",
 		mock.Anything, mock.Anything, mock.Anything,
 	).Return([]byte(`{"test": "testingcreds"}`), nil)
@@ -107,8 +107,9 @@ func TestBind(t *testing.T) {
 				rt.On("CreateExtractedCredential", bID.String(), mock.Anything,
 					map[string]interface{}{"test": "testingcreds"},
 					map[string]string{
-						"bundleAction": "bind",
-						"bundleName":   "new-fq-name",
+						"bundleAction":   "bind",
+						"bundleName":     "new-fq-name",
+						"bundleInstance": u.String(),
 					},
 				).Return(nil)
 			},
@@ -155,8 +156,9 @@ func TestBind(t *testing.T) {
 				rt.On("CreateExtractedCredential", bID.String(), mock.Anything,
 					map[string]interface{}{"test": "testingcreds"},
 					map[string]string{
-						"bundleAction": "bind",
-						"bundleName":   "new-fq-name",
+						"bundleAction":   "bind",
+						"bundleName":     "new-fq-name",
+						"bundleInstance": u.String(),
 					},
 				).Return(nil)
 			},
@@ -237,6 +239,7 @@ func TestBindFailure(t *testing.T) {
 		extractedCreds  *ExtractedCredentials
 		addExpectations func(rt *runtime.MockRuntime, e Executor)
 		validateMessage func([]StatusMessage) bool
+		verify          func(t *testing.T, rt *runtime.MockRuntime)
 	}{
 		{
 			name:   "bind failed to copystate",
@@ -264,7 +267,7 @@ func TestBindFailure(t *testing.T) {
 
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 				rt.On("CopyState",
 					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
@@ -346,7 +349,7 @@ func TestBindFailure(t *testing.T) {
 				).Return(nil)
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 				b := make([]byte, 1)
 				rt.On("ExtractCredentials",
@@ -408,6 +411,51 @@ func TestBindFailure(t *testing.T) {
 			},
 			extractedCreds: nil,
 		},
+		{
+			name: "bind fails to publish the projection",
+			config: ExecutorConfig{
+				BindProjection: &Projection{Format: ProjectionServiceBinding},
+			},
+			rt: *new(runtime.MockRuntime),
+			si: ServiceInstance{
+				ID:         u,
+				Spec:       spec,
+				Context:    ctx,
+				Parameters: &Parameters{"test-param": true},
+			},
+			bindingID: bID.String(),
+			addExpectations: func(rt *runtime.MockRuntime, e Executor) {
+				mockExecuteApb(rt, e, u.String())
+				mockCommonBind(rt, e)
+				rt.On("CreateSandbox",
+					mock.Anything, mock.Anything, []string{"target"},
+					mock.Anything, mock.Anything,
+				).Return("service-account-1", "location", nil)
+
+				rt.On("CreateExtractedCredential", bID.String(), mock.Anything,
+					mock.Anything, mock.Anything,
+				).Return(nil)
+				rt.On("DeleteExtractedCredential", bID.String(), mock.Anything).Return(nil)
+			},
+			validateMessage: func(m []StatusMessage) bool {
+				if len(m) != 2 {
+					return false
+				}
+				first := m[0]
+				second := m[1]
+				if first.State != StateInProgress {
+					return false
+				}
+				if second.State != StateFailed {
+					return false
+				}
+				return true
+			},
+			verify: func(t *testing.T, rt *runtime.MockRuntime) {
+				rt.AssertCalled(t, "DeleteExtractedCredential", bID.String(), mock.Anything)
+			},
+			extractedCreds: nil,
+		},
 		{
 			name:   "watch pod fails",
 			config: ExecutorConfig{},
@@ -434,7 +482,7 @@ func TestBindFailure(t *testing.T) {
 
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -477,7 +525,7 @@ func TestBindFailure(t *testing.T) {
 
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -517,6 +565,9 @@ func TestBindFailure(t *testing.T) {
 			if e.LastStatus().Error == nil {
 				t.Fatal("we expected the executor to have an error")
 			}
+			if tc.verify != nil {
+				tc.verify(t, &tc.rt)
+			}
 
 			// verify credentials
 			if tc.extractedCreds != nil {
@@ -0,0 +1,136 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"github.com/automationbroker/bundle-lib/runtime"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClonedFromKey - Key of the _apb_metadata that holds the id of the
+// instance a cloned instance was created from.
+const ClonedFromKey = "cloned_from"
+
+// CloneOptions - Options for cloning a service instance.
+type CloneOptions struct {
+	// CopyCredentials - copies the extracted credentials of the source
+	// instance to the new instance.
+	CopyCredentials bool
+}
+
+// Clone - will copy the state of the source instance to the instance and
+// run the apb with the provision action. The bundle finds the id of the
+// source instance under cloned_from in the _apb_metadata.
+func (e *executor) Clone(source *ServiceInstance, instance *ServiceInstance, options CloneOptions) <-chan StatusMessage {
+	log.Infof("============================================================")
+	log.Infof("                         CLONING                            ")
+	log.Infof("============================================================")
+	log.Infof("Source ID: %s", source.ID)
+	log.Infof("Spec.ID: %s", instance.Spec.ID)
+	log.Infof("Spec.Name: %s", instance.Spec.FQName)
+	log.Infof("Spec.Image: %s", instance.Spec.Image)
+	log.Infof("============================================================")
+
+	go func() {
+		e.actionStarted()
+		copiedCreds, err := e.cloneState(source, instance, options)
+		if err != nil {
+			log.Errorf("Clone APB error: %v", err)
+			e.removeClone(instance, copiedCreds)
+			e.actionFinishedWithError(err)
+			return
+		}
+		e.apbMetadata = map[string]interface{}{ClonedFromKey: source.ID.String()}
+		err = e.provisionOrUpdate(executionMethodProvision, instance)
+		if err != nil {
+			log.Errorf("Clone APB error: %v", err)
+			e.removeClone(instance, copiedCreds)
+			e.actionFinishedWithError(err)
+			return
+		}
+		if e.extractedCredentials != nil {
+			labels := map[string]string{CredentialsActionLabel: string(executionMethodProvision), CredentialsBundleLabel: instance.Spec.FQName}
+			save := runtime.Provider.CreateExtractedCredential
+			if copiedCreds {
+				save = runtime.Provider.UpdateExtractedCredential
+			}
+			err := save(instance.ID.String(), clusterConfig.Namespace, e.extractedCredentials.Credentials, labels)
+			if err != nil {
+				log.Errorf("apb::%v error occurred - %v", executionMethodProvision, err)
+				e.removeClone(instance, copiedCreds)
+				e.actionFinishedWithError(err)
+				return
+			}
+		}
+		e.actionFinishedWithSuccess()
+	}()
+	return e.statusChan
+}
+
+// cloneState - copies the master state and optionally the extracted
+// credentials of the source instance to the instance. Returns true when
+// credentials were copied.
+func (e *executor) cloneState(source *ServiceInstance, instance *ServiceInstance, options CloneOptions) (bool, error) {
+	sourceState := e.stateManager.MasterName(source.ID.String())
+	present, err := e.stateManager.StateIsPresent(sourceState)
+	if err != nil {
+		return false, err
+	}
+	if present {
+		log.Infof("state: copying state of service instance %s", source.ID)
+		err := e.stateManager.CopyState(
+			sourceState,
+			e.stateManager.MasterName(instance.ID.String()),
+			e.stateManager.MasterNamespace(),
+			e.stateManager.MasterNamespace(),
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+	if !options.CopyCredentials {
+		return false, nil
+	}
+	creds, err := runtime.Provider.GetExtractedCredential(source.ID.String(), clusterConfig.Namespace)
+	if err == runtime.ErrCredentialsNotFound {
+		log.Debugf("no extracted credentials to copy for service instance %s", source.ID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	labels := map[string]string{CredentialsActionLabel: string(executionMethodProvision), CredentialsBundleLabel: instance.Spec.FQName}
+	err = runtime.Provider.CreateExtractedCredential(instance.ID.String(), clusterConfig.Namespace, creds, labels)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// removeClone - removes the state and the credentials copied to an instance
+// whose clone failed.
+func (e *executor) removeClone(instance *ServiceInstance, copiedCreds bool) {
+	if err := e.stateManager.DeleteState(e.stateManager.MasterName(instance.ID.String())); err != nil {
+		log.Errorf("unable to remove the cloned state of %s - %v", instance.ID, err)
+	}
+	if !copiedCreds {
+		return
+	}
+	if err := runtime.Provider.DeleteExtractedCredential(instance.ID.String(), clusterConfig.Namespace); err != nil {
+		log.Errorf("unable to remove the cloned credentials of %s - %v", instance.ID, err)
+	}
+}
@@ -0,0 +1,132 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClone(t *testing.T) {
+	source := uuid.NewUUID()
+	u := uuid.NewUUID()
+	creds := map[string]interface{}{"user": "admin"}
+	clonedFrom := mock.MatchedBy(func(ec runtime.ExecutionContext) bool {
+		return strings.Contains(ec.ExtraVars, fmt.Sprintf(`"%s":"%s"`, ClonedFromKey, source.String()))
+	})
+	commonExpectations := func(rt *runtime.MockRuntime) {
+		rt.On("CreateSandbox", mock.Anything, mock.Anything, []string{"target"}, mock.Anything, mock.Anything).Return("service-account-1", "location", nil)
+		rt.On("GetRuntime").Return("kubernetes")
+		rt.On("CopySecretsToNamespace", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		rt.On("MasterName", source.String()).Return("source-master-name")
+		rt.On("MasterName", u.String()).Return("new-master-name")
+		rt.On("MasterNamespace").Return("new-masternamespace")
+		rt.On("StateIsPresent", "source-master-name").Return(true, nil)
+		rt.On("StateIsPresent", "new-master-name").Return(false, nil)
+		rt.On("CopyState", "source-master-name", "new-master-name", "new-masternamespace", "new-masternamespace").Return(nil)
+		rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	}
+	testCases := []struct {
+		name            string
+		options         CloneOptions
+		addExpectations func(rt *runtime.MockRuntime)
+		state           State
+		validate        func(t *testing.T, rt *runtime.MockRuntime)
+	}{
+		{
+			name:  "clone state",
+			state: StateSucceeded,
+			addExpectations: func(rt *runtime.MockRuntime) {
+				commonExpectations(rt)
+				rt.On("RunBundle", clonedFrom).Return(runtime.ExecutionContext{}, nil)
+			},
+			validate: func(t *testing.T, rt *runtime.MockRuntime) {
+				rt.AssertCalled(t, "CopyState", "source-master-name", "new-master-name", "new-masternamespace", "new-masternamespace")
+				rt.AssertNotCalled(t, "GetExtractedCredential", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "clone state and credentials",
+			options: CloneOptions{CopyCredentials: true},
+			state:   StateSucceeded,
+			addExpectations: func(rt *runtime.MockRuntime) {
+				commonExpectations(rt)
+				rt.On("GetExtractedCredential", source.String(), mock.Anything).Return(creds, nil)
+				rt.On("CreateExtractedCredential", u.String(), mock.Anything, creds, mock.Anything).Return(nil)
+				rt.On("RunBundle", clonedFrom).Return(runtime.ExecutionContext{}, nil)
+			},
+			validate: func(t *testing.T, rt *runtime.MockRuntime) {
+				rt.AssertCalled(t, "CreateExtractedCredential", u.String(), mock.Anything, creds, mock.Anything)
+			},
+		},
+		{
+			name:    "failed clone removes the copies",
+			options: CloneOptions{CopyCredentials: true},
+			state:   StateFailed,
+			addExpectations: func(rt *runtime.MockRuntime) {
+				commonExpectations(rt)
+				rt.On("GetExtractedCredential", source.String(), mock.Anything).Return(creds, nil)
+				rt.On("CreateExtractedCredential", u.String(), mock.Anything, creds, mock.Anything).Return(nil)
+				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, fmt.Errorf("failed"))
+				rt.On("DeleteState", "new-master-name").Return(nil)
+				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
+			},
+			validate: func(t *testing.T, rt *runtime.MockRuntime) {
+				rt.AssertCalled(t, "DeleteState", "new-master-name")
+				rt.AssertCalled(t, "DeleteExtractedCredential", u.String(), mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := new(runtime.MockRuntime)
+			runtime.Provider = rt
+			tc.addExpectations(rt)
+			e := NewExecutor(ExecutorConfig{})
+			si := &ServiceInstance{
+				ID: u,
+				Spec: &Spec{
+					ID:      "new-spec-id",
+					Image:   "new-image",
+					FQName:  "new-fq-name",
+					Runtime: 2,
+				},
+				Context: &Context{
+					Namespace: "target",
+					Platform:  "kubernetes",
+				},
+				Parameters: &Parameters{"test-param": true},
+			}
+			var last StatusMessage
+			for mess := range e.Clone(&ServiceInstance{ID: source}, si, tc.options) {
+				last = mess
+			}
+			if last.State != tc.state {
+				t.Fatalf("expected clone to end in %v got %#v", tc.state, last)
+			}
+			tc.validate(t, rt)
+		})
+	}
+}
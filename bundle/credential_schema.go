@@ -0,0 +1,183 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	schema "github.com/lestrrat/go-jsschema"
+	log "github.com/sirupsen/logrus"
+)
+
+// CredentialDescriptors - Returns the credentials declared for the plan, the
+// declarations of the plan override the declarations of the spec with the
+// same name. The spec declarations are returned when the plan is unknown.
+func (s *Spec) CredentialDescriptors(planName string) []CredentialDescriptor {
+	descriptors := []CredentialDescriptor{}
+	plan, _ := s.GetPlan(planName)
+	declared := map[string]bool{}
+	for _, cd := range plan.Credentials {
+		declared[cd.Name] = true
+	}
+	for _, cd := range s.Credentials {
+		if !declared[cd.Name] {
+			descriptors = append(descriptors, cd)
+		}
+	}
+	return append(descriptors, plan.Credentials...)
+}
+
+// ConvertSpecPlansToSchema - converts the plans of a spec to schema, the
+// credentials declared by the spec are added to every plan.
+func ConvertSpecPlansToSchema(spec *Spec) ([]SchemaPlan, error) {
+	plans := make([]Plan, len(spec.Plans))
+	for i, plan := range spec.Plans {
+		plans[i] = plan
+		plans[i].Credentials = spec.CredentialDescriptors(plan.Name)
+	}
+	return ConvertPlansToSchema(plans)
+}
+
+// ValidateCredentialDescriptors - Checks the credentials declared by the spec
+// and its plans. Every declaration needs a name that is unique within the
+// spec or plan and a known type.
+func (s *Spec) ValidateCredentialDescriptors() error {
+	if err := checkCredentialDescriptors(s.Credentials); err != nil {
+		return fmt.Errorf("invalid credentials of spec %v - %v", s.FQName, err)
+	}
+	for _, plan := range s.Plans {
+		if err := checkCredentialDescriptors(plan.Credentials); err != nil {
+			return fmt.Errorf("invalid credentials of plan %v - %v", plan.Name, err)
+		}
+	}
+	return nil
+}
+
+func checkCredentialDescriptors(descriptors []CredentialDescriptor) error {
+	names := map[string]bool{}
+	for _, cd := range descriptors {
+		if cd.Name == "" {
+			return fmt.Errorf("credential without a name")
+		}
+		if names[cd.Name] {
+			return fmt.Errorf("credential %v is declared more than once", cd.Name)
+		}
+		names[cd.Name] = true
+		if _, err := getType(cd.Type); err != nil {
+			return fmt.Errorf("credential %v - %v", cd.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateCredentials - checks the credentials returned by a bundle against
+// the declared credentials. Keys that are not declared are allowed.
+func validateCredentials(descriptors []CredentialDescriptor, creds map[string]interface{}) error {
+	missing := []string{}
+	for _, cd := range descriptors {
+		value, ok := creds[cd.Name]
+		if !ok {
+			if cd.Required {
+				missing = append(missing, cd.Name)
+			}
+			continue
+		}
+		t, err := getType(cd.Type)
+		if err != nil {
+			return fmt.Errorf("invalid declaration of credential %v - %v", cd.Name, err)
+		}
+		if !credentialHasType(value, t[0]) {
+			return fmt.Errorf("credential %v is not of type %v", cd.Name, cd.Type)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("bundle did not return the required credentials: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// credentialHasType - true when a value decoded from json has the type.
+func credentialHasType(value interface{}, t schema.PrimitiveType) bool {
+	switch t {
+	case schema.StringType:
+		_, ok := value.(string)
+		return ok
+	case schema.IntegerType:
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case schema.NumberType:
+		_, ok := value.(float64)
+		return ok
+	case schema.BooleanType:
+		_, ok := value.(bool)
+		return ok
+	case schema.ObjectType:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case schema.ArrayType:
+		_, ok := value.([]interface{})
+		return ok
+	case schema.NullType:
+		return value == nil
+	}
+	return false
+}
+
+// validateExtractedCredentials - validates the credentials extracted for an
+// instance against the declaration of the plan the instance was run with.
+// The plan is read from the parameters of the instance when parameters is
+// nil.
+func validateExtractedCredentials(instance *ServiceInstance, parameters *Parameters, creds *ExtractedCredentials) error {
+	if parameters == nil {
+		parameters = instance.Parameters
+	}
+	planName := ""
+	if parameters != nil {
+		planName, _ = (*parameters)[PlanKey].(string)
+	}
+	descriptors := instance.Spec.CredentialDescriptors(planName)
+	if len(descriptors) == 0 {
+		return nil
+	}
+	if err := validateCredentials(descriptors, creds.Credentials); err != nil {
+		log.Errorf("invalid credentials returned by %v - %v", instance.Spec.FQName, err)
+		log.Debugf("credentials returned by %v: %v", instance.Spec.FQName,
+			redactCredentials(descriptors, creds.Credentials))
+		return err
+	}
+	return nil
+}
+
+// redactCredentials - returns a copy of the credentials that is safe to log,
+// only the values of credentials declared without Sensitive are kept.
+func redactCredentials(descriptors []CredentialDescriptor, creds map[string]interface{}) map[string]interface{} {
+	visible := map[string]bool{}
+	for _, cd := range descriptors {
+		visible[cd.Name] = !cd.Sensitive
+	}
+	redacted := map[string]interface{}{}
+	for key, value := range creds {
+		if visible[key] {
+			redacted[key] = value
+		} else {
+			redacted[key] = "<redacted>"
+		}
+	}
+	return redacted
+}
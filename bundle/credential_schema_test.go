@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"testing"
+
+	ft "github.com/stretchr/testify/assert"
+)
+
+var credentialSpec = &Spec{
+	FQName: "mysql",
+	Credentials: []CredentialDescriptor{
+		{Name: "host", Type: "string", Required: true},
+		{Name: "port", Type: "int", Required: true},
+		{Name: "password", Type: "string", Sensitive: true},
+	},
+	Plans: []Plan{
+		{Name: "dev"},
+		{
+			Name: "prod",
+			Credentials: []CredentialDescriptor{
+				{Name: "password", Type: "string", Required: true, Sensitive: true},
+				{Name: "replicas", Type: "array"},
+			},
+		},
+	},
+}
+
+func TestCredentialDescriptors(t *testing.T) {
+	ft.Equal(t, credentialSpec.Credentials, credentialSpec.CredentialDescriptors("dev"))
+	ft.Equal(t, credentialSpec.Credentials, credentialSpec.CredentialDescriptors("unknown"))
+	ft.Equal(t, []CredentialDescriptor{
+		{Name: "host", Type: "string", Required: true},
+		{Name: "port", Type: "int", Required: true},
+		{Name: "password", Type: "string", Required: true, Sensitive: true},
+		{Name: "replicas", Type: "array"},
+	}, credentialSpec.CredentialDescriptors("prod"))
+}
+
+func TestValidateCredentials(t *testing.T) {
+	testCases := []struct {
+		name        string
+		plan        string
+		creds       map[string]interface{}
+		shouldError bool
+	}{
+		{
+			name:  "valid",
+			plan:  "dev",
+			creds: map[string]interface{}{"host": "db", "port": float64(3306), "extra": true},
+		},
+		{
+			name:        "missing required key",
+			plan:        "dev",
+			creds:       map[string]interface{}{"host": "db"},
+			shouldError: true,
+		},
+		{
+			name:        "required by the plan",
+			plan:        "prod",
+			creds:       map[string]interface{}{"host": "db", "port": float64(3306)},
+			shouldError: true,
+		},
+		{
+			name:        "integer with a fraction",
+			plan:        "dev",
+			creds:       map[string]interface{}{"host": "db", "port": 3306.5},
+			shouldError: true,
+		},
+		{
+			name:        "wrong type",
+			plan:        "prod",
+			creds:       map[string]interface{}{"host": "db", "port": float64(1), "password": "x", "replicas": "a,b"},
+			shouldError: true,
+		},
+		{
+			name:  "array",
+			plan:  "prod",
+			creds: map[string]interface{}{"host": "db", "port": float64(1), "password": "x", "replicas": []interface{}{"a"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instance := &ServiceInstance{Spec: credentialSpec}
+			err := validateExtractedCredentials(instance, &Parameters{PlanKey: tc.plan}, &ExtractedCredentials{Credentials: tc.creds})
+			if tc.shouldError {
+				ft.Error(t, err)
+			} else {
+				ft.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateCredentialsWithoutDeclaration(t *testing.T) {
+	instance := &ServiceInstance{Spec: &Spec{}}
+	err := validateExtractedCredentials(instance, nil, &ExtractedCredentials{Credentials: map[string]interface{}{"a": 1}})
+	ft.NoError(t, err)
+}
+
+func TestValidateCredentialDescriptors(t *testing.T) {
+	ft.NoError(t, credentialSpec.ValidateCredentialDescriptors())
+	testCases := []struct {
+		name string
+		spec *Spec
+	}{
+		{
+			name: "unknown type",
+			spec: &Spec{Credentials: []CredentialDescriptor{{Name: "host", Type: "hostname"}}},
+		},
+		{
+			name: "missing name",
+			spec: &Spec{Credentials: []CredentialDescriptor{{Type: "string"}}},
+		},
+		{
+			name: "declared twice in a plan",
+			spec: &Spec{Plans: []Plan{{
+				Name: "dev",
+				Credentials: []CredentialDescriptor{
+					{Name: "host", Type: "string"},
+					{Name: "host", Type: "string"},
+				},
+			}}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ft.Error(t, tc.spec.ValidateCredentialDescriptors())
+		})
+	}
+}
+
+func TestRedactCredentials(t *testing.T) {
+	creds := map[string]interface{}{"host": "db", "password": "secret", "token": "abc"}
+	ft.Equal(t, map[string]interface{}{
+		"host":     "db",
+		"password": "<redacted>",
+		"token":    "<redacted>",
+	}, redactCredentials(credentialSpec.Credentials, creds))
+}
+
+func TestConvertSpecPlansToSchema(t *testing.T) {
+	plans, err := ConvertSpecPlansToSchema(credentialSpec)
+	ft.NoError(t, err)
+	ft.Equal(t, 2, len(plans))
+	ft.Equal(t, credentialSpec.Credentials, plans[0].Credentials)
+	ft.Equal(t, 4, len(plans[1].Credentials))
+	ft.True(t, plans[1].Credentials[2].Sensitive)
+	// the plans of the spec are not changed
+	ft.Equal(t, 0, len(credentialSpec.Plans[0].Credentials))
+}
@@ -48,7 +48,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("DeleteState", "new-master-name").Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
@@ -96,7 +96,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("DeleteState", "new-master-name").Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
@@ -143,7 +143,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("DeleteState", "new-master-name").Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
 			},
@@ -197,7 +197,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					ex.updateDescription("dashboard url", "https://url.com")
 				}).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
 			},
@@ -250,7 +250,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("DeleteState", "new-master-name").Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
@@ -365,7 +365,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("GetRuntime").Return("kubernetes")
 				rt.On("CopySecretsToNamespace", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("unable to copy secrets"))
 				rt.On("MasterName", u.String()).Return("new-master-name")
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(nil)
 				rt.On("DeleteState", "new-master-name").Return(nil)
 			},
@@ -412,7 +412,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("DeleteState", "new-master-name").Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("unable to watch runnign bundle"))
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -457,7 +457,7 @@ func TestDeprovision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("DeleteState", "new-master-name").Return(fmt.Errorf("unable to delete state"))
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("DeleteExtractedCredential", u.String(), mock.Anything).Return(fmt.Errorf("unable to delete extracted cred"))
 			},
 			validateMessage: func(m []StatusMessage) bool {
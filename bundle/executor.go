@@ -19,8 +19,10 @@ package bundle
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/automationbroker/bundle-lib/runtime"
 	log "github.com/sirupsen/logrus"
@@ -32,6 +34,10 @@ type ExecutorAccessors interface {
 	LastStatus() StatusMessage
 	DashboardURL() string
 	ExtractedCredentials() *ExtractedCredentials
+	SandboxError() error
+	RotatedBindings() []string
+	Outputs() map[string]interface{}
+	Warnings() []string
 }
 
 // ExecutorAsync - Main interface used for running APBs asynchronously.
@@ -41,6 +47,8 @@ type ExecutorAsync interface {
 	Bind(instance *ServiceInstance, parameters *Parameters, bindingID string) <-chan StatusMessage
 	Unbind(instance *ServiceInstance, parameters *Parameters, bindingID string) <-chan StatusMessage
 	Update(instance *ServiceInstance) <-chan StatusMessage
+	Clone(source *ServiceInstance, instance *ServiceInstance, options CloneOptions) <-chan StatusMessage
+	Rotate(instance *ServiceInstance, parameters *Parameters, gracePeriod time.Duration) <-chan StatusMessage
 }
 
 //go:generate mockery -name=Executor -case=underscore -inpkg -note=Generated
@@ -60,6 +68,15 @@ type executor struct {
 	mutex                sync.Mutex
 	stateManager         runtime.StateManager
 	skipCreateNS         bool
+	rollbackOnError      bool
+	sandboxError         error
+	rotatedBindings      []string
+	bindProjection       *Projection
+	runtimeVersion       int
+	outputs              map[string]interface{}
+	warnings             []string
+	// apbMetadata - added to the _apb_metadata of the next bundle run
+	apbMetadata map[string]interface{}
 }
 
 // ExecutorConfig - configuration for the executor.
@@ -67,15 +84,25 @@ type ExecutorConfig struct {
 	// This will tell the executor to use the context namespace as the
 	// namespace for the bundle to be created in.
 	SkipCreateNS bool
+	// RollbackStateOnError will restore the state of the instance to the
+	// snapshot taken before the action when the action fails. This requires
+	// the runtime to keep a state history.
+	RollbackStateOnError bool
+	// BindProjection publishes the credentials of every binding in this
+	// shape as a secret named after the binding in the namespace of the
+	// instance. The secret is removed on unbind.
+	BindProjection *Projection
 }
 
 // NewExecutor - Creates a new Executor for running an APB.
 func NewExecutor(config ExecutorConfig) Executor {
 	return &executor{
-		statusChan:   make(chan StatusMessage),
-		lastStatus:   StatusMessage{State: StateNotYetStarted},
-		skipCreateNS: config.SkipCreateNS,
-		stateManager: runtime.Provider,
+		statusChan:      make(chan StatusMessage),
+		lastStatus:      StatusMessage{State: StateNotYetStarted},
+		skipCreateNS:    config.SkipCreateNS,
+		rollbackOnError: config.RollbackStateOnError,
+		bindProjection:  config.BindProjection,
+		stateManager:    runtime.Provider,
 	}
 }
 
@@ -100,6 +127,117 @@ func (e *executor) ExtractedCredentials() *ExtractedCredentials {
 	return e.extractedCredentials
 }
 
+// SandboxError - The error returned while tearing down the sandbox of the
+// action, the action itself may still have succeeded. Use
+// runtime.SandboxLeftovers to find the resources that were left behind.
+func (e *executor) SandboxError() error {
+	return e.sandboxError
+}
+
+// RotatedBindings - The bindings whose credentials were replaced by the
+// rotate action.
+func (e *executor) RotatedBindings() []string {
+	return e.rotatedBindings
+}
+
+// Outputs - Returns the outputs of a runtime 3 bundle that are not
+// credentials.
+func (e *executor) Outputs() map[string]interface{} {
+	return e.outputs
+}
+
+// Warnings - Returns the warnings of a runtime 3 bundle.
+func (e *executor) Warnings() []string {
+	return e.warnings
+}
+
+// collectResult - keeps the outputs and warnings of a runtime 3 bundle, the
+// result is lost once the sandbox is destroyed.
+func (e *executor) collectResult(ec runtime.ExecutionContext) {
+	result, err := runtime.Provider.GetBundleResult(ec.BundleName, ec.Location)
+	if err != nil {
+		log.Errorf("unable to get the result of bundle [%s] - %v", ec.BundleName, err)
+		return
+	}
+	if result == nil {
+		return
+	}
+	e.outputs = result.Outputs
+	e.warnings = result.Warnings
+}
+
+// destroySandbox - tears down the sandbox the action ran in. The teardown
+// error is kept so that it can be surfaced by SandboxError.
+func (e *executor) destroySandbox(ec runtime.ExecutionContext) {
+	if e.runtimeVersion >= runtime.ResultRuntimeVersion {
+		e.collectResult(ec)
+	}
+	err := runtime.Provider.DestroySandbox(
+		ec.BundleName,
+		ec.Location,
+		ec.Targets,
+		clusterConfig.Namespace,
+		clusterConfig.KeepNamespace,
+		clusterConfig.KeepNamespaceOnError,
+	)
+	if err != nil {
+		log.Errorf("unable to destroy sandbox [%s] - %v", ec.BundleName, err)
+		e.sandboxError = err
+	}
+}
+
+// stateSnapshot - the state of an instance before an action.
+type stateSnapshot struct {
+	name     string
+	snapshot *runtime.StateSnapshot
+}
+
+// snapshotState - takes a snapshot of the state of the instance before the
+// action when the state manager keeps a history. Returns nil when the state
+// can not be rolled back.
+func (e *executor) snapshotState(instance *ServiceInstance, action string) *stateSnapshot {
+	history, ok := e.stateManager.(runtime.StateHistory)
+	if !ok {
+		return nil
+	}
+	name := e.stateManager.MasterName(instance.ID.String())
+	snapshot, err := history.SnapshotState(name, action)
+	if err != nil {
+		if err != runtime.ErrStateHistoryDisabled {
+			log.Warningf("unable to snapshot state %s before %s - %v", name, action, err)
+		}
+		return nil
+	}
+	return &stateSnapshot{name: name, snapshot: snapshot}
+}
+
+// rollbackState - restores the state of the instance to the snapshot taken
+// before the failed action. When there was no state before the action the
+// state is removed. Returns the error of the action, with the error of the
+// rollback when the state could not be restored.
+func (e *executor) rollbackState(s *stateSnapshot, actionErr error) error {
+	if !e.rollbackOnError || s == nil {
+		return actionErr
+	}
+	history, ok := e.stateManager.(runtime.StateHistory)
+	if !ok {
+		return actionErr
+	}
+	var err error
+	if s.snapshot == nil {
+		log.Infof("rolling back state %s by removing it", s.name)
+		err = e.stateManager.DeleteState(s.name)
+	} else {
+		log.Infof("rolling back state %s to revision %d", s.name, s.snapshot.Revision)
+		err = history.RestoreStateSnapshot(s.name, s.snapshot.Revision)
+	}
+	if err != nil {
+		log.Errorf("unable to roll back state %s - %v", s.name, err)
+		return fmt.Errorf("%v; unable to roll back state %s - %v", actionErr, s.name, err)
+	}
+	return actionErr
+}
+
 func (e *executor) actionStarted() {
 	log.Debug("executor::actionStarted")
 	e.lastStatus.State = StateInProgress
@@ -164,6 +302,7 @@ func (e *executor) executeApb(
 	log.Debugf("action:[ %s ]", exContext.Action)
 	log.Debugf("pullPolicy:[ %s ]", clusterConfig.PullPolicy)
 	log.Debugf("role:[ %s ]", clusterConfig.SandboxRole)
+	e.runtimeVersion = instance.Spec.Runtime
 
 	// It's a critical error if a Namespace is not provided to the
 	// broker because its required to know where to execute the pods and
@@ -175,7 +314,7 @@ func (e *executor) executeApb(
 		return exContext, errors.New(errStr)
 	}
 
-	extraVars, err := createExtraVars(exContext.Targets[0], parameters)
+	extraVars, err := createExtraVars(exContext.Targets, e.apbMetadata, parameters)
 	if err != nil {
 		return exContext, err
 	}
@@ -185,6 +324,7 @@ func (e *executor) executeApb(
 	exContext.Secrets = secrets
 	exContext.ExtraVars = extraVars
 	exContext.Policy = clusterConfig.PullPolicy
+	exContext.Runtime = instance.Spec.Runtime
 
 	err = runtime.Provider.CopySecretsToNamespace(exContext, clusterConfig.Namespace, secrets)
 	if err != nil {
@@ -217,7 +357,7 @@ func (e *executor) executeApb(
 // TODO: Instead of putting namespace directly as a parameter, we should create a dictionary
 // of apb_metadata and put context and other variables in it so we don't pollute the user
 // parameter space.
-func createExtraVars(targetNamespace string, parameters *Parameters) (string, error) {
+func createExtraVars(targets []string, metadata map[string]interface{}, parameters *Parameters) (string, error) {
 	var paramsCopy Parameters
 	if parameters != nil && *parameters != nil {
 		paramsCopy = *parameters
@@ -225,9 +365,17 @@ func createExtraVars(targetNamespace string, parameters *Parameters) (string, er
 		paramsCopy = make(Parameters)
 	}
 
-	if targetNamespace != "" {
-		paramsCopy[NamespaceKey] = targetNamespace
+	if len(targets) > 0 && targets[0] != "" {
+		paramsCopy[NamespaceKey] = targets[0]
+	}
+	// The bundle finds every namespace it has been given access to in the
+	// metadata, the first one is the context namespace.
+	apbMetadata := map[string]interface{}{}
+	for k, v := range metadata {
+		apbMetadata[k] = v
 	}
+	apbMetadata["namespaces"] = targets
+	paramsCopy[MetadataKey] = apbMetadata
 
 	paramsCopy[ClusterKey] = runtime.Provider.GetRuntime()
 	extraVars, err := json.Marshal(paramsCopy)
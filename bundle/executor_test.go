@@ -180,3 +180,91 @@ func TestGetProxyConfig(t *testing.T) {
 		})
 	}
 }
+
+// historyStateManager - records how the executor rolls back state.
+type historyStateManager struct {
+	runtime.StateManager
+	snapshot   *runtime.StateSnapshot
+	restored   int
+	deleted    bool
+	restoreErr error
+}
+
+func (h *historyStateManager) MasterName(id string) string {
+	return id + "-state"
+}
+
+func (h *historyStateManager) DeleteState(name string) error {
+	h.deleted = true
+	return nil
+}
+
+func (h *historyStateManager) SnapshotState(name, action string) (*runtime.StateSnapshot, error) {
+	return h.snapshot, nil
+}
+
+func (h *historyStateManager) ListStateSnapshots(name string) ([]runtime.StateSnapshot, error) {
+	return nil, nil
+}
+
+func (h *historyStateManager) DiffStateSnapshot(name string, revision int) (runtime.StateDiff, error) {
+	return runtime.StateDiff{}, nil
+}
+
+func (h *historyStateManager) RestoreStateSnapshot(name string, revision int) error {
+	h.restored = revision
+	return h.restoreErr
+}
+
+func TestRollbackState(t *testing.T) {
+	instance := &ServiceInstance{}
+	actionErr := errors.New("action failed")
+	testCases := []struct {
+		name       string
+		rollback   bool
+		snapshot   *runtime.StateSnapshot
+		restoreErr error
+		restored   int
+		deleted    bool
+		failed     bool
+	}{
+		{
+			name:     "rollback disabled",
+			snapshot: &runtime.StateSnapshot{Revision: 3},
+		},
+		{
+			name:     "restore the snapshot",
+			rollback: true,
+			snapshot: &runtime.StateSnapshot{Revision: 3},
+			restored: 3,
+		},
+		{
+			name:     "remove state created by the action",
+			rollback: true,
+			deleted:  true,
+		},
+		{
+			name:       "failed rollback is reported",
+			rollback:   true,
+			snapshot:   &runtime.StateSnapshot{Revision: 3},
+			restoreErr: errors.New("restore failed"),
+			restored:   3,
+			failed:     true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &historyStateManager{snapshot: tc.snapshot, restoreErr: tc.restoreErr}
+			e := &executor{stateManager: h, rollbackOnError: tc.rollback}
+			err := e.rollbackState(e.snapshotState(instance, "update"), actionErr)
+			assert.Equal(t, tc.restored, h.restored)
+			assert.Equal(t, tc.deleted, h.deleted)
+			if tc.failed {
+				assert.Contains(t, err.Error(), actionErr.Error())
+				assert.Contains(t, err.Error(), tc.restoreErr.Error())
+			} else {
+				assert.Equal(t, actionErr, err)
+			}
+		})
+	}
+}
@@ -18,6 +18,9 @@ package bundle
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/automationbroker/bundle-lib/runtime"
 	log "github.com/sirupsen/logrus"
@@ -29,6 +32,16 @@ const (
 	// need to be moved to runtime. Therefore keeping all of this together
 	// makes sense
 	GatherCredentialsCommand = "broker-bind-creds"
+
+	// CredentialsActionLabel - label holding the action that extracted the
+	// credentials.
+	CredentialsActionLabel = "bundleAction"
+	// CredentialsBundleLabel - label holding the bundle that extracted the
+	// credentials.
+	CredentialsBundleLabel = "bundleName"
+	// CredentialsInstanceLabel - label holding the service instance of the
+	// credentials of a binding.
+	CredentialsInstanceLabel = "bundleInstance"
 )
 
 var (
@@ -36,9 +49,21 @@ var (
 	ErrExtractedCredentialsNotFound = fmt.Errorf("credentials not found")
 )
 
-// RecoverExtractCredentials - Recover extracted credentials.
-func RecoverExtractCredentials(podname, ns, fqname, id string, method JobMethod, targets []string, rt int) error {
-	defer runtime.Provider.DestroySandbox(podname, ns, targets, clusterConfig.Namespace, clusterConfig.KeepNamespace, clusterConfig.KeepNamespaceOnError)
+// RecoverExtractCredentials - Recover extracted credentials. The instanceID
+// is the service instance the credentials of a binding belong to. The error
+// from tearing down the sandbox is returned when recovering succeeded.
+func RecoverExtractCredentials(
+	podname, ns, fqname, id, instanceID string, method JobMethod, targets []string, rt int,
+) (err error) {
+	defer func() {
+		destroyErr := runtime.Provider.DestroySandbox(podname, ns, targets, clusterConfig.Namespace, clusterConfig.KeepNamespace, clusterConfig.KeepNamespaceOnError)
+		if destroyErr != nil {
+			log.Errorf("unable to destroy sandbox [%s] - %v", podname, destroyErr)
+			if err == nil {
+				err = destroyErr
+			}
+		}
+	}()
 	credBytes, err := runtime.Provider.ExtractCredentials(podname, ns, rt)
 	if err != nil {
 		log.Errorf("bundle unable to extract credentials - %v", err)
@@ -49,7 +74,10 @@ func RecoverExtractCredentials(podname, ns, fqname, id string, method JobMethod,
 		log.Errorf("bundle unable to build extracted credentials - %v", err)
 		return err
 	}
-	labels := map[string]string{"bundleAction": string(method), "bundleName": fqname}
+	labels := map[string]string{CredentialsActionLabel: string(method), CredentialsBundleLabel: fqname}
+	if method == JobMethodBind {
+		labels[CredentialsInstanceLabel] = instanceID
+	}
 	err = runtime.Provider.CreateExtractedCredential(id, clusterConfig.Namespace, creds.Credentials, labels)
 	if err != nil {
 		log.Errorf("Bundle unable to save extracted credentials - %v", err)
@@ -91,3 +119,86 @@ func SetExtractedCredentials(id string, creds *ExtractedCredentials) error {
 func SetExtractedCredentialsWithLabels(id string, creds *ExtractedCredentials, labels map[string]string) error {
 	return runtime.Provider.CreateExtractedCredential(id, clusterConfig.Namespace, creds.Credentials, labels)
 }
+
+// ExtractedCredentialsInfo - Describes stored extracted credentials without
+// the credentials themselves.
+type ExtractedCredentialsInfo struct {
+	ID         string
+	BundleName string
+	Action     string
+	// InstanceID - the service instance of the credentials, the id of the
+	// credentials for the credentials of an instance.
+	InstanceID string
+	Created    time.Time
+	Labels     map[string]string
+}
+
+// CredentialsFilter - Selects extracted credentials, empty fields match all
+// credentials.
+type CredentialsFilter struct {
+	BundleName string
+	Action     string
+	InstanceID string
+	// OlderThan - only credentials created longer ago than this match.
+	OlderThan time.Duration
+}
+
+// ListExtractedCredentials - Lists the extracted credentials of every
+// instance and binding, sorted by id.
+func ListExtractedCredentials() ([]ExtractedCredentialsInfo, error) {
+	return QueryExtractedCredentials(CredentialsFilter{})
+}
+
+// QueryExtractedCredentials - Lists the extracted credentials matching the
+// filter, sorted by id. Credentials of bindings only match an instance when
+// they were extracted with the instance label.
+func QueryExtractedCredentials(filter CredentialsFilter) ([]ExtractedCredentialsInfo, error) {
+	selector := map[string]string{}
+	if filter.BundleName != "" {
+		selector[CredentialsBundleLabel] = filter.BundleName
+	}
+	if filter.Action != "" {
+		selector[CredentialsActionLabel] = filter.Action
+	}
+	creds, err := runtime.Provider.QueryExtractedCredentials(clusterConfig.Namespace, selector)
+	if err != nil {
+		log.Errorf("unable to query extracted credentials - %v", err)
+		return nil, err
+	}
+	infos := []ExtractedCredentialsInfo{}
+	for id, labels := range creds {
+		info := newExtractedCredentialsInfo(id, labels)
+		if filter.InstanceID != "" && info.InstanceID != filter.InstanceID {
+			continue
+		}
+		if filter.OlderThan > 0 && (info.Created.IsZero() || time.Since(info.Created) < filter.OlderThan) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos, nil
+}
+
+func newExtractedCredentialsInfo(id string, labels map[string]string) ExtractedCredentialsInfo {
+	info := ExtractedCredentialsInfo{
+		ID:         id,
+		BundleName: labels[CredentialsBundleLabel],
+		Action:     labels[CredentialsActionLabel],
+		InstanceID: labels[CredentialsInstanceLabel],
+		Labels:     map[string]string{},
+	}
+	if info.InstanceID == "" && info.Action != bindAction {
+		info.InstanceID = id
+	}
+	for k, v := range labels {
+		if k == runtime.CredentialCreatedLabel {
+			if created, err := strconv.ParseInt(v, 10, 64); err == nil {
+				info.Created = time.Unix(created, 0)
+			}
+			continue
+		}
+		info.Labels[k] = v
+	}
+	return info
+}
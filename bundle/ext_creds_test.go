@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestQueryExtractedCredentials(t *testing.T) {
+	old := strconv.FormatInt(time.Now().Add(-48*time.Hour).Unix(), 10)
+	recent := strconv.FormatInt(time.Now().Unix(), 10)
+	stored := map[string]map[string]string{
+		"instance-1": {"bundleAction": "provision", "bundleName": "mysql", runtime.CredentialCreatedLabel: old},
+		"binding-1":  {"bundleAction": "bind", "bundleName": "mysql", "bundleInstance": "instance-1", runtime.CredentialCreatedLabel: recent},
+		"binding-2":  {"bundleAction": "bind", "bundleName": "mysql", runtime.CredentialCreatedLabel: old},
+	}
+	testCases := []struct {
+		name        string
+		filter      CredentialsFilter
+		selector    map[string]string
+		queryErr    error
+		expected    []string
+		shouldError bool
+	}{
+		{
+			name:     "all credentials",
+			selector: map[string]string{},
+			expected: []string{"binding-1", "binding-2", "instance-1"},
+		},
+		{
+			name:     "bundle and action become labels",
+			filter:   CredentialsFilter{BundleName: "mysql", Action: "bind"},
+			selector: map[string]string{"bundleName": "mysql", "bundleAction": "bind"},
+			expected: []string{"binding-1", "binding-2", "instance-1"},
+		},
+		{
+			name:     "instance matches the instance and its bindings",
+			filter:   CredentialsFilter{InstanceID: "instance-1"},
+			selector: map[string]string{},
+			expected: []string{"binding-1", "instance-1"},
+		},
+		{
+			name:     "older than",
+			filter:   CredentialsFilter{OlderThan: 24 * time.Hour},
+			selector: map[string]string{},
+			expected: []string{"binding-2", "instance-1"},
+		},
+		{
+			name:        "query failure",
+			selector:    map[string]string{},
+			queryErr:    fmt.Errorf("unable to list"),
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := new(runtime.MockRuntime)
+			runtime.Provider = rt
+			rt.On("QueryExtractedCredentials", mock.Anything, tc.selector).Return(stored, tc.queryErr)
+
+			infos, err := QueryExtractedCredentials(tc.filter)
+			if tc.shouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			ids := []string{}
+			for _, info := range infos {
+				ids = append(ids, info.ID)
+				_, ok := info.Labels[runtime.CredentialCreatedLabel]
+				assert.False(t, ok)
+				assert.False(t, info.Created.IsZero())
+			}
+			assert.Equal(t, tc.expected, ids)
+			rt.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecoverExtractCredentials(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method JobMethod
+		labels map[string]string
+	}{
+		{
+			name:   "provision",
+			method: JobMethodProvision,
+			labels: map[string]string{"bundleAction": "provision", "bundleName": "mysql"},
+		},
+		{
+			name:   "bind keeps the instance",
+			method: JobMethodBind,
+			labels: map[string]string{"bundleAction": "bind", "bundleName": "mysql", "bundleInstance": "instance-1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := new(runtime.MockRuntime)
+			runtime.Provider = rt
+			rt.On("ExtractCredentials", "pod", "sandbox", 2).Return([]byte(`{"password": "secret"}`), nil)
+			rt.On("CreateExtractedCredential", "credentials-1", mock.Anything,
+				map[string]interface{}{"password": "secret"}, tc.labels).Return(nil)
+			rt.On("DestroySandbox", "pod", "sandbox", []string{"target"},
+				mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			err := RecoverExtractCredentials("pod", "sandbox", "mysql", "credentials-1", "instance-1", tc.method, []string{"target"}, 2)
+			assert.NoError(t, err)
+			rt.AssertExpectations(t)
+		})
+	}
+}
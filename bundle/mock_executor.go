@@ -5,6 +5,7 @@
 package bundle
 
 import mock "github.com/stretchr/testify/mock"
+import time "time"
 
 // MockExecutor is an autogenerated mock type for the Executor type
 type MockExecutor struct {
@@ -27,6 +28,22 @@ func (_m *MockExecutor) Bind(instance *ServiceInstance, parameters *Parameters,
 	return r0
 }
 
+// Clone provides a mock function with given fields: source, instance, options
+func (_m *MockExecutor) Clone(source *ServiceInstance, instance *ServiceInstance, options CloneOptions) <-chan StatusMessage {
+	ret := _m.Called(source, instance, options)
+
+	var r0 <-chan StatusMessage
+	if rf, ok := ret.Get(0).(func(*ServiceInstance, *ServiceInstance, CloneOptions) <-chan StatusMessage); ok {
+		r0 = rf(source, instance, options)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan StatusMessage)
+		}
+	}
+
+	return r0
+}
+
 // Deprovision provides a mock function with given fields: instance
 func (_m *MockExecutor) Deprovision(instance *ServiceInstance) <-chan StatusMessage {
 	ret := _m.Called(instance)
@@ -87,6 +104,22 @@ func (_m *MockExecutor) DashboardURL() string {
 	return r0
 }
 
+// Outputs provides a mock function with given fields:
+func (_m *MockExecutor) Outputs() map[string]interface{} {
+	ret := _m.Called()
+
+	var r0 map[string]interface{}
+	if rf, ok := ret.Get(0).(func() map[string]interface{}); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]interface{})
+		}
+	}
+
+	return r0
+}
+
 // PodName provides a mock function with given fields:
 func (_m *MockExecutor) PodName() string {
 	ret := _m.Called()
@@ -117,6 +150,52 @@ func (_m *MockExecutor) Provision(_a0 *ServiceInstance) <-chan StatusMessage {
 	return r0
 }
 
+// Rotate provides a mock function with given fields: instance, parameters, gracePeriod
+func (_m *MockExecutor) Rotate(instance *ServiceInstance, parameters *Parameters, gracePeriod time.Duration) <-chan StatusMessage {
+	ret := _m.Called(instance, parameters, gracePeriod)
+
+	var r0 <-chan StatusMessage
+	if rf, ok := ret.Get(0).(func(*ServiceInstance, *Parameters, time.Duration) <-chan StatusMessage); ok {
+		r0 = rf(instance, parameters, gracePeriod)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan StatusMessage)
+		}
+	}
+
+	return r0
+}
+
+// RotatedBindings provides a mock function with given fields:
+func (_m *MockExecutor) RotatedBindings() []string {
+	ret := _m.Called()
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
+// SandboxError provides a mock function with given fields:
+func (_m *MockExecutor) SandboxError() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Unbind provides a mock function with given fields: instance, parameters, bindingID
 func (_m *MockExecutor) Unbind(instance *ServiceInstance, parameters *Parameters, bindingID string) <-chan StatusMessage {
 	ret := _m.Called(instance, parameters, bindingID)
@@ -148,3 +227,19 @@ func (_m *MockExecutor) Update(instance *ServiceInstance) <-chan StatusMessage {
 
 	return r0
 }
+
+// Warnings provides a mock function with given fields:
+func (_m *MockExecutor) Warnings() []string {
+	ret := _m.Called()
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
@@ -0,0 +1,211 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProjectionFormat - the shape a projection renders the credentials in.
+type ProjectionFormat string
+
+const (
+	// ProjectionTemplate - every key is rendered from a text/template with
+	// the credentials as data, e.g. a connection URI.
+	ProjectionTemplate ProjectionFormat = "template"
+	// ProjectionEnv - every credential becomes an environment variable
+	// style key, e.g. DB_PASSWORD.
+	ProjectionEnv ProjectionFormat = "env"
+	// ProjectionServiceBinding - the servicebinding.io layout, a type and
+	// provider key and one key per credential.
+	ProjectionServiceBinding ProjectionFormat = "servicebinding"
+
+	// ProjectionEnvFileKey - key of the env projection holding all of the
+	// variables as a .env file.
+	ProjectionEnvFileKey = ".env"
+	// ServiceBindingSecretTypePrefix - prefix of the type of the secrets
+	// published for the servicebinding projection.
+	ServiceBindingSecretTypePrefix = "servicebinding.io/"
+)
+
+var envKeyInvalidChars = regexp.MustCompile("[^A-Z0-9_]")
+
+// Projection - Renders extracted credentials in the shape a consumer needs.
+type Projection struct {
+	Format ProjectionFormat `json:"format" yaml:"format"`
+	// Templates - key to text/template for the template format.
+	Templates map[string]string `json:"templates,omitempty" yaml:"templates"`
+	// Prefix - prepended to the variable names of the env format.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix"`
+	// Type - the type of the servicebinding format, required.
+	Type string `json:"type,omitempty" yaml:"type"`
+	// Provider - the provider of the servicebinding format.
+	Provider string `json:"provider,omitempty" yaml:"provider"`
+}
+
+// Render - Returns the keys of the projection of the credentials.
+func (p Projection) Render(creds *ExtractedCredentials) (map[string][]byte, error) {
+	switch p.Format {
+	case ProjectionTemplate:
+		return p.renderTemplates(creds.Credentials)
+	case ProjectionEnv:
+		return p.renderEnv(creds.Credentials)
+	case ProjectionServiceBinding:
+		return p.renderServiceBinding(creds.Credentials)
+	}
+	return nil, fmt.Errorf("unknown projection format %v", p.Format)
+}
+
+func (p Projection) renderTemplates(creds map[string]interface{}) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	for key, text := range p.Templates {
+		t, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for %v - %v", key, err)
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, creds); err != nil {
+			return nil, fmt.Errorf("unable to render %v - %v", key, err)
+		}
+		data[key] = b.Bytes()
+	}
+	return data, nil
+}
+
+func (p Projection) renderEnv(creds map[string]interface{}) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	names := []string{}
+	for key, value := range creds {
+		name := envKeyInvalidChars.ReplaceAllString(strings.ToUpper(p.Prefix+key), "_")
+		if _, ok := data[name]; ok {
+			return nil, fmt.Errorf("credentials %v and another key both map to %v", key, name)
+		}
+		v, err := credentialString(value)
+		if err != nil {
+			return nil, err
+		}
+		data[name] = []byte(v)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var env bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&env, "%s=%s\n", name, strconv.Quote(string(data[name])))
+	}
+	data[ProjectionEnvFileKey] = env.Bytes()
+	return data, nil
+}
+
+func (p Projection) renderServiceBinding(creds map[string]interface{}) (map[string][]byte, error) {
+	if p.Type == "" {
+		return nil, fmt.Errorf("the servicebinding projection requires a type")
+	}
+	data := map[string][]byte{"type": []byte(p.Type)}
+	if p.Provider != "" {
+		data["provider"] = []byte(p.Provider)
+	}
+	for key, value := range creds {
+		if key == "type" || key == "provider" {
+			return nil, fmt.Errorf("credential %v is reserved by the servicebinding projection", key)
+		}
+		v, err := credentialString(value)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = []byte(v)
+	}
+	return data, nil
+}
+
+// credentialString - the value of a credential as a string, values that are
+// not strings, numbers or booleans are json encoded.
+func credentialString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// PublishProjection - Creates or updates a secret holding the projection of
+// the credentials for workloads to mount.
+func PublishProjection(p Projection, creds *ExtractedCredentials, name, namespace string, labels map[string]string) error {
+	data, err := p.Render(creds)
+	if err != nil {
+		return err
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	secret := &v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Data: data,
+	}
+	if p.Format == ProjectionServiceBinding {
+		secret.Type = v1.SecretType(ServiceBindingSecretTypePrefix + p.Type)
+	}
+	_, err = k8scli.Client.CoreV1().Secrets(namespace).Create(secret)
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = k8scli.Client.CoreV1().Secrets(namespace).Update(secret)
+	}
+	if err != nil {
+		log.Errorf("unable to publish the credentials projection %v/%v - %v", namespace, name, err)
+		return err
+	}
+	return nil
+}
+
+// UnpublishProjection - Removes a secret published with PublishProjection,
+// a missing secret is not an error.
+func UnpublishProjection(name, namespace string) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	err = k8scli.Client.CoreV1().Secrets(namespace).Delete(name, &meta_v1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		log.Errorf("unable to remove the credentials projection %v/%v - %v", namespace, name, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectionRender(t *testing.T) {
+	creds := &ExtractedCredentials{Credentials: map[string]interface{}{
+		"host":     "db.example.com",
+		"port":     float64(5432),
+		"username": "admin",
+		"ssl":      true,
+	}}
+	testCases := []struct {
+		name        string
+		projection  Projection
+		expected    map[string]string
+		shouldError bool
+	}{
+		{
+			name: "template",
+			projection: Projection{
+				Format:    ProjectionTemplate,
+				Templates: map[string]string{"uri": "postgres://{{.username}}@{{.host}}:{{.port}}/db"},
+			},
+			expected: map[string]string{"uri": "postgres://admin@db.example.com:5432/db"},
+		},
+		{
+			name: "template with missing key",
+			projection: Projection{
+				Format:    ProjectionTemplate,
+				Templates: map[string]string{"uri": "{{.password}}"},
+			},
+			shouldError: true,
+		},
+		{
+			name:       "env",
+			projection: Projection{Format: ProjectionEnv, Prefix: "db-"},
+			expected: map[string]string{
+				"DB_HOST":     "db.example.com",
+				"DB_PORT":     "5432",
+				"DB_USERNAME": "admin",
+				"DB_SSL":      "true",
+				".env":        "DB_HOST=\"db.example.com\"\nDB_PORT=\"5432\"\nDB_SSL=\"true\"\nDB_USERNAME=\"admin\"\n",
+			},
+		},
+		{
+			name:       "servicebinding",
+			projection: Projection{Format: ProjectionServiceBinding, Type: "postgresql", Provider: "bitnami"},
+			expected: map[string]string{
+				"type":     "postgresql",
+				"provider": "bitnami",
+				"host":     "db.example.com",
+				"port":     "5432",
+				"username": "admin",
+				"ssl":      "true",
+			},
+		},
+		{
+			name:        "servicebinding without type",
+			projection:  Projection{Format: ProjectionServiceBinding},
+			shouldError: true,
+		},
+		{
+			name:        "unknown format",
+			projection:  Projection{Format: "xml"},
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.projection.Render(creds)
+			if tc.shouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			result := map[string]string{}
+			for k, v := range data {
+				result[k] = string(v)
+			}
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+func TestPublishProjection(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "target"},
+		Data:       map[string][]byte{"type": []byte("old")},
+	})
+	p := Projection{Format: ProjectionServiceBinding, Type: "mysql"}
+	creds := &ExtractedCredentials{Credentials: map[string]interface{}{"password": "secret"}}
+
+	for _, name := range []string{"binding-1", "existing"} {
+		err := PublishProjection(p, creds, name, "target", map[string]string{"bundleName": "mysql"})
+		assert.NoError(t, err)
+		secret, err := k.Client.CoreV1().Secrets("target").Get(name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, v1.SecretType("servicebinding.io/mysql"), secret.Type)
+		assert.Equal(t, "mysql", string(secret.Data["type"]))
+		assert.Equal(t, "secret", string(secret.Data["password"]))
+	}
+
+	assert.NoError(t, UnpublishProjection("binding-1", "target"))
+	assert.NoError(t, UnpublishProjection("binding-1", "target"))
+	_, err = k.Client.CoreV1().Secrets("target").Get("binding-1", metav1.GetOptions{})
+	assert.Error(t, err)
+}
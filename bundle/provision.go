@@ -34,22 +34,27 @@ func (e *executor) Provision(instance *ServiceInstance) <-chan StatusMessage {
 
 	go func() {
 		e.actionStarted()
+		snapshot := e.snapshotState(instance, string(executionMethodProvision))
+		previousDigest := instance.pinImage()
 		err := e.provisionOrUpdate(executionMethodProvision, instance)
 		if err != nil {
 			log.Errorf("Provision APB error: %v", err)
-			e.actionFinishedWithError(err)
+			instance.ImageDigest = previousDigest
+			e.actionFinishedWithError(e.rollbackState(snapshot, err))
 			return
 		}
 		// Provision can not have extracted credentials.
 		if e.extractedCredentials != nil {
-			labels := map[string]string{"bundleAction": string(executionMethodProvision), "bundleName": instance.Spec.FQName}
+			labels := map[string]string{CredentialsActionLabel: string(executionMethodProvision), CredentialsBundleLabel: instance.Spec.FQName}
 			err := runtime.Provider.CreateExtractedCredential(instance.ID.String(), clusterConfig.Namespace, e.extractedCredentials.Credentials, labels)
 			if err != nil {
 				log.Errorf("apb::%v error occurred - %v", executionMethodProvision, err)
-				e.actionFinishedWithError(err)
+				instance.ImageDigest = previousDigest
+				e.actionFinishedWithError(e.rollbackState(snapshot, err))
 				return
 			}
 		}
+		instance.BundleVersion = instance.Spec.BundleVersion
 		e.actionFinishedWithSuccess()
 	}()
 	return e.statusChan
@@ -32,7 +32,9 @@ const (
 	executionMethodUpdate    executionMethod = "update"
 )
 
-// returns PodName, ExtractedCredentials, error
+// provisionOrUpdate - runs the bundle and sets the extracted credentials.
+// The action is not finished here, the caller finishes it once it has
+// cleaned up after a failure.
 func (e *executor) provisionOrUpdate(method executionMethod, instance *ServiceInstance) error {
 	// Explicitly error out if image field is missing from instance.Spec
 	// was introduced as a change to the apb instance.Spec to support integration
@@ -55,16 +57,17 @@ func (e *executor) provisionOrUpdate(method executionMethod, instance *ServiceIn
 	}
 	// Create the podname
 	pn := fmt.Sprintf("bundle-%s", uuid.New())
-	targets := []string{instance.Context.Namespace}
+	targets := instance.Context.Targets()
 	labels := map[string]string{
-		"bundle-fqname":   instance.Spec.FQName,
-		"bundle-action":   string(method),
-		"bundle-pod-name": pn,
+		"bundle-fqname":      instance.Spec.FQName,
+		"bundle-action":      string(method),
+		"bundle-pod-name":    pn,
+		"bundle-instance-id": instance.ID.String(),
+		"bundle-spec-id":     instance.Spec.ID,
 	}
 	serviceAccount, namespace, err := runtime.Provider.CreateSandbox(pn, ns, targets, clusterConfig.SandboxRole, labels)
 	if err != nil {
 		log.Errorf("Problem executing bundle create sandbox [%s] %v", pn, method)
-		e.actionFinishedWithError(err)
 		return err
 	}
 	ec := runtime.ExecutionContext{
@@ -72,22 +75,14 @@ func (e *executor) provisionOrUpdate(method executionMethod, instance *ServiceIn
 		Targets:    targets,
 		Metadata:   labels,
 		Action:     string(method),
-		Image:      instance.Spec.Image,
+		Image:      instance.Image(),
 		Account:    serviceAccount,
 		Location:   namespace,
 	}
 	ec, err = e.executeApb(ec, instance, instance.Parameters)
-	defer runtime.Provider.DestroySandbox(
-		ec.BundleName,
-		ec.Location,
-		ec.Targets,
-		clusterConfig.Namespace,
-		clusterConfig.KeepNamespace,
-		clusterConfig.KeepNamespaceOnError,
-	)
+	defer e.destroySandbox(ec)
 	if err != nil {
 		log.Errorf("Problem executing bundle [%s] %v", ec.BundleName, method)
-		e.actionFinishedWithError(err)
 		return err
 	}
 
@@ -130,6 +125,9 @@ func (e *executor) provisionOrUpdate(method executionMethod, instance *ServiceIn
 		log.Errorf("bundle::%v error occurred - %v", method, err)
 		return err
 	}
+	if err := validateExtractedCredentials(instance, nil, creds); err != nil {
+		return err
+	}
 
 	e.extractedCredentials = creds
 	return nil
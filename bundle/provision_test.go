@@ -50,7 +50,7 @@ func TestProvision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -96,7 +96,7 @@ func TestProvision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("CreateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "provision", "bundleName": "new-fq-name"}).Return(nil)
 			},
@@ -153,7 +153,7 @@ func TestProvision(t *testing.T) {
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					ex.updateDescription("dashboard url", "https://url.com")
 				}).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("CreateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "provision", "bundleName": "new-fq-name"}).Return(nil)
 			},
@@ -209,7 +209,7 @@ func TestProvision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -323,7 +323,7 @@ func TestProvision(t *testing.T) {
 			addExpectations: func(rt *runtime.MockRuntime, e Executor) {
 				rt.On("CreateSandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("service-account-1", "", nil)
 				rt.On("GetRuntime").Return("kubernetes")
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -369,7 +369,7 @@ func TestProvision(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("CreateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "provision", "bundleName": "new-fq-name"}).Return(fmt.Errorf("unable to create extracted creds"))
 			},
@@ -418,3 +418,55 @@ func TestProvision(t *testing.T) {
 		})
 	}
 }
+
+func TestProvisionPinsImage(t *testing.T) {
+	u := uuid.NewUUID()
+	testCases := []struct {
+		name           string
+		runErr         error
+		expectedDigest string
+	}{
+		{name: "provision keeps the digest it ran", expectedDigest: "sha256:a"},
+		{name: "failed provision is not pinned", runErr: fmt.Errorf("unable to run bundle")},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := new(runtime.MockRuntime)
+			runtime.Provider = rt
+			si := &ServiceInstance{
+				ID: u,
+				Spec: &Spec{
+					ID:          "new-spec-id",
+					Image:       "docker.io/org/foo:latest",
+					ImageDigest: "sha256:a",
+					FQName:      "new-fq-name",
+					Runtime:     2,
+				},
+				Context:    &Context{Namespace: "target", Platform: "kubernetes"},
+				Parameters: &Parameters{},
+			}
+			pinned := mock.MatchedBy(func(ec runtime.ExecutionContext) bool {
+				return ec.Image == "docker.io/org/foo@sha256:a"
+			})
+			rt.On("CreateSandbox", mock.Anything, mock.Anything, []string{"target"}, mock.Anything, mock.Anything).Return("service-account-1", "location", nil)
+			rt.On("GetRuntime").Return("kubernetes")
+			rt.On("CopySecretsToNamespace", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("MasterName", u.String()).Return("new-master-name")
+			rt.On("MasterNamespace").Return("new-masternamespace")
+			rt.On("StateIsPresent", "new-master-name").Return(false, nil)
+			rt.On("RunBundle", pinned).Return(runtime.ExecutionContext{}, tc.runErr)
+			rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			e := NewExecutor(ExecutorConfig{})
+			for range e.Provision(si) {
+			}
+			// the tag is never pulled, even by the provision
+			rt.AssertCalled(t, "RunBundle", pinned)
+			if si.ImageDigest != tc.expectedDigest {
+				t.Fatalf("expected digest %q got %q", tc.expectedDigest, si.ImageDigest)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	rotateAction = "rotate"
+
+	// RotationExpiresLabel - label on the previous credentials holding the
+	// unix time at which they are removed.
+	RotationExpiresLabel = "rotationExpires"
+)
+
+// afterFunc - schedules the removal of the previous credentials, replaced in
+// tests.
+var afterFunc = time.AfterFunc
+
+// PreviousCredentialsID - Returns the id the previous credentials are kept
+// under while a rotation is in its grace period.
+func PreviousCredentialsID(id string) string {
+	return id + "-previous"
+}
+
+// Rotate - runs the apb with the rotate action. The credentials the bundle
+// returns replace the credentials of the instance. A runtime 3 bundle is
+// given the ids of the bindings and replaces the credentials of a binding by
+// returning them under its id in the binding credentials of its result, the
+// credentials of the bindings it does not return are left alone. The previous
+// credentials are kept until the grace period expires, RotatedBindings
+// returns the bindings whose credentials changed. When a credential cannot
+// be replaced, the credentials that were already replaced are restored.
+func (e *executor) Rotate(
+	instance *ServiceInstance, parameters *Parameters, gracePeriod time.Duration,
+) <-chan StatusMessage {
+	log.Infof("============================================================")
+	log.Infof("                       ROTATING                             ")
+	log.Infof("============================================================")
+	log.Infof("ServiceInstance.ID: %s", instance.Spec.ID)
+	log.Infof("ServiceInstance.Name: %v", instance.Spec.FQName)
+	log.Infof("ServiceInstance.Image: %s", instance.Image())
+	log.Infof("GracePeriod: %v", gracePeriod)
+	log.Infof("============================================================")
+
+	go func() {
+		e.actionStarted()
+		snapshot := e.snapshotState(instance, rotateAction)
+		creds, bindingCreds, err := e.rotate(instance, parameters)
+		if err != nil {
+			e.actionFinishedWithError(e.rollbackState(snapshot, err))
+			return
+		}
+		e.extractedCredentials = creds
+		if err := RemoveExpiredCredentials(); err != nil {
+			log.Warningf("unable to remove expired previous credentials - %v", err)
+		}
+		rotated, err := replaceCredentials(instance, creds, bindingCreds, gracePeriod, e.bindProjection)
+		e.rotatedBindings = rotated
+		if err != nil {
+			log.Errorf("apb::%v error occurred - %v", rotateAction, err)
+			e.actionFinishedWithError(err)
+			return
+		}
+		e.actionFinishedWithSuccess()
+	}()
+
+	return e.statusChan
+}
+
+func (e *executor) rotate(
+	instance *ServiceInstance, parameters *Parameters,
+) (*ExtractedCredentials, map[string]map[string]interface{}, error) {
+	// Create namespace name that will be used to generate a name.
+	ns := fmt.Sprintf("%s-%.4s-", instance.Spec.FQName, rotateAction)
+	// Determine if we should be using the context namespace from the executor config.
+	if e.skipCreateNS {
+		ns = instance.Context.Namespace
+	}
+	// Create the podname
+	pn := fmt.Sprintf("bundle-%s", uuid.New())
+	targets := instance.Context.Targets()
+	labels := map[string]string{
+		"bundle-fqname":      instance.Spec.FQName,
+		"bundle-action":      rotateAction,
+		"bundle-pod-name":    pn,
+		"bundle-instance-id": instance.ID.String(),
+		"bundle-spec-id":     instance.Spec.ID,
+	}
+
+	serviceAccount, namespace, err := runtime.Provider.CreateSandbox(pn, ns, targets, clusterConfig.SandboxRole, labels)
+	ec := runtime.ExecutionContext{
+		BundleName: pn,
+		Targets:    targets,
+		Metadata:   labels,
+		Action:     rotateAction,
+		Image:      instance.Image(),
+		Account:    serviceAccount,
+		Location:   namespace,
+	}
+	if err != nil {
+		log.Errorf("Problem executing bundle create sandbox [%s] rotate", ec.BundleName)
+		return nil, nil, err
+	}
+	// The bundle is told which bindings it can return credentials for.
+	rotateParameters := Parameters{BindingIDsKey: sortedBindingIDs(instance)}
+	if parameters != nil {
+		for k, v := range *parameters {
+			rotateParameters[k] = v
+		}
+	}
+	ec, err = e.executeApb(ec, instance, &rotateParameters)
+	defer e.destroySandbox(ec)
+	if err != nil {
+		log.Errorf("Problem executing bundle [%s] rotate", ec.BundleName)
+		return nil, nil, err
+	}
+
+	if instance.Spec.Runtime >= 2 {
+		err := runtime.Provider.WatchRunningBundle(ec.BundleName, ec.Location, e.updateDescription)
+		if err != nil {
+			log.Errorf("Rotate action failed - %v", err)
+			return nil, nil, err
+		}
+	}
+
+	// pod execution is complete so transfer state back
+	err = e.stateManager.CopyState(
+		ec.BundleName,
+		e.stateManager.MasterName(instance.ID.String()),
+		ec.Location, e.stateManager.MasterNamespace())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credBytes, err := runtime.Provider.ExtractCredentials(
+		ec.BundleName,
+		ec.Location,
+		instance.Spec.Runtime,
+	)
+	if err != nil {
+		log.Errorf("apb::rotate error occurred - %v", err)
+		return nil, nil, err
+	}
+	creds, err := buildExtractedCredentials(credBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateExtractedCredentials(instance, parameters, creds); err != nil {
+		return nil, nil, err
+	}
+	bindingCreds := map[string]map[string]interface{}{}
+	if instance.Spec.Runtime < runtime.ResultRuntimeVersion {
+		return creds, bindingCreds, nil
+	}
+	result, err := runtime.Provider.GetBundleResult(ec.BundleName, ec.Location)
+	if err != nil {
+		log.Errorf("apb::rotate error occurred - %v", err)
+		return nil, nil, err
+	}
+	if result == nil {
+		return creds, bindingCreds, nil
+	}
+	for id, c := range result.BindingCredentials {
+		if !instance.BindingIDs[id] {
+			log.Warningf("ignoring the credentials returned for unknown binding %s", id)
+			continue
+		}
+		err := validateExtractedCredentials(instance, parameters, &ExtractedCredentials{Credentials: c})
+		if err != nil {
+			return nil, nil, err
+		}
+		bindingCreds[id] = c
+	}
+	return creds, bindingCreds, nil
+}
+
+// rotation - the credentials a rotation replaced, kept to restore them when
+// the rotation fails.
+type rotation struct {
+	instance       *ServiceInstance
+	gracePeriod    time.Duration
+	previousLabels map[string]string
+	projection     *Projection
+	// replaced - the credentials that were replaced by id.
+	replaced map[string]map[string]interface{}
+	labels   map[string]map[string]string
+	// previous - the ids of the previous credentials that were kept.
+	previous []string
+}
+
+// replaceCredentials - updates the credentials of the instance and of the
+// bindings the bundle returned credentials for, republishes the projection
+// of those bindings and keeps the previous credentials for the grace period.
+// Returns the ids of the bindings that were updated. When a credential
+// cannot be replaced the credentials replaced so far are restored, and no
+// binding is reported.
+func replaceCredentials(
+	instance *ServiceInstance, creds *ExtractedCredentials, bindingCreds map[string]map[string]interface{},
+	gracePeriod time.Duration, projection *Projection,
+) ([]string, error) {
+	existing, err := runtime.Provider.QueryExtractedCredentials(
+		clusterConfig.Namespace, map[string]string{CredentialsBundleLabel: instance.Spec.FQName})
+	if err != nil {
+		return []string{}, err
+	}
+	expires := time.Now().Add(gracePeriod)
+	r := &rotation{
+		instance:    instance,
+		gracePeriod: gracePeriod,
+		previousLabels: map[string]string{
+			CredentialsActionLabel:   rotateAction,
+			CredentialsBundleLabel:   instance.Spec.FQName,
+			CredentialsInstanceLabel: instance.ID.String(),
+			RotationExpiresLabel:     strconv.FormatInt(expires.Unix(), 10),
+		},
+		projection: projection,
+		replaced:   map[string]map[string]interface{}{},
+		labels:     map[string]map[string]string{},
+	}
+
+	replacements := map[string]map[string]interface{}{instance.ID.String(): creds.Credentials}
+	ids := []string{instance.ID.String()}
+	for _, id := range sortedBindingIDs(instance) {
+		if c, ok := bindingCreds[id]; ok {
+			replacements[id] = c
+			ids = append(ids, id)
+			continue
+		}
+		log.Debugf("no credentials returned for binding %s, keeping its credentials", id)
+	}
+
+	rotated := []string{}
+	for _, id := range ids {
+		ok, err := r.replace(id, replacements[id], existing[id])
+		if err != nil {
+			r.restore()
+			return []string{}, err
+		}
+		if ok && id != instance.ID.String() {
+			rotated = append(rotated, id)
+		}
+	}
+	if len(r.previous) > 0 {
+		previous := r.previous
+		afterFunc(gracePeriod, func() {
+			for _, id := range previous {
+				if err := deletePreviousCredentials(id); err != nil {
+					log.Errorf("unable to remove the previous credentials %s - %v", id, err)
+				}
+			}
+		})
+	}
+	return rotated, nil
+}
+
+// replace - keeps the previous credentials of the id and replaces them.
+// Returns false when the id has no credentials to replace.
+func (r *rotation) replace(id string, creds map[string]interface{}, existing map[string]string) (bool, error) {
+	old, err := runtime.Provider.GetExtractedCredential(id, clusterConfig.Namespace)
+	if err == runtime.ErrCredentialsNotFound {
+		log.Debugf("no extracted credentials to rotate for %s", id)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if r.gracePeriod > 0 {
+		// A rotation within the grace period of the last one replaces the
+		// previous credentials.
+		prevID := PreviousCredentialsID(id)
+		if err := deletePreviousCredentials(prevID); err != nil {
+			return false, err
+		}
+		err := runtime.Provider.CreateExtractedCredential(prevID, clusterConfig.Namespace, old, r.previousLabels)
+		if err != nil {
+			return false, err
+		}
+		r.previous = append(r.previous, prevID)
+	}
+	labels := rotatedLabels(r.instance, id, existing)
+	err = runtime.Provider.UpdateExtractedCredential(id, clusterConfig.Namespace, creds, labels)
+	if err != nil {
+		return false, err
+	}
+	r.replaced[id] = old
+	r.labels[id] = labels
+	return true, r.publish(id, creds)
+}
+
+// restore - puts back the credentials that were replaced and removes the
+// previous credentials that were kept.
+func (r *rotation) restore() {
+	for id, old := range r.replaced {
+		err := runtime.Provider.UpdateExtractedCredential(id, clusterConfig.Namespace, old, r.labels[id])
+		if err != nil {
+			log.Errorf("unable to restore the credentials %s - %v", id, err)
+			continue
+		}
+		if err := r.publish(id, old); err != nil {
+			log.Errorf("unable to restore the projection of %s - %v", id, err)
+		}
+	}
+	for _, id := range r.previous {
+		if err := deletePreviousCredentials(id); err != nil {
+			log.Errorf("unable to remove the previous credentials %s - %v", id, err)
+		}
+	}
+}
+
+// publish - publishes the projection of the credentials of a binding.
+func (r *rotation) publish(id string, creds map[string]interface{}) error {
+	if r.projection == nil || id == r.instance.ID.String() {
+		return nil
+	}
+	return PublishProjection(*r.projection, &ExtractedCredentials{Credentials: creds},
+		id, r.instance.Context.Namespace, r.labels[id])
+}
+
+// sortedBindingIDs - the ids of the bindings of the instance, sorted.
+func sortedBindingIDs(instance *ServiceInstance) []string {
+	bindingIDs := []string{}
+	for id := range instance.BindingIDs {
+		bindingIDs = append(bindingIDs, id)
+	}
+	sort.Strings(bindingIDs)
+	return bindingIDs
+}
+
+// rotatedLabels - returns the labels of rotated credentials. The action
+// that extracted the credentials is kept so that rotated bindings are still
+// found as bindings.
+func rotatedLabels(instance *ServiceInstance, id string, existing map[string]string) map[string]string {
+	labels := map[string]string{CredentialsBundleLabel: instance.Spec.FQName}
+	action := existing[CredentialsActionLabel]
+	if id == instance.ID.String() {
+		if action == "" {
+			action = rotateAction
+		}
+	} else {
+		if action == "" {
+			action = bindAction
+		}
+		labels[CredentialsInstanceLabel] = instance.ID.String()
+	}
+	labels[CredentialsActionLabel] = action
+	return labels
+}
+
+// RemoveExpiredCredentials - removes the previous credentials whose grace
+// period expired. The previous credentials are removed when the grace period
+// expires, this removes those that outlived the process that rotated them and
+// should be called when the broker starts.
+func RemoveExpiredCredentials() error {
+	creds, err := runtime.Provider.QueryExtractedCredentials(
+		clusterConfig.Namespace, map[string]string{CredentialsActionLabel: rotateAction})
+	if err != nil {
+		log.Errorf("unable to query previous credentials - %v", err)
+		return err
+	}
+	now := time.Now()
+	for id, labels := range creds {
+		value, ok := labels[RotationExpiresLabel]
+		if !ok {
+			continue
+		}
+		expires, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			log.Warningf("invalid %s label on %s - %v", RotationExpiresLabel, id, err)
+			continue
+		}
+		if now.Before(time.Unix(expires, 0)) {
+			continue
+		}
+		log.Debugf("removing expired previous credentials %s", id)
+		if err := deletePreviousCredentials(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletePreviousCredentials - removes previous credentials, credentials that
+// were already removed are not an error.
+func deletePreviousCredentials(id string) error {
+	err := runtime.Provider.DeleteExtractedCredential(id, clusterConfig.Namespace)
+	if err != nil && err != runtime.ErrCredentialsNotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/runtime"
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRotate(t *testing.T) {
+	u := uuid.NewUUID()
+	oldCreds := map[string]interface{}{"password": "old"}
+	newCreds := map[string]interface{}{"password": "new"}
+	binding1Creds := map[string]interface{}{"password": "binding-1"}
+	binding2Creds := map[string]interface{}{"password": "binding-2"}
+	bothBindings := &runtime.BundleResult{
+		Credentials: newCreds,
+		BindingCredentials: map[string]map[string]interface{}{
+			"binding-1": binding1Creds,
+			"binding-2": binding2Creds,
+		},
+	}
+	testCases := []struct {
+		name            string
+		runtime         int
+		result          *runtime.BundleResult
+		gracePeriod     time.Duration
+		projection      *Projection
+		addExpectations func(rt *runtime.MockRuntime)
+		state           State
+		rotated         []string
+		scheduled       bool
+		verify          func(t *testing.T, rt *runtime.MockRuntime)
+	}{
+		{
+			name:        "rotate instance and the bindings returned by the bundle",
+			runtime:     3,
+			result:      &runtime.BundleResult{BindingCredentials: map[string]map[string]interface{}{"binding-1": binding1Creds}},
+			gracePeriod: time.Hour,
+			addExpectations: func(rt *runtime.MockRuntime) {
+				rt.On("GetExtractedCredential", u.String(), mock.Anything).Return(oldCreds, nil)
+				rt.On("GetExtractedCredential", "binding-1", mock.Anything).Return(oldCreds, nil)
+				rt.On("DeleteExtractedCredential", mock.Anything, mock.Anything).Return(nil)
+				rt.On("CreateExtractedCredential", u.String()+"-previous", mock.Anything, oldCreds, mock.Anything).Return(nil)
+				rt.On("CreateExtractedCredential", "binding-1-previous", mock.Anything, oldCreds, mock.Anything).Return(nil)
+				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, newCreds, map[string]string{
+					CredentialsActionLabel: "provision",
+					CredentialsBundleLabel: "new-fq-name",
+				}).Return(nil)
+				rt.On("UpdateExtractedCredential", "binding-1", mock.Anything, binding1Creds, map[string]string{
+					CredentialsActionLabel:   bindAction,
+					CredentialsBundleLabel:   "new-fq-name",
+					CredentialsInstanceLabel: u.String(),
+				}).Return(nil)
+			},
+			state:     StateSucceeded,
+			rotated:   []string{"binding-1"},
+			scheduled: true,
+			verify: func(t *testing.T, rt *runtime.MockRuntime) {
+				// binding-2 was not returned by the bundle
+				rt.AssertNotCalled(t, "GetExtractedCredential", "binding-2", mock.Anything)
+			},
+		},
+		{
+			name: "bindings are left alone without a runtime 3 result",
+			addExpectations: func(rt *runtime.MockRuntime) {
+				rt.On("GetExtractedCredential", u.String(), mock.Anything).Return(oldCreds, nil)
+				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, newCreds, mock.Anything).Return(nil)
+			},
+			state:   StateSucceeded,
+			rotated: []string{},
+			verify: func(t *testing.T, rt *runtime.MockRuntime) {
+				rt.AssertNumberOfCalls(t, "UpdateExtractedCredential", 1)
+			},
+		},
+		{
+			name:       "rotate republishes the bind projection",
+			runtime:    3,
+			result:     bothBindings,
+			projection: &Projection{Format: ProjectionEnv},
+			addExpectations: func(rt *runtime.MockRuntime) {
+				rt.On("GetExtractedCredential", u.String(), mock.Anything).Return(oldCreds, nil)
+				rt.On("GetExtractedCredential", "binding-1", mock.Anything).Return(oldCreds, nil)
+				rt.On("GetExtractedCredential", "binding-2", mock.Anything).Return(nil, runtime.ErrCredentialsNotFound)
+				rt.On("UpdateExtractedCredential", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			},
+			state:   StateSucceeded,
+			rotated: []string{"binding-1"},
+			verify: func(t *testing.T, rt *runtime.MockRuntime) {
+				k, _ := clients.Kubernetes()
+				secret, err := k.Client.CoreV1().Secrets("target").Get("binding-1", metav1.GetOptions{})
+				assert.NoError(t, err)
+				assert.Equal(t, "binding-1", string(secret.Data["PASSWORD"]))
+			},
+		},
+		{
+			name:        "failed update restores the replaced credentials",
+			runtime:     3,
+			result:      bothBindings,
+			gracePeriod: time.Hour,
+			addExpectations: func(rt *runtime.MockRuntime) {
+				rt.On("GetExtractedCredential", mock.Anything, mock.Anything).Return(oldCreds, nil)
+				rt.On("DeleteExtractedCredential", mock.Anything, mock.Anything).Return(nil)
+				rt.On("CreateExtractedCredential", mock.Anything, mock.Anything, oldCreds, mock.Anything).Return(nil)
+				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, newCreds, mock.Anything).Return(nil)
+				rt.On("UpdateExtractedCredential", "binding-1", mock.Anything, binding1Creds, mock.Anything).Return(nil)
+				rt.On("UpdateExtractedCredential", "binding-2", mock.Anything, binding2Creds, mock.Anything).Return(fmt.Errorf("update failed"))
+				rt.On("UpdateExtractedCredential", mock.Anything, mock.Anything, oldCreds, mock.Anything).Return(nil)
+			},
+			state:   StateFailed,
+			rotated: []string{},
+			verify: func(t *testing.T, rt *runtime.MockRuntime) {
+				rt.AssertCalled(t, "UpdateExtractedCredential", u.String(), mock.Anything, oldCreds, mock.Anything)
+				rt.AssertCalled(t, "UpdateExtractedCredential", "binding-1", mock.Anything, oldCreds, mock.Anything)
+				rt.AssertNotCalled(t, "UpdateExtractedCredential", "binding-2", mock.Anything, oldCreds, mock.Anything)
+				for _, id := range []string{u.String(), "binding-1", "binding-2"} {
+					rt.AssertCalled(t, "DeleteExtractedCredential", PreviousCredentialsID(id), mock.Anything)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := new(runtime.MockRuntime)
+			runtime.Provider = rt
+			rt.On("CreateSandbox", mock.Anything, mock.Anything, []string{"target"}, mock.Anything, mock.Anything).Return("service-account-1", "location", nil)
+			rt.On("GetRuntime").Return("kubernetes")
+			rt.On("CopySecretsToNamespace", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("MasterName", u.String()).Return("new-master-name")
+			rt.On("MasterNamespace").Return("new-masternamespace")
+			rt.On("StateIsPresent", "new-master-name").Return(false, nil)
+			rt.On("RunBundle", mock.MatchedBy(func(ec runtime.ExecutionContext) bool {
+				return ec.Action == "rotate"
+			})).Return(runtime.ExecutionContext{}, nil)
+			rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"password": "new"}`), nil)
+			rt.On("GetBundleResult", mock.Anything, mock.Anything).Return(tc.result, nil)
+			rt.On("QueryExtractedCredentials", mock.Anything, map[string]string{CredentialsBundleLabel: "new-fq-name"}).Return(
+				map[string]map[string]string{
+					u.String():  {CredentialsActionLabel: "provision", CredentialsBundleLabel: "new-fq-name"},
+					"binding-1": {CredentialsActionLabel: bindAction, CredentialsBundleLabel: "new-fq-name"},
+				}, nil)
+			rt.On("QueryExtractedCredentials", mock.Anything, map[string]string{CredentialsActionLabel: "rotate"}).Return(
+				map[string]map[string]string{}, nil)
+			tc.addExpectations(rt)
+
+			var scheduled func()
+			afterFunc = func(d time.Duration, f func()) *time.Timer {
+				assert.Equal(t, tc.gracePeriod, d)
+				scheduled = f
+				return nil
+			}
+			defer func() { afterFunc = time.AfterFunc }()
+			k, err := clients.Kubernetes()
+			if err != nil {
+				t.Fail()
+			}
+			k.Client = fake.NewSimpleClientset()
+
+			specRuntime := tc.runtime
+			if specRuntime == 0 {
+				specRuntime = 2
+			}
+			si := &ServiceInstance{
+				ID: u,
+				Spec: &Spec{
+					ID:      "new-spec-id",
+					Image:   "new-image",
+					FQName:  "new-fq-name",
+					Runtime: specRuntime,
+				},
+				Context:    &Context{Namespace: "target", Platform: "kubernetes"},
+				BindingIDs: map[string]bool{"binding-2": true, "binding-1": true},
+			}
+			e := NewExecutor(ExecutorConfig{BindProjection: tc.projection})
+			var last StatusMessage
+			for mess := range e.Rotate(si, nil, tc.gracePeriod) {
+				last = mess
+			}
+			assert.Equal(t, tc.state, last.State)
+			assert.Equal(t, tc.rotated, e.RotatedBindings())
+			assert.Equal(t, tc.scheduled, scheduled != nil)
+			if scheduled != nil {
+				scheduled()
+				rt.AssertCalled(t, "DeleteExtractedCredential", "binding-1-previous", mock.Anything)
+			}
+			if tc.verify != nil {
+				tc.verify(t, rt)
+			}
+		})
+	}
+}
+
+func TestRemoveExpiredCredentials(t *testing.T) {
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	pending := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	rt := new(runtime.MockRuntime)
+	runtime.Provider = rt
+	rt.On("QueryExtractedCredentials", mock.Anything, map[string]string{CredentialsActionLabel: "rotate"}).Return(
+		map[string]map[string]string{
+			"expired-previous": {RotationExpiresLabel: expired},
+			"removed-previous": {RotationExpiresLabel: expired},
+			"pending-previous": {RotationExpiresLabel: pending},
+			"invalid-previous": {RotationExpiresLabel: "soon"},
+			"rotated":          {CredentialsBundleLabel: "new-fq-name"},
+		}, nil)
+	rt.On("DeleteExtractedCredential", "expired-previous", mock.Anything).Return(nil)
+	rt.On("DeleteExtractedCredential", "removed-previous", mock.Anything).Return(runtime.ErrCredentialsNotFound)
+
+	assert.NoError(t, RemoveExpiredCredentials())
+	rt.AssertNumberOfCalls(t, "DeleteExtractedCredential", 2)
+	rt.AssertNotCalled(t, "DeleteExtractedCredential", "pending-previous", mock.Anything)
+	rt.AssertNotCalled(t, "DeleteExtractedCredential", "invalid-previous", mock.Anything)
+	rt.AssertNotCalled(t, "DeleteExtractedCredential", "rotated", mock.Anything)
+}
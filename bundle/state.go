@@ -0,0 +1,64 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/runtime"
+)
+
+// ErrStateHistoryNotSupported - The runtime does not keep a state history.
+var ErrStateHistoryNotSupported = fmt.Errorf("state history is not supported by the runtime")
+
+func stateHistory() (runtime.StateHistory, error) {
+	history, ok := runtime.Provider.(runtime.StateHistory)
+	if !ok {
+		return nil, ErrStateHistoryNotSupported
+	}
+	return history, nil
+}
+
+// ListStateSnapshots - Will list the snapshots of the state of a service
+// instance, oldest first.
+func ListStateSnapshots(instanceID string) ([]runtime.StateSnapshot, error) {
+	history, err := stateHistory()
+	if err != nil {
+		return nil, err
+	}
+	return history.ListStateSnapshots(runtime.Provider.MasterName(instanceID))
+}
+
+// DiffStateSnapshot - Will compare a snapshot to the current state of a
+// service instance.
+func DiffStateSnapshot(instanceID string, revision int) (runtime.StateDiff, error) {
+	history, err := stateHistory()
+	if err != nil {
+		return runtime.StateDiff{}, err
+	}
+	return history.DiffStateSnapshot(runtime.Provider.MasterName(instanceID), revision)
+}
+
+// RestoreStateSnapshot - Will replace the state of a service instance with a
+// snapshot. This should not be called while an action runs for the instance.
+func RestoreStateSnapshot(instanceID string, revision int) error {
+	history, err := stateHistory()
+	if err != nil {
+		return err
+	}
+	return history.RestoreStateSnapshot(runtime.Provider.MasterName(instanceID), revision)
+}
@@ -0,0 +1,64 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/authorization"
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ValidateTargets - Validates that every target namespace of the context
+// exists and, when an authorizer is given, that the user is allowed to use
+// it. The executor does not know the user of a request and the sandbox only
+// checks the targets exist, so the broker must call this with the user
+// before it provisions, updates or binds an instance. Otherwise any user can
+// give a bundle access to the additional namespaces.
+func ValidateTargets(context *Context, user authorization.AuthorizeUser, authorizer authorization.Authorizer) error {
+	if context == nil || context.Namespace == "" {
+		return fmt.Errorf("context does not have a namespace")
+	}
+	if authorizer != nil && user == nil {
+		return fmt.Errorf("a user is required to authorize the target namespaces")
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	for _, target := range context.Targets() {
+		_, err := k8scli.Client.CoreV1().Namespaces().Get(target, meta_v1.GetOptions{})
+		if err != nil {
+			log.Errorf("unable to get target namespace %v - %v", target, err)
+			return fmt.Errorf("unable to get target namespace %v - %v", target, err)
+		}
+		if authorizer == nil {
+			continue
+		}
+		decision, err := authorizer.Authorize(user, target)
+		if err != nil {
+			return fmt.Errorf("unable to authorize user for target namespace %v - %v", target, err)
+		}
+		if decision != authorization.DecisionAllowed {
+			log.Infof("user %v is not allowed to use target namespace %v - %v", user.Username(), target, decision)
+			return fmt.Errorf("user %v is not allowed to use target namespace %v", user.Username(), target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package bundle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/authorization"
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type targetUser string
+
+func (u targetUser) Username() string {
+	return string(u)
+}
+
+// targetAuthorizer - allows the namespaces in the map.
+type targetAuthorizer map[string]bool
+
+func (a targetAuthorizer) Authorize(user authorization.AuthorizeUser, location string) (authorization.Decision, error) {
+	if a[location] {
+		return authorization.DecisionAllowed, nil
+	}
+	return authorization.DecisionDeny, nil
+}
+
+func TestContextTargets(t *testing.T) {
+	c := &Context{
+		Namespace:            "app",
+		AdditionalNamespaces: []string{"data", "", "app", "data"},
+	}
+	expected := []string{"app", "data"}
+	if !reflect.DeepEqual(c.Targets(), expected) {
+		t.Fatalf("expected targets %v got %v", expected, c.Targets())
+	}
+}
+
+func TestValidateTargets(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	namespace := func(name string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	testCases := []struct {
+		name        string
+		context     *Context
+		authorizer  authorization.Authorizer
+		shouldError bool
+	}{
+		{
+			name:    "all targets exist",
+			context: &Context{Namespace: "app", AdditionalNamespaces: []string{"data"}},
+		},
+		{
+			name:        "missing namespace",
+			context:     &Context{},
+			shouldError: true,
+		},
+		{
+			name:        "additional namespace does not exist",
+			context:     &Context{Namespace: "app", AdditionalNamespaces: []string{"missing"}},
+			shouldError: true,
+		},
+		{
+			name:       "user allowed in all targets",
+			context:    &Context{Namespace: "app", AdditionalNamespaces: []string{"data"}},
+			authorizer: targetAuthorizer{"app": true, "data": true},
+		},
+		{
+			name:        "user denied in additional namespace",
+			context:     &Context{Namespace: "app", AdditionalNamespaces: []string{"data"}},
+			authorizer:  targetAuthorizer{"app": true},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k.Client = fake.NewSimpleClientset(namespace("app"), namespace("data"))
+			err := ValidateTargets(tc.context, targetUser("developer"), tc.authorizer)
+			if tc.shouldError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -18,7 +18,9 @@ package bundle
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 
 	schema "github.com/lestrrat/go-jsschema"
 	"github.com/pborman/uuid"
@@ -81,6 +83,18 @@ type Dependency struct {
 	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
 }
 
+// CredentialDescriptor - a key of the credentials a bundle returns when it
+// is bound.
+type CredentialDescriptor struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Sensitive - the value must be kept secret, e.g. a password. Only the
+	// values of declared credentials that are not sensitive are logged.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
 // Schema  - Schema to be returned
 // based on 2.13 of the open service broker api. https://github.com/avade/servicebroker/blob/cda8c57b6a4bb7eaee84be20bb52dc155269758a/spec.md
 type Schema struct {
@@ -113,6 +127,7 @@ type Plan struct {
 	Parameters     []ParameterDescriptor  `json:"parameters"`
 	BindParameters []ParameterDescriptor  `json:"bind_parameters,omitempty" yaml:"bind_parameters,omitempty"`
 	UpdatesTo      []string               `json:"updates_to,omitempty" yaml:"updates_to,omitempty"`
+	Credentials    []CredentialDescriptor `json:"credentials,omitempty"`
 }
 
 // SchemaPlan - Plan object describing an APB deployment plan and associated parameters
@@ -125,6 +140,7 @@ type SchemaPlan struct {
 	Bindable    bool                   `json:"bindable,omitempty"`
 	UpdatesTo   []string               `json:"updates_to,omitempty" yaml:"updates_to,omitempty"`
 	Schemas     Schema                 `json:"schema,omitempty"`
+	Credentials []CredentialDescriptor `json:"credentials,omitempty"`
 }
 
 // GetParameter - retrieves a reference to a ParameterDescriptor from a plan by name. Will return
@@ -153,6 +169,47 @@ type Spec struct {
 	Plans       []Plan                 `json:"plans"`
 	Alpha       map[string]interface{} `json:"alpha,omitempty"`
 	Delete      bool                   `json:"delete"`
+	// Credentials - the credentials every plan returns when bound, plans
+	// add to and override them.
+	Credentials []CredentialDescriptor `json:"credentials,omitempty"`
+	// BundleVersion - the version of the bundle the spec was loaded from,
+	// e.g. the image tag or the chart version. Version is the version of
+	// the spec format.
+	BundleVersion string `json:"bundle_version,omitempty" yaml:"bundle_version,omitempty"`
+	// ImageDigest - the manifest digest of the image when the spec was
+	// loaded, the tag of the image may move later.
+	ImageDigest string `json:"image_digest,omitempty" yaml:"-"`
+}
+
+// BundleName - the name the versions of the bundle share. Specs published
+// next to other versions of the bundle are renamed, the shared name is kept
+// in the metadata.
+func (s *Spec) BundleName() string {
+	if name, ok := s.Metadata[BundleNameMetadataKey].(string); ok && name != "" {
+		return name
+	}
+	return s.FQName
+}
+
+// PinImage - the image reference pinned to the digest, e.g.
+// docker.io/org/foo:latest is docker.io/org/foo@sha256:... The tag or digest
+// of the reference is replaced.
+func PinImage(image, digest string) string {
+	if digest == "" {
+		return image
+	}
+	if at := strings.Index(image, "@"); at != -1 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+	return fmt.Sprintf("%s@%s", image, digest)
+}
+
+// SpecID - the deterministic ID of a version of a bundle.
+func SpecID(bundleName, bundleVersion string) string {
+	return uuid.NewSHA1(uuid.NameSpace_URL, []byte(fmt.Sprintf("bundle:%s@%s", bundleName, bundleVersion))).String()
 }
 
 // GetPlan - retrieves a plan from a spec by name. Will return
@@ -181,6 +238,25 @@ func (s *Spec) GetPlanFromID(id string) (Plan, bool) {
 type Context struct {
 	Platform  string `json:"platform"`
 	Namespace string `json:"namespace"`
+	// AdditionalNamespaces - namespaces the bundle is given access to on top
+	// of Namespace, for example a shared data namespace. The user access is
+	// checked by ValidateTargets.
+	AdditionalNamespaces []string `json:"additional_namespaces,omitempty"`
+}
+
+// Targets - Returns the namespaces the bundle should be given access to. The
+// context namespace is always first, duplicates and empty names are dropped.
+func (c *Context) Targets() []string {
+	targets := []string{}
+	seen := map[string]bool{}
+	for _, ns := range append([]string{c.Namespace}, c.AdditionalNamespaces...) {
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		targets = append(targets, ns)
+	}
+	return targets
 }
 
 // ExtractedCredentials - Credentials that are extracted from the pods
@@ -225,6 +301,9 @@ const (
 
 	// JobMethodUpdate - Update MethodType const.
 	JobMethodUpdate JobMethod = "update"
+
+	// JobMethodRotate - Rotate MethodType const.
+	JobMethodRotate JobMethod = "rotate"
 )
 
 // JobState - The job state
@@ -271,10 +350,29 @@ const (
 	ProvisionCredentialsKey = "_apb_provision_creds"
 	// BindCredentialsKey parameter name passed to APBs
 	BindCredentialsKey = "_apb_bind_creds"
+	// BindingIDsKey parameter name holding the ids of the bindings of the
+	// instance, passed to the rotate action
+	BindingIDsKey = "_apb_binding_ids"
 	// ClusterKey parameter name passed to APBs
 	ClusterKey = "cluster"
 	// NamespaceKey parameter name passed to APBs
 	NamespaceKey = "namespace"
+	// MetadataKey parameter name passed to APBs holding information about
+	// the context the bundle runs in, such as the target namespaces.
+	MetadataKey = "_apb_metadata"
+	// PlanKey parameter name holding the name of the plan the bundle runs
+	// with
+	PlanKey = "_apb_plan_id"
+
+	// BundleNameMetadataKey spec metadata holding the name the versions of
+	// a bundle share
+	BundleNameMetadataKey = "bundleName"
+	// BundleVersionsMetadataKey spec metadata listing the published
+	// versions of a bundle, highest first
+	BundleVersionsMetadataKey = "bundleVersions"
+	// OriginalImageMetadataKey spec metadata holding the image reference
+	// the registry loaded, before it was rewritten to a mirror
+	OriginalImageMetadataKey = "originalImage"
 )
 
 // SpecLogDump - log spec for debug
@@ -338,6 +436,58 @@ type ServiceInstance struct {
 	Parameters   *Parameters     `json:"parameters"`
 	BindingIDs   map[string]bool `json:"binding_ids"`
 	DashboardURL string          `json:"dashboard_url"`
+	// BundleVersion - the version of the bundle the instance runs, recorded
+	// when a provision or update succeeds.
+	BundleVersion string `json:"bundle_version,omitempty"`
+	// ImageDigest - the manifest digest of the image the instance was
+	// provisioned or last upgraded with, the actions of the instance run it.
+	ImageDigest string `json:"image_digest,omitempty"`
+}
+
+// Image - the image the actions of the instance run, the image of the spec
+// pinned to the digest of the instance. A moved tag does not change the
+// code an instance runs until it is upgraded.
+func (si *ServiceInstance) Image() string {
+	if si.Spec == nil {
+		return ""
+	}
+	return PinImage(si.Spec.Image, si.ImageDigest)
+}
+
+// UpgradeImage - unpin the image of the instance, the next update runs the
+// image of its spec and pins its digest.
+func (si *ServiceInstance) UpgradeImage() {
+	si.ImageDigest = ""
+}
+
+// pinImage - pin the instance to the digest of the image of its spec before
+// an action runs it, so the action runs the image every later action runs.
+// Instances already pinned keep their digest. The previous digest is
+// returned to restore when the action fails.
+func (si *ServiceInstance) pinImage() string {
+	previous := si.ImageDigest
+	if si.ImageDigest == "" {
+		si.ImageDigest = si.Spec.ImageDigest
+	}
+	return previous
+}
+
+// ChangeVersion - switch the instance to another version of its bundle, the
+// next update runs the spec. BundleVersion moves when the update succeeds,
+// ImageDigest when it starts.
+func (si *ServiceInstance) ChangeVersion(spec *Spec) error {
+	if si.Spec == nil || spec == nil {
+		return fmt.Errorf("unable to change the version of an instance without a spec")
+	}
+	if spec.BundleVersion == "" {
+		return fmt.Errorf("spec %v does not have a bundle version", spec.FQName)
+	}
+	if si.Spec.BundleName() != spec.BundleName() {
+		return fmt.Errorf("spec %v is not a version of bundle %v", spec.FQName, si.Spec.BundleName())
+	}
+	si.Spec = spec
+	si.UpgradeImage()
+	return nil
 }
 
 // AddBinding - Add binding ID to service instance
@@ -374,7 +524,7 @@ func (bi *BindInstance) UserParameters() Parameters {
 	for key, value := range *bi.Parameters {
 		switch key {
 		// Do not copy keys that are generally added by the broker itself.
-		case ClusterKey, NamespaceKey, ProvisionCredentialsKey:
+		case ClusterKey, NamespaceKey, MetadataKey, ProvisionCredentialsKey:
 			continue
 		}
 		userparams[key] = value
@@ -925,3 +925,73 @@ func TestNewSpecManifest(t *testing.T) {
 		})
 	}
 }
+
+func TestSpecID(t *testing.T) {
+	assert.Equal(t, SpecID("mariadb", "1.0.0"), SpecID("mariadb", "1.0.0"))
+	assert.NotEqual(t, SpecID("mariadb", "1.0.0"), SpecID("mariadb", "2.0.0"))
+	assert.NotEqual(t, SpecID("mariadb", "1.0.0"), SpecID("postgresql", "1.0.0"))
+	assert.NotNil(t, uuid.Parse(SpecID("mariadb", "1.0.0")))
+}
+
+func TestServiceInstanceChangeVersion(t *testing.T) {
+	current := &Spec{FQName: "mariadb", BundleVersion: "2.0.0"}
+	older := &Spec{
+		FQName:        "mariadb-1.0.0",
+		BundleVersion: "1.0.0",
+		Metadata:      map[string]interface{}{BundleNameMetadataKey: "mariadb"},
+	}
+	other := &Spec{FQName: "postgresql", BundleVersion: "1.0.0"}
+	unversioned := &Spec{FQName: "mariadb"}
+
+	si := &ServiceInstance{Spec: current, BundleVersion: "2.0.0"}
+	assert.Error(t, si.ChangeVersion(other))
+	assert.Error(t, si.ChangeVersion(unversioned))
+	assert.Error(t, si.ChangeVersion(nil))
+	assert.Equal(t, current, si.Spec)
+
+	si.ImageDigest = "sha256:a"
+	assert.NoError(t, si.ChangeVersion(older))
+	assert.Equal(t, older, si.Spec)
+	assert.Equal(t, "", si.ImageDigest)
+	// the recorded version moves once the update succeeds
+	assert.Equal(t, "2.0.0", si.BundleVersion)
+	assert.NoError(t, si.ChangeVersion(current))
+}
+
+func TestPinImage(t *testing.T) {
+	testCases := []struct {
+		image    string
+		digest   string
+		expected string
+	}{
+		{"docker.io/org/foo:latest", "sha256:a", "docker.io/org/foo@sha256:a"},
+		{"registry:5000/org/foo:1.0", "sha256:a", "registry:5000/org/foo@sha256:a"},
+		{"registry:5000/org/foo", "sha256:a", "registry:5000/org/foo@sha256:a"},
+		{"org/foo@sha256:b", "sha256:a", "org/foo@sha256:a"},
+		{"org/foo:1.0@sha256:b", "sha256:a", "org/foo@sha256:a"},
+		{"org/foo:1.0", "", "org/foo:1.0"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, PinImage(tc.image, tc.digest))
+	}
+}
+
+func TestServiceInstanceImage(t *testing.T) {
+	si := &ServiceInstance{Spec: &Spec{Image: "docker.io/org/foo:latest", ImageDigest: "sha256:a"}}
+	assert.Equal(t, "docker.io/org/foo:latest", si.Image())
+	assert.Equal(t, "", si.pinImage())
+	assert.Equal(t, "docker.io/org/foo@sha256:a", si.Image())
+
+	// the tag moved, the instance keeps the image it was provisioned with
+	si.Spec = &Spec{Image: "docker.io/org/foo@sha256:b", ImageDigest: "sha256:b"}
+	assert.Equal(t, "docker.io/org/foo@sha256:a", si.Image())
+	assert.Equal(t, "sha256:a", si.pinImage())
+	assert.Equal(t, "sha256:a", si.ImageDigest)
+
+	si.UpgradeImage()
+	assert.Equal(t, "docker.io/org/foo@sha256:b", si.Image())
+	assert.Equal(t, "", si.pinImage())
+	assert.Equal(t, "sha256:b", si.ImageDigest)
+
+	assert.Equal(t, "", (&ServiceInstance{}).Image())
+}
@@ -48,7 +48,7 @@ func mockCommonUnbind(rt *runtime.MockRuntime, e Executor) {
 	).Return(nil)
 	rt.On("DestroySandbox",
 		mock.Anything, mock.Anything, mock.Anything,
-		mock.Anything, mock.Anything, mock.Anything)
+		mock.Anything, mock.Anything, mock.Anything).Return(nil)
 }
 
 func TestUnbind(t *testing.T) {
@@ -222,7 +222,7 @@ func TestUnbind(t *testing.T) {
 
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 				rt.On("CopyState",
 					mock.Anything, mock.Anything, mock.Anything, mock.Anything,
@@ -352,7 +352,7 @@ func TestUnbindFailure(t *testing.T) {
 
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -394,7 +394,7 @@ func TestUnbindFailure(t *testing.T) {
 
 				rt.On("DestroySandbox",
 					mock.Anything, mock.Anything, mock.Anything,
-					mock.Anything, mock.Anything, mock.Anything)
+					mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
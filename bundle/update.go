@@ -34,21 +34,26 @@ func (e *executor) Update(instance *ServiceInstance) <-chan StatusMessage {
 
 	go func() {
 		e.actionStarted()
+		snapshot := e.snapshotState(instance, string(executionMethodUpdate))
+		previousDigest := instance.pinImage()
 		err := e.provisionOrUpdate(executionMethodUpdate, instance)
 		if err != nil {
 			log.Errorf("Update APB error: %v", err)
-			e.actionFinishedWithError(err)
+			instance.ImageDigest = previousDigest
+			e.actionFinishedWithError(e.rollbackState(snapshot, err))
 			return
 		}
 		if e.extractedCredentials != nil {
-			labels := map[string]string{"bundleAction": string(executionMethodUpdate), "bundleName": instance.Spec.FQName}
+			labels := map[string]string{CredentialsActionLabel: string(executionMethodUpdate), CredentialsBundleLabel: instance.Spec.FQName}
 			err := runtime.Provider.UpdateExtractedCredential(instance.ID.String(), clusterConfig.Namespace, e.extractedCredentials.Credentials, labels)
 			if err != nil {
 				log.Errorf("apb::%v error occurred - %v", executionMethodUpdate, err)
-				e.actionFinishedWithError(err)
+				instance.ImageDigest = previousDigest
+				e.actionFinishedWithError(e.rollbackState(snapshot, err))
 				return
 			}
 		}
+		instance.BundleVersion = instance.Spec.BundleVersion
 		e.actionFinishedWithSuccess()
 	}()
 
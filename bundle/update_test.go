@@ -50,7 +50,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -96,7 +96,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "update", "bundleName": "new-fq-name"}).Return(nil)
 			},
@@ -153,7 +153,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					ex.updateDescription("dashboard url", "https://url.com")
 				}).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "update", "bundleName": "new-fq-name"}).Return(nil)
 			},
@@ -209,7 +209,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -249,7 +249,7 @@ func TestUpdate(t *testing.T) {
 			addExpectations: func(rt *runtime.MockRuntime, e Executor) {
 				rt.On("CreateSandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("service-account-1", "", nil)
 				rt.On("GetRuntime").Return("kubernetes")
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -301,7 +301,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					ex.updateDescription("dashboard url", "https://url.com")
 				}).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`{"test": "testingcreds"}`), nil)
 				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "update", "bundleName": "new-fq-name"}).Return(fmt.Errorf("unable to update credentials"))
 			},
@@ -352,7 +352,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("unable to watch bundle"))
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -398,7 +398,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("unable to extract credentials"))
 				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "update", "bundleName": "new-fq-name"}).Return(nil)
 			},
@@ -446,7 +446,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("unable to copy state"))
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 			},
 			validateMessage: func(m []StatusMessage) bool {
 				if len(m) != 2 {
@@ -492,7 +492,7 @@ func TestUpdate(t *testing.T) {
 				rt.On("RunBundle", mock.Anything).Return(runtime.ExecutionContext{}, nil)
 				rt.On("CopyState", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("WatchRunningBundle", mock.Anything, mock.Anything, mock.Anything).Return(nil)
-				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				rt.On("DestroySandbox", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 				rt.On("ExtractCredentials", mock.Anything, mock.Anything, mock.Anything).Return([]byte(`"test": "testingcreds"}`), nil)
 				rt.On("UpdateExtractedCredential", u.String(), mock.Anything, map[string]interface{}{"test": "testingcreds"}, map[string]string{"bundleAction": "update", "bundleName": "new-fq-name"}).Return(nil)
 			},
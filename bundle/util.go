@@ -50,6 +50,7 @@ func ConvertPlansToSchema(plans []Plan) ([]SchemaPlan, error) {
 			Bindable:    plan.Bindable,
 			UpdatesTo:   plan.UpdatesTo,
 			Schemas:     schemas,
+			Credentials: plan.Credentials,
 		}
 	}
 	return brokerPlans, nil
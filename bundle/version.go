@@ -39,7 +39,7 @@ const MaxSpecVersion = "1.0.0"
 const MinRuntimeVersion = 1
 
 // MaxRuntimeVersion constant to describe maximum supported runtime version
-const MaxRuntimeVersion = 2
+const MaxRuntimeVersion = 3
 
 // The minimum/maximum Bundle spec semantic versions
 var minSpecSemver = semver.New("1.0.0")
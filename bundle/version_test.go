@@ -35,6 +35,9 @@ func TestValidateVersion(t *testing.T) {
 	testSpec.Runtime = 2
 	ft.True(t, testSpec.ValidateVersion())
 
+	testSpec.Runtime = 3
+	ft.True(t, testSpec.ValidateVersion())
+
 	testSpec.Version = "1.0" // Deprecated Spec Version
 	ft.True(t, testSpec.ValidateVersion())
 
@@ -52,6 +55,6 @@ func TestValidateVersion(t *testing.T) {
 	testSpec.Runtime = 0
 	ft.False(t, testSpec.ValidateVersion()) // less than min
 
-	testSpec.Runtime = 3
+	testSpec.Runtime = 4
 	ft.False(t, testSpec.ValidateVersion()) // greater than max
 }
@@ -24,6 +24,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -166,6 +167,26 @@ func (k KubernetesClient) DeleteExtractedCredentialSecret(instanceID, ns string)
 	return nil
 }
 
+// ListExtractedCredentialSecrets - Lists the extracted credentials secrets
+// in a namespace having all of the labels of the selector. Secrets without
+// credentials are skipped.
+func (k KubernetesClient) ListExtractedCredentialSecrets(ns string, selector map[string]string) ([]apiv1.Secret, error) {
+	list, err := k.Client.CoreV1().Secrets(ns).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set(selector)).String(),
+	})
+	if err != nil {
+		log.Errorf("Unable to list secrets in namespace '%v'", ns)
+		return nil, err
+	}
+	secrets := []apiv1.Secret{}
+	for _, s := range list.Items {
+		if _, ok := s.Data[credentialsKey]; ok {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets, nil
+}
+
 // GetPodStatus - Returns the current status of a pod in a specified namespace
 func (k KubernetesClient) GetPodStatus(podName, namespace string) (*apiv1.PodStatus, error) {
 	pod, err := k.Client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 
@@ -27,6 +28,28 @@ import (
 	"github.com/automationbroker/bundle-lib/bundle"
 
 	"github.com/pborman/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The BundleInstance CRD has no fields for these, they are kept as
+// annotations. The broker must keep the annotations of the BundleInstance
+// that ConvertServiceInstanceToCRD returns.
+const (
+	// AdditionalNamespacesAnnotation - the additional namespaces of the
+	// context of the instance, comma separated.
+	AdditionalNamespacesAnnotation = "automationbroker.io/additional-namespaces"
+	// BundleVersionAnnotation - the version of the bundle the instance runs.
+	BundleVersionAnnotation = "automationbroker.io/bundle-version"
+	// ImageDigestAnnotation - the digest of the image the instance runs.
+	ImageDigestAnnotation = "automationbroker.io/image-digest"
+)
+
+// The Bundle CRD has no fields for these, they are kept in the alpha of the
+// spec or the metadata of the plan under reserved keys.
+const (
+	bundleVersionAlphaKey = "_bundle_version"
+	imageDigestAlphaKey   = "_image_digest"
+	credentialsKey        = "_credentials"
 )
 
 type arrayErrors []error
@@ -45,7 +68,7 @@ func ConvertSpecToBundle(spec *bundle.Spec) (v1alpha1.BundleSpec, error) {
 	}
 	plans := []v1alpha1.Plan{}
 	// encode the alpha as string
-	alphaBytes, err := json.Marshal(spec.Alpha)
+	alphaBytes, err := json.Marshal(specAlpha(spec))
 	if err != nil {
 		log.Errorf("unable to marshal the alpha for spec to a json byte array - %v", err)
 		return v1alpha1.BundleSpec{}, err
@@ -100,6 +123,21 @@ func ConvertBundleToSpec(spec v1alpha1.BundleSpec, id string) (*bundle.Spec, err
 		log.Errorf("unable to unmarshal the alpha for spec - %v", err)
 		return &bundle.Spec{}, err
 	}
+	var bundleVersion string
+	if err := popReserved(alphaMap, bundleVersionAlphaKey, &bundleVersion); err != nil {
+		log.Errorf("unable to unmarshal the bundle version for spec - %v", err)
+		return &bundle.Spec{}, err
+	}
+	var imageDigest string
+	if err := popReserved(alphaMap, imageDigestAlphaKey, &imageDigest); err != nil {
+		log.Errorf("unable to unmarshal the image digest for spec - %v", err)
+		return &bundle.Spec{}, err
+	}
+	var credentials []bundle.CredentialDescriptor
+	if err := popReserved(alphaMap, credentialsKey, &credentials); err != nil {
+		log.Errorf("unable to unmarshal the credentials for spec - %v", err)
+		return &bundle.Spec{}, err
+	}
 	errs := arrayErrors{}
 	for _, specPlan := range spec.Plans {
 		plan, err := convertPlanToAPB(specPlan)
@@ -115,19 +153,22 @@ func ConvertBundleToSpec(spec v1alpha1.BundleSpec, id string) (*bundle.Spec, err
 	}
 
 	return &bundle.Spec{
-		ID:          id,
-		Runtime:     spec.Runtime,
-		Version:     spec.Version,
-		FQName:      spec.FQName,
-		Image:       spec.Image,
-		Tags:        spec.Tags,
-		Bindable:    spec.Bindable,
-		Description: spec.Description,
-		Async:       convertAsyncTypeToString(spec.Async),
-		Metadata:    metadataMap,
-		Alpha:       alphaMap,
-		Plans:       plans,
-		Delete:      spec.Delete,
+		ID:            id,
+		Runtime:       spec.Runtime,
+		Version:       spec.Version,
+		FQName:        spec.FQName,
+		Image:         spec.Image,
+		Tags:          spec.Tags,
+		Bindable:      spec.Bindable,
+		Description:   spec.Description,
+		Async:         convertAsyncTypeToString(spec.Async),
+		Metadata:      metadataMap,
+		Alpha:         alphaMap,
+		Plans:         plans,
+		Delete:        spec.Delete,
+		BundleVersion: bundleVersion,
+		ImageDigest:   imageDigest,
+		Credentials:   credentials,
 	}, nil
 }
 
@@ -149,7 +190,23 @@ func ConvertServiceInstanceToCRD(si *bundle.ServiceInstance) (v1alpha1.BundleIns
 		bindings = append(bindings, v1alpha1.LocalObjectReference{Name: key})
 	}
 
+	annotations := map[string]string{}
+	if len(si.Context.AdditionalNamespaces) > 0 {
+		annotations[AdditionalNamespacesAnnotation] = strings.Join(si.Context.AdditionalNamespaces, ",")
+	}
+	if si.BundleVersion != "" {
+		annotations[BundleVersionAnnotation] = si.BundleVersion
+	}
+	if si.ImageDigest != "" {
+		annotations[ImageDigestAnnotation] = si.ImageDigest
+	}
+	var meta metav1.ObjectMeta
+	if len(annotations) > 0 {
+		meta.Annotations = annotations
+	}
+
 	return v1alpha1.BundleInstance{
+		ObjectMeta: meta,
 		Spec: v1alpha1.BundleInstanceSpec{
 			Bundle: v1alpha1.LocalObjectReference{Name: si.Spec.ID},
 			Context: v1alpha1.Context{
@@ -190,12 +247,15 @@ func ConvertServiceInstanceToAPB(si v1alpha1.BundleInstance, spec *bundle.Spec,
 		ID:   uuid.Parse(id),
 		Spec: spec,
 		Context: &bundle.Context{
-			Namespace: si.Spec.Context.Namespace,
-			Platform:  si.Spec.Context.Platform,
+			Namespace:            si.Spec.Context.Namespace,
+			Platform:             si.Spec.Context.Platform,
+			AdditionalNamespaces: splitAnnotation(si.Annotations[AdditionalNamespacesAnnotation]),
 		},
-		Parameters:   parameters,
-		BindingIDs:   bindingIDs,
-		DashboardURL: si.Spec.DashboardURL,
+		Parameters:    parameters,
+		BindingIDs:    bindingIDs,
+		DashboardURL:  si.Spec.DashboardURL,
+		BundleVersion: si.Annotations[BundleVersionAnnotation],
+		ImageDigest:   si.Annotations[ImageDigestAnnotation],
 	}, nil
 }
 
@@ -288,6 +348,9 @@ func ConvertJobMethodToCRD(j bundle.JobMethod) v1alpha1.JobMethod {
 		return v1alpha1.JobMethodUnbind
 	case bundle.JobMethodUpdate:
 		return v1alpha1.JobMethodUpdate
+	case bundle.JobMethodRotate:
+		// The crd has no rotate method, a rotation updates the instance.
+		return v1alpha1.JobMethodUpdate
 	}
 	log.Errorf("unable to find the job method - %v", j)
 	// This should never be called as all cases should already be covered.
@@ -317,6 +380,68 @@ func ConvertJobMethodToAPB(j v1alpha1.JobMethod) bundle.JobMethod {
 // Internal
 ////////////////////////////////////////////////////////////
 
+// specAlpha - the alpha of the spec with the spec fields the Bundle CRD has
+// no fields for.
+func specAlpha(spec *bundle.Spec) map[string]interface{} {
+	extra := map[string]interface{}{}
+	if spec.BundleVersion != "" {
+		extra[bundleVersionAlphaKey] = spec.BundleVersion
+	}
+	if spec.ImageDigest != "" {
+		extra[imageDigestAlphaKey] = spec.ImageDigest
+	}
+	if len(spec.Credentials) > 0 {
+		extra[credentialsKey] = spec.Credentials
+	}
+	return withReserved(spec.Alpha, extra)
+}
+
+// planMetadata - the metadata of the plan with the plan fields the Bundle
+// CRD has no fields for.
+func planMetadata(plan bundle.Plan) map[string]interface{} {
+	extra := map[string]interface{}{}
+	if len(plan.Credentials) > 0 {
+		extra[credentialsKey] = plan.Credentials
+	}
+	return withReserved(plan.Metadata, extra)
+}
+
+// withReserved - a copy of m with the reserved keys added, m itself when
+// there are none.
+func withReserved(m map[string]interface{}, extra map[string]interface{}) map[string]interface{} {
+	if len(extra) == 0 {
+		return m
+	}
+	for key, value := range m {
+		extra[key] = value
+	}
+	return extra
+}
+
+// popReserved - decodes the value of the reserved key into v and removes it
+// from the alpha or metadata. v is left alone when the key is not set.
+func popReserved(m map[string]interface{}, key string, v interface{}) error {
+	value, ok := m[key]
+	if !ok {
+		return nil
+	}
+	delete(m, key)
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// splitAnnotation - the values of a comma separated annotation, nil when
+// it is not set.
+func splitAnnotation(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
 func convertToAsyncType(s string) v1alpha1.AsyncType {
 	switch s {
 	case "optional":
@@ -335,7 +460,7 @@ func convertToAsyncType(s string) v1alpha1.AsyncType {
 }
 
 func convertPlanToCRD(plan bundle.Plan) (v1alpha1.Plan, error) {
-	b, err := json.Marshal(plan.Metadata)
+	b, err := json.Marshal(planMetadata(plan))
 	if err != nil {
 		log.Errorf("unable to marshal the metadata for plan to a json byte array - %v", err)
 		return v1alpha1.Plan{}, err
@@ -450,6 +575,11 @@ func convertPlanToAPB(plan v1alpha1.Plan) (bundle.Plan, error) {
 		log.Errorf("unable to unmarshal the metadata for plan - %v", err)
 		return bundle.Plan{}, err
 	}
+	var credentials []bundle.CredentialDescriptor
+	if err := popReserved(m, credentialsKey, &credentials); err != nil {
+		log.Errorf("unable to unmarshal the credentials for plan - %v", err)
+		return bundle.Plan{}, err
+	}
 
 	bindParams := []bundle.ParameterDescriptor{}
 	params := []bundle.ParameterDescriptor{}
@@ -486,6 +616,7 @@ func convertPlanToAPB(plan v1alpha1.Plan) (bundle.Plan, error) {
 		UpdatesTo:      plan.UpdatesTo,
 		Parameters:     params,
 		BindParameters: bindParams,
+		Credentials:    credentials,
 	}, nil
 }
 
@@ -1199,3 +1199,77 @@ func TestConvertToAsyncType(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertServiceInstanceRoundTrip(t *testing.T) {
+	uid := uuid.New()
+	spec := &bundle.Spec{ID: "spec-id", FQName: "mariadb", Image: "docker.io/org/mariadb:latest"}
+	si := &bundle.ServiceInstance{
+		ID:   uuid.Parse(uid),
+		Spec: spec,
+		Context: &bundle.Context{
+			Namespace:            "app",
+			Platform:             "kubernetes",
+			AdditionalNamespaces: []string{"data", "cache"},
+		},
+		Parameters:    &bundle.Parameters{"foo": "bar"},
+		BindingIDs:    map[string]bool{},
+		BundleVersion: "1.0.0",
+		ImageDigest:   "sha256:a",
+	}
+
+	crd, err := ConvertServiceInstanceToCRD(si)
+	if err != nil {
+		t.Fatalf("unexpected error during test: %v\n", err)
+	}
+	assert.Equal(t, "data,cache", crd.Annotations[AdditionalNamespacesAnnotation])
+	// the version the instance runs, not the one of its spec
+	assert.Equal(t, "1.0.0", crd.Annotations[BundleVersionAnnotation])
+	assert.Equal(t, "sha256:a", crd.Annotations[ImageDigestAnnotation])
+
+	output, err := ConvertServiceInstanceToAPB(crd, spec, uid)
+	if err != nil {
+		t.Fatalf("unexpected error during test: %v\n", err)
+	}
+	assert.Equal(t, si, output)
+	assert.Equal(t, []string{"app", "data", "cache"}, output.Context.Targets())
+}
+
+func TestConvertSpecRoundTrip(t *testing.T) {
+	spec := &bundle.Spec{
+		ID:            "spec-id",
+		FQName:        "mariadb",
+		Image:         "docker.io/org/mariadb:2.0.0",
+		Async:         "optional",
+		Metadata:      map[string]interface{}{"displayName": "MariaDB"},
+		Alpha:         map[string]interface{}{"foo": "bar"},
+		BundleVersion: "2.0.0",
+		ImageDigest:   "sha256:b",
+		Credentials: []bundle.CredentialDescriptor{
+			{Name: "host", Type: "string", Required: true},
+		},
+		Plans: []bundle.Plan{
+			{
+				Name:           "prod",
+				Metadata:       map[string]interface{}{"displayName": "Production"},
+				Parameters:     []bundle.ParameterDescriptor{},
+				BindParameters: []bundle.ParameterDescriptor{},
+				Credentials: []bundle.CredentialDescriptor{
+					{Name: "password", Type: "string", Sensitive: true},
+				},
+			},
+		},
+	}
+
+	b, err := ConvertSpecToBundle(spec)
+	if err != nil {
+		t.Fatalf("unexpected error during test: %v\n", err)
+	}
+	output, err := ConvertBundleToSpec(b, "spec-id")
+	if err != nil {
+		t.Fatalf("unexpected error during test: %v\n", err)
+	}
+	assert.Equal(t, spec, output)
+	// the spec is not changed by the conversion
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, spec.Alpha)
+	assert.Equal(t, map[string]interface{}{"displayName": "Production"}, spec.Plans[0].Metadata)
+}
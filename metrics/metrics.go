@@ -24,7 +24,12 @@ import (
 )
 
 const (
-	sandboxGuageName = "bundlelib_sandbox"
+	sandboxGuageName      = "bundlelib_sandbox"
+	sandboxPoolGuageName  = "bundlelib_sandbox_pool"
+	sandboxPoolHitsName   = "bundlelib_sandbox_pool_hits"
+	sandboxPoolMissesName = "bundlelib_sandbox_pool_misses"
+	specCacheHitsName     = "bundlelib_spec_cache_hits"
+	specCacheMissesName   = "bundlelib_spec_cache_misses"
 )
 
 var (
@@ -34,7 +39,12 @@ var (
 
 // Collector - collects bundlelib metrics
 type Collector struct {
-	Sandbox prom.Gauge
+	Sandbox           prom.Gauge
+	SandboxPool       prom.Gauge
+	SandboxPoolHits   prom.Counter
+	SandboxPoolMisses prom.Counter
+	SpecCacheHits     *prom.CounterVec
+	SpecCacheMisses   *prom.CounterVec
 }
 
 // We will never want to panic our app because of metric saving.
@@ -55,6 +65,26 @@ func RegisterCollector() {
 				Name: sandboxGuageName,
 				Help: "Guage of all sandbox namespaces that are active.",
 			}),
+			SandboxPool: prom.NewGauge(prom.GaugeOpts{
+				Name: sandboxPoolGuageName,
+				Help: "Guage of the sandbox namespaces that are ready in the pool.",
+			}),
+			SandboxPoolHits: prom.NewCounter(prom.CounterOpts{
+				Name: sandboxPoolHitsName,
+				Help: "Counter of the sandboxes claimed from the pool.",
+			}),
+			SandboxPoolMisses: prom.NewCounter(prom.CounterOpts{
+				Name: sandboxPoolMissesName,
+				Help: "Counter of the sandboxes that could not be claimed from the pool.",
+			}),
+			SpecCacheHits: prom.NewCounterVec(prom.CounterOpts{
+				Name: specCacheHitsName,
+				Help: "Counter of the specs loaded from the spec cache.",
+			}, []string{"registry"}),
+			SpecCacheMisses: prom.NewCounterVec(prom.CounterOpts{
+				Name: specCacheMissesName,
+				Help: "Counter of the specs fetched from the registry by the spec cache.",
+			}, []string{"registry"}),
 		}
 
 		err := prom.Register(collector)
@@ -76,12 +106,55 @@ func SandboxDeleted() {
 	collector.Sandbox.Dec()
 }
 
+// SandboxPoolSize - Guage for how many sandboxes are ready in the pool.
+func SandboxPoolSize(size int) {
+	defer recoverMetricPanic()
+	collector.SandboxPool.Set(float64(size))
+}
+
+// SandboxPoolHit - Counter for how many sandboxes were claimed from the pool.
+func SandboxPoolHit() {
+	defer recoverMetricPanic()
+	collector.SandboxPoolHits.Inc()
+}
+
+// SandboxPoolMiss - Counter for how many sandboxes could not be claimed from
+// the pool.
+func SandboxPoolMiss() {
+	defer recoverMetricPanic()
+	collector.SandboxPoolMisses.Inc()
+}
+
+// SpecCacheHit - Counter for how many specs of the registry were loaded from
+// the spec cache.
+func SpecCacheHit(registry string) {
+	defer recoverMetricPanic()
+	collector.SpecCacheHits.WithLabelValues(registry).Inc()
+}
+
+// SpecCacheMiss - Counter for how many specs of the registry were fetched
+// from the registry by the spec cache.
+func SpecCacheMiss(registry string) {
+	defer recoverMetricPanic()
+	collector.SpecCacheMisses.WithLabelValues(registry).Inc()
+}
+
 // Describe - returns all the descriptions of the collector
 func (c Collector) Describe(ch chan<- *prom.Desc) {
 	c.Sandbox.Describe(ch)
+	c.SandboxPool.Describe(ch)
+	c.SandboxPoolHits.Describe(ch)
+	c.SandboxPoolMisses.Describe(ch)
+	c.SpecCacheHits.Describe(ch)
+	c.SpecCacheMisses.Describe(ch)
 }
 
 // Collect - returns the current state of the metrics
 func (c Collector) Collect(ch chan<- prom.Metric) {
 	c.Sandbox.Collect(ch)
+	c.SandboxPool.Collect(ch)
+	c.SandboxPoolHits.Collect(ch)
+	c.SandboxPoolMisses.Collect(ch)
+	c.SpecCacheHits.Collect(ch)
+	c.SpecCacheMisses.Collect(ch)
 }
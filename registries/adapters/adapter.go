@@ -18,12 +18,14 @@ package adapters
 
 import (
 	"bytes"
+	"crypto/sha256"
 	b64 "encoding/base64"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"fmt"
 
@@ -43,6 +45,30 @@ type Adapter interface {
 	FetchSpecs([]string) ([]*bundle.Spec, error)
 }
 
+// ImageSpecAdapter - Adapter that reports why the specs of an image could
+// not be fetched. The spec cache only caches the specs of an image that was
+// fetched without an error.
+type ImageSpecAdapter interface {
+	Adapter
+	// FetchImageSpecs will return the specs of the image, and the error of
+	// the last tag that could not be loaded.
+	FetchImageSpecs(string) ([]*bundle.Spec, error)
+}
+
+// DigestAdapter - Adapter that can look up the manifest digest of an image
+// without fetching its spec. The spec cache only refetches the specs of the
+// images whose digest changed.
+type DigestAdapter interface {
+	Adapter
+	// ImageDigest will return the manifest digest of the configured tag of
+	// the image.
+	ImageDigest(string) (string, error)
+}
+
+// contentDigestHeader - header holding the manifest digest in the responses
+// of a v2 registry.
+const contentDigestHeader = "Docker-Content-Digest"
+
 // BundleSpecLabel - label on the image that we should use to pull out the abp spec.
 const BundleSpecLabel = "com.redhat.apb.spec"
 
@@ -60,11 +86,25 @@ type Configuration struct {
 	Tag           string
 	SkipVerifyTLS bool
 	AdapterName   string
+	// FetchWorkers - how many specs are loaded at once.
+	FetchWorkers int
+	// FetchRetries - how often a transient error is retried, a negative
+	// value disables the retries.
+	FetchRetries int
+	// RateLimit - the spec loads per second sent to the registry host, no
+	// limit when zero.
+	RateLimit float64
+	// Architecture - the platform picked from manifest lists, defaults to
+	// the architecture of the broker.
+	Architecture string
+	// TagPolicy - selects the tags specs are loaded from, Tag when empty.
+	TagPolicy TagPolicy
 }
 
 type registryResponseError struct {
-	code    int
-	message string
+	code       int
+	message    string
+	retryAfter time.Duration
 }
 
 type imageLabel struct {
@@ -102,11 +142,43 @@ func registryResponseHandler(resp *http.Response) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, &registryResponseError{code: resp.StatusCode, message: fmt.Sprintf("unexpected response code %v body %v", resp.StatusCode, string(body))}
+		return nil, newRegistryResponseError(resp, fmt.Sprintf("unexpected response code %v body %v", resp.StatusCode, string(body)))
 	}
 	return body, nil
 }
 
+// newRegistryResponseError - the error of an unexpected registry response,
+// keeping the Retry-After of the registry.
+func newRegistryResponseError(resp *http.Response, message string) *registryResponseError {
+	return &registryResponseError{
+		code:       resp.StatusCode,
+		message:    message,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// manifestDigest - the manifest digest of a HEAD manifest response.
+func manifestDigest(resp *http.Response, imageName string) (string, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", newRegistryResponseError(resp, fmt.Sprintf("unable to get the manifest of %v", imageName))
+	}
+	digest := resp.Header.Get(contentDigestHeader)
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a digest for %v", imageName)
+	}
+	return digest, nil
+}
+
+// contentDigest - the digest of the manifest in the response, the sha256 of
+// the body when the registry does not return it.
+func contentDigest(resp *http.Response, body []byte) string {
+	if digest := resp.Header.Get(contentDigestHeader); digest != "" {
+		return digest
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+}
+
 // Retrieve the spec from a manifest response
 func responseToSpec(response []byte, image string) (*bundle.Spec, error) {
 	mResp := manifestResponse{}
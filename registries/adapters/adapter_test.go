@@ -377,6 +377,17 @@ func TestGetSchemaVersion(t *testing.T) {
 			input:    schema2Ct,
 			expected: 2,
 		},
+		{
+			name:     "oci manifest reads as schema version 2",
+			input:    ociManifestCt,
+			expected: 2,
+		},
+		{
+			name:        "manifest lists are resolved before",
+			input:       ociIndexCt,
+			expected:    0,
+			expectederr: true,
+		},
 		{
 			name:        "invalid schema version string",
 			input:       "invalid version",
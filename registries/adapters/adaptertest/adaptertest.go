@@ -17,6 +17,7 @@
 package adaptertest
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -104,8 +105,8 @@ func GetAPIV2Server(t *testing.T, supportDiscovery bool) *httptest.Server {
 
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", fmt.Sprintf("%s", schema1Ct))
-		if r.Method != "GET" {
-			t.Errorf("Expected `GET` request, got `%s`", r.Method)
+		if r.Method != "GET" && r.Method != "HEAD" {
+			t.Errorf("Expected `GET` or `HEAD` request, got `%s`", r.Method)
 		}
 
 		if strings.HasSuffix(r.URL.EscapedPath(), "/v2/") {
@@ -118,7 +119,12 @@ func GetAPIV2Server(t *testing.T, supportDiscovery bool) *httptest.Server {
 		}
 		if strings.Contains(r.URL.EscapedPath(), "manifests/") {
 			name := strings.Split(r.URL.EscapedPath(), "manifests/")[1]
-			fmt.Fprintf(w, fmt.Sprintf(apiV2ManifestResponse, name))
+			manifest := fmt.Sprintf(apiV2ManifestResponse, name)
+			w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest))))
+			if r.Method == "HEAD" {
+				return
+			}
+			fmt.Fprintf(w, manifest)
 		}
 	}))
 }
@@ -19,23 +19,47 @@ package adapters
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"net/http"
+	goruntime "runtime"
 	"strings"
 
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/registries/adapters/oauth"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
 	apiV2ManifestPath = "/v2/%v/manifests/%v"
 	apiV2CatalogURL   = "%v/v2/_catalog"
+	apiV2TagsPath     = "/v2/%v/tags/list"
 	schema1Ct         = "application/vnd.docker.distribution.manifest.v1+json"
 	schema1CtSigned   = "application/vnd.docker.distribution.manifest.v1+prettyjws"
 	schema2Ct         = "application/vnd.docker.distribution.manifest.v2+json"
+	manifestListCt    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	ociManifestCt     = "application/vnd.oci.image.manifest.v1+json"
+	ociIndexCt        = "application/vnd.oci.image.index.v1+json"
 )
 
+// manifestAccept - every manifest media type the adapter reads.
+var manifestAccept = strings.Join([]string{
+	schema1Ct, schema1CtSigned, schema2Ct, ociManifestCt, manifestListCt, ociIndexCt,
+}, ",")
+
+// manifestList - A docker manifest list or an OCI index, a manifest per
+// platform.
+type manifestList struct {
+	Manifests []struct {
+		MediaType string `json:"mediaType"`
+		Digest    string `json:"digest"`
+		Platform  struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
 // OpenShiftAdapter - OpenShift Adapter
 type OpenShiftAdapter struct {
 	APIV2Adapter
@@ -57,6 +81,11 @@ type apiV2CatalogResponse struct {
 	Repositories []string `json:"repositories"`
 }
 
+// apiV2TagsResponse - Tags list Response
+type apiV2TagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
 // NewOpenShiftAdapter - creates a new OpenShift Adapter
 func NewOpenShiftAdapter(config Configuration) (OpenShiftAdapter, error) {
 	apiV2, err := NewAPIV2Adapter(config)
@@ -94,6 +123,11 @@ func NewAPIV2Adapter(config Configuration) (APIV2Adapter, error) {
 		apiv2a.config.Tag = "latest"
 	}
 
+	// pick the manifests of the cluster's architecture from manifest lists
+	if apiv2a.config.Architecture == "" {
+		apiv2a.config.Architecture = goruntime.GOARCH
+	}
+
 	return apiv2a, nil
 }
 
@@ -158,18 +192,76 @@ func (r APIV2Adapter) GetImageNames() ([]string, error) {
 // FetchSpecs - retrieve the spec for the image names.
 func (r APIV2Adapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
 	log.Debugf("%s - FetchSpecs", r.config.AdapterName)
-	specs := []*bundle.Spec{}
-	for _, imageName := range imageNames {
-		log.Debugf("%v", imageName)
-		spec, err := r.loadSpec(imageName)
+	return r.fetcher().fetch(imageNames), nil
+}
+
+// FetchImageSpecs - retrieve the specs of the image, with the error of a
+// tag that could not be loaded.
+func (r APIV2Adapter) FetchImageSpecs(imageName string) ([]*bundle.Spec, error) {
+	return r.fetcher().fetchImage(imageName)
+}
+
+func (r APIV2Adapter) fetcher() specFetcher {
+	return newSpecFetcher(r.config, r.config.URL.Host, r.listTags, r.loadSpec)
+}
+
+// ImageDigest - retrieve the manifest digest of the selected tags of the
+// image with HEAD requests.
+func (r APIV2Adapter) ImageDigest(imageName string) (string, error) {
+	return r.fetcher().digest(imageName, r.tagDigest)
+}
+
+func (r APIV2Adapter) listTags(imageName string) ([]string, error) {
+	return listV2Tags(r.client, imageName)
+}
+
+// listV2Tags - the tags of the image from /v2/<name>/tags/list, following
+// the 'Link' of every page.
+func listV2Tags(client *oauth.Client, imageName string) ([]string, error) {
+	tags := []string{}
+	path := fmt.Sprintf(apiV2TagsPath, imageName)
+	for path != "" {
+		req, err := client.NewRequest(path)
 		if err != nil {
-			log.Errorf("Failed to retrieve spec data for image %s:%s - %v", imageName, r.config.Tag, err)
+			return nil, err
 		}
-		if spec != nil {
-			specs = append(specs, spec)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := registryResponseHandler(resp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to list the tags of image %v", imageName)
+		}
+		page := apiV2TagsResponse{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		tags = append(tags, page.Tags...)
+
+		// https://docs.docker.com/registry/spec/api/#pagination
+		path = ""
+		if link := resp.Header.Get("Link"); link != "" {
+			path = strings.Trim(strings.TrimSpace(strings.Split(link, ";")[0]), "<>")
 		}
 	}
-	return specs, nil
+	return tags, nil
+}
+
+// tagDigest - the manifest digest of the image at the tag.
+func (r APIV2Adapter) tagDigest(imageName, tag string) (string, error) {
+	req, err := r.client.NewRequest(fmt.Sprintf(apiV2ManifestPath, imageName, tag))
+	if err != nil {
+		return "", err
+	}
+	req.Method = http.MethodHead
+	req.Header.Set("accept", manifestAccept)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	return manifestDigest(resp, imageName)
 }
 
 // discoverImages - Get Imagenames from the /v2/_catalog URL
@@ -256,31 +348,44 @@ func (r APIV2Adapter) getNextImageURL(link string) string {
 	return (r.config.URL.String() + lvalue)
 }
 
-func (r APIV2Adapter) loadSpec(imageName string) (*bundle.Spec, error) {
+func (r APIV2Adapter) loadSpec(imageName, tag string) (*bundle.Spec, error) {
 	log.Debugf("%s - LoadSpec", r.config.AdapterName)
 
-	req, err := r.client.NewRequest(fmt.Sprintf(apiV2ManifestPath, imageName, r.config.Tag))
+	// The digest of a manifest list is pinned, the cluster pulls the image
+	// of its platform from it.
+	body, ct, imageDigest, err := r.getManifest(imageName, tag)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("accept", fmt.Sprintf("%s,%s,%s", schema1Ct, schema1CtSigned, schema2Ct))
+	if isManifestList(ct) {
+		log.Debugf("manifest list for image [%s]", imageName)
+		digest, err := selectPlatformManifest(body, r.config.Architecture)
+		if err != nil {
+			return nil, fmt.Errorf("image [%s] - %v", imageName, err)
+		}
+		body, ct, _, err = r.getManifest(imageName, digest)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	resp, err := r.client.Do(req)
-	if err != nil {
+	spec, err := r.manifestToSpec(imageName, tag, body, ct)
+	if err != nil || spec == nil {
 		return nil, err
 	}
+	spec.ImageDigest = imageDigest
+	return spec, nil
+}
 
-	body, err := registryResponseHandler(resp)
-	if err != nil {
-		return nil, fmt.Errorf("%s - error handling registry response %s", r.config.AdapterName, err)
-	}
+// manifestToSpec - the spec in the config of the image manifest.
+func (r APIV2Adapter) manifestToSpec(imageName, tag string, body []byte, ct string) (*bundle.Spec, error) {
 
 	registryName := r.config.URL.Hostname()
 	if r.config.URL.Port() != "" {
 		registryName = fmt.Sprintf("%s:%s", r.config.URL.Hostname(), r.config.URL.Port())
 	}
 
-	schemaVersion, err := getSchemaVersion(resp.Header.Get("content-type"))
+	schemaVersion, err := getSchemaVersion(ct)
 	if err != nil {
 		return nil, err
 	}
@@ -288,7 +393,7 @@ func (r APIV2Adapter) loadSpec(imageName string) (*bundle.Spec, error) {
 	switch schemaVersion {
 	case 1:
 		log.Debugf("manifest schema 1 for image [%s]", imageName)
-		return responseToSpec(body, fmt.Sprintf("%s/%s:%s", registryName, imageName, r.config.Tag))
+		return responseToSpec(body, fmt.Sprintf("%s/%s:%s", registryName, imageName, tag))
 	case 2:
 		log.Debugf("manifest schema 2 for image [%s]", imageName)
 		mConf := manifestConfig{}
@@ -303,34 +408,82 @@ func (r APIV2Adapter) loadSpec(imageName string) (*bundle.Spec, error) {
 		digest := mConf.Config.Digest
 
 		// get response with digest
-		req, err = r.client.NewRequest(fmt.Sprintf("/v2/%s/blobs/%s", imageName, digest))
+		req, err := r.client.NewRequest(fmt.Sprintf("/v2/%s/blobs/%s", imageName, digest))
 		if err != nil {
 			return nil, err
 		}
-		resp, err = r.client.Do(req)
+		resp, err := r.client.Do(req)
 		if err != nil {
 			return nil, err
 		}
 		body, err = registryResponseHandler(resp)
 		if err != nil {
-			return nil, fmt.Errorf("%s - error getting configuration object for image [%s] : %s", r.config.AdapterName, imageName, err)
+			return nil, errors.Wrapf(err, "%s - error getting configuration object for image [%s]", r.config.AdapterName, imageName)
 		}
-		return configToSpec(body, fmt.Sprintf("%s/%s:%s", registryName, imageName, r.config.Tag))
+		return configToSpec(body, fmt.Sprintf("%s/%s:%s", registryName, imageName, tag))
 	default:
 		return nil, errors.New("unsupported schema version")
 	}
 }
 
+// getManifest - the manifest of the reference, a tag or a digest, its
+// media type and its digest.
+func (r APIV2Adapter) getManifest(imageName, reference string) ([]byte, string, string, error) {
+	req, err := r.client.NewRequest(fmt.Sprintf(apiV2ManifestPath, imageName, reference))
+	if err != nil {
+		return nil, "", "", err
+	}
+	req.Header.Set("accept", manifestAccept)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	body, err := registryResponseHandler(resp)
+	if err != nil {
+		return nil, "", "", errors.Wrapf(err, "%s - error handling registry response", r.config.AdapterName)
+	}
+	return body, resp.Header.Get("content-type"), contentDigest(resp, body), nil
+}
+
+// isManifestList - whether the media type is a list of per platform
+// manifests.
+func isManifestList(ct string) bool {
+	return ct == manifestListCt || ct == ociIndexCt
+}
+
+// selectPlatformManifest - the digest of the linux manifest for the
+// architecture in a manifest list.
+func selectPlatformManifest(body []byte, architecture string) (string, error) {
+	list := manifestList{}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return "", fmt.Errorf("unable to read manifest list - %v", err)
+	}
+	for _, m := range list.Manifests {
+		if m.Platform.Architecture != architecture {
+			continue
+		}
+		if m.Platform.OS != "" && m.Platform.OS != "linux" {
+			continue
+		}
+		return m.Digest, nil
+	}
+	return "", fmt.Errorf("no manifest for architecture %v", architecture)
+}
+
 func getSchemaVersion(ct string) (int, error) {
 	// See below links for more information on accepted media types for Docker manifests
 	// https://docs.docker.com/registry/spec/manifest-v2-1/
 	// https://docs.docker.com/registry/spec/manifest-v2-2/
+	// https://github.com/opencontainers/image-spec/blob/master/manifest.md
 	switch ct {
 	case "":
 		return 0, errors.New("content-type is empty")
 	case schema1Ct, schema1CtSigned:
 		return 1, nil
-	case schema2Ct:
+	case schema2Ct, ociManifestCt:
+		// OCI manifests have the layout of schema 2
 		return 2, nil
 	default:
 		return 0, errors.New("unsupported schema version")
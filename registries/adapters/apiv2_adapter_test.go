@@ -17,8 +17,13 @@
 package adapters
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/automationbroker/bundle-lib/registries/adapters/adaptertest"
@@ -187,6 +192,26 @@ func TestAPIV2FetchSpecs(t *testing.T) {
 	if len(specs) != 3 {
 		t.Fatal("Error: did not find 3 expected specs, only found: ", len(specs))
 	}
+	for _, spec := range specs {
+		ft.True(t, strings.HasPrefix(spec.ImageDigest, "sha256:"), spec.Image)
+	}
+}
+
+func TestAPIV2ImageDigest(t *testing.T) {
+	serv := adaptertest.GetAPIV2Server(t, true)
+	defer serv.Close()
+	testConfig.URL = adaptertest.GetURL(t, serv)
+	apiv2a, _ := NewAPIV2Adapter(testConfig)
+
+	first, err := apiv2a.ImageDigest(apiV2UniqueImages[0])
+	ft.NoError(t, err)
+	ft.True(t, strings.HasPrefix(first, "sha256:"))
+	again, err := apiv2a.ImageDigest(apiV2UniqueImages[0])
+	ft.NoError(t, err)
+	ft.Equal(t, first, again)
+	other, err := apiv2a.ImageDigest(apiV2UniqueImages[1])
+	ft.NoError(t, err)
+	ft.NotEqual(t, first, other)
 }
 
 func TestGetNextImageURL(t *testing.T) {
@@ -244,3 +269,134 @@ func TestGetNextImageURL(t *testing.T) {
 
 	}
 }
+
+const ociIndex = `{
+   "schemaVersion": 2,
+   "mediaType": "application/vnd.oci.image.index.v1+json",
+   "manifests": [
+      {
+         "mediaType": "application/vnd.oci.image.manifest.v1+json",
+         "digest": "sha256:amd64",
+         "platform": {"architecture": "amd64", "os": "linux"}
+      },
+      {
+         "mediaType": "application/vnd.oci.image.manifest.v1+json",
+         "digest": "sha256:arm64windows",
+         "platform": {"architecture": "arm64", "os": "windows"}
+      },
+      {
+         "mediaType": "application/vnd.oci.image.manifest.v1+json",
+         "digest": "sha256:arm64",
+         "platform": {"architecture": "arm64", "os": "linux"}
+      }
+   ]
+}`
+
+func TestSelectPlatformManifest(t *testing.T) {
+	testCases := []struct {
+		name         string
+		architecture string
+		expected     string
+		shouldError  bool
+	}{
+		{name: "amd64", architecture: "amd64", expected: "sha256:amd64"},
+		{name: "linux entry of arm64", architecture: "arm64", expected: "sha256:arm64"},
+		{name: "missing architecture", architecture: "s390x", shouldError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			digest, err := selectPlatformManifest([]byte(ociIndex), tc.architecture)
+			if tc.shouldError {
+				ft.Error(t, err)
+				return
+			}
+			ft.NoError(t, err)
+			ft.Equal(t, tc.expected, digest)
+		})
+	}
+}
+
+func TestAPIV2FetchSpecsManifestList(t *testing.T) {
+	blob, err := json.Marshal(manifestConfig{config{imageLabel{Spec: testApbSpec, Runtime: "2"}, ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifests := map[string]struct {
+		ct   string
+		body string
+	}{
+		"/v2/foo/manifests/latest":       {ociIndexCt, ociIndex},
+		"/v2/foo/manifests/sha256:arm64": {ociManifestCt, `{"schemaVersion": 2, "config": {"digest": "sha256:config"}}`},
+		"/v2/bar/manifests/latest":       {manifestListCt, `{"manifests": [{"digest": "sha256:ppc", "platform": {"architecture": "ppc64le"}}]}`},
+	}
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m, ok := manifests[r.URL.Path]; ok {
+			ft.Contains(t, r.Header.Get("accept"), ociIndexCt)
+			w.Header().Set("Content-Type", m.ct)
+			fmt.Fprint(w, m.body)
+			return
+		}
+		if r.URL.Path == "/v2/foo/blobs/sha256:config" {
+			w.Write(blob)
+			return
+		}
+		if r.URL.Path != "/v2/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer serv.Close()
+
+	config := Configuration{URL: adaptertest.GetURL(t, serv), Architecture: "arm64", FetchRetries: -1}
+	apiv2a, err := NewAPIV2Adapter(config)
+	ft.NoError(t, err)
+
+	specs, err := apiv2a.FetchSpecs([]string{"foo", "bar"})
+	ft.NoError(t, err)
+	// bar has no arm64 manifest
+	if ft.Equal(t, 1, len(specs)) {
+		ft.Equal(t, 2, specs[0].Runtime)
+		// the digest of the manifest list is recorded, computed without a
+		// Docker-Content-Digest header
+		ft.Equal(t, fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(ociIndex))), specs[0].ImageDigest)
+	}
+}
+
+func TestAPIV2FetchSpecsSkipsImagesWithoutSpec(t *testing.T) {
+	bundleBlob, err := json.Marshal(manifestConfig{config{imageLabel{Spec: testApbSpec, Runtime: "2"}, ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainBlob, err := json.Marshal(manifestConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs := map[string][]byte{
+		"/v2/bundle/blobs/sha256:config": bundleBlob,
+		"/v2/plain/blobs/sha256:config":  plainBlob,
+	}
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/manifests/latest") {
+			w.Header().Set("Content-Type", ociManifestCt)
+			fmt.Fprint(w, `{"schemaVersion": 2, "config": {"digest": "sha256:config"}}`)
+			return
+		}
+		if blob, ok := blobs[r.URL.Path]; ok {
+			w.Write(blob)
+			return
+		}
+		if r.URL.Path != "/v2/" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer serv.Close()
+
+	config := Configuration{URL: adaptertest.GetURL(t, serv), FetchRetries: -1}
+	apiv2a, err := NewAPIV2Adapter(config)
+	ft.NoError(t, err)
+
+	specs, err := apiv2a.FetchSpecs([]string{"bundle", "plain"})
+	ft.NoError(t, err)
+	if ft.Equal(t, 1, len(specs)) {
+		ft.True(t, strings.HasPrefix(specs[0].ImageDigest, "sha256:"))
+	}
+}
@@ -0,0 +1,170 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+)
+
+// CatalogArchiveAPIVersion - the version of the catalog archive format.
+const CatalogArchiveAPIVersion = "v1"
+
+const (
+	catalogArchiveMetadata = "metadata.json"
+	catalogArchiveSpecs    = "specs.json"
+	catalogArchiveDigests  = "digests.json"
+	catalogArchiveDir      = "registries"
+)
+
+// CatalogArchive - A snapshot of the specs of registries, for clusters that
+// can not reach them. The archive is a gzipped tarball holding metadata.json
+// and, for every registry, registries/<name>/specs.json and digests.json.
+type CatalogArchive struct {
+	APIVersion string                   `json:"apiVersion"`
+	Created    time.Time                `json:"created"`
+	Registries []CatalogArchiveRegistry `json:"registries"`
+}
+
+// CatalogArchiveRegistry - The specs of a registry in a catalog archive.
+type CatalogArchiveRegistry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+	Org  string `json:"org,omitempty"`
+	// ImageCount - the images of the registry, before filtering.
+	ImageCount int `json:"imageCount"`
+	// Specs - the loaded, filtered and validated specs, by ID.
+	Specs bundle.SpecManifest `json:"-"`
+	// Digests - the manifest digest of the images, by image.
+	Digests map[string]string `json:"-"`
+}
+
+// archiveFile - a file of the archive and the value it holds as json.
+type archiveFile struct {
+	name  string
+	value interface{}
+}
+
+// NewCatalogArchive - Create an empty catalog archive of the current version.
+func NewCatalogArchive() *CatalogArchive {
+	return &CatalogArchive{
+		APIVersion: CatalogArchiveAPIVersion,
+		Created:    time.Now().UTC(),
+		Registries: []CatalogArchiveRegistry{},
+	}
+}
+
+// Write - write the archive as a gzipped tarball.
+func (a *CatalogArchive) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := []archiveFile{{catalogArchiveMetadata, a}}
+	for _, reg := range a.Registries {
+		dir := path.Join(catalogArchiveDir, reg.Name)
+		files = append(files,
+			archiveFile{path.Join(dir, catalogArchiveSpecs), reg.Specs},
+			archiveFile{path.Join(dir, catalogArchiveDigests), reg.Digests})
+	}
+
+	for _, f := range files {
+		data, err := json.Marshal(f.value)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:     f.name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  a.Created,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadCatalogArchive - read an archive written by Write.
+func ReadCatalogArchive(r io.Reader) (*CatalogArchive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the catalog archive - %v", err)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the catalog archive - %v", err)
+		}
+		data := make([]byte, hdr.Size)
+		if _, err := io.ReadFull(tr, data); err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	data, ok := files[catalogArchiveMetadata]
+	if !ok {
+		return nil, fmt.Errorf("%v is missing from the catalog archive", catalogArchiveMetadata)
+	}
+	a := &CatalogArchive{}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+	if a.APIVersion != CatalogArchiveAPIVersion {
+		return nil, fmt.Errorf("unsupported catalog archive apiVersion %q", a.APIVersion)
+	}
+
+	for i := range a.Registries {
+		reg := &a.Registries[i]
+		dir := path.Join(catalogArchiveDir, reg.Name)
+		reg.Specs = bundle.SpecManifest{}
+		reg.Digests = map[string]string{}
+		if data, ok := files[path.Join(dir, catalogArchiveSpecs)]; ok {
+			if err := json.Unmarshal(data, &reg.Specs); err != nil {
+				return nil, err
+			}
+		}
+		if data, ok := files[path.Join(dir, catalogArchiveDigests)]; ok {
+			if err := json.Unmarshal(data, &reg.Digests); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	log "github.com/sirupsen/logrus"
+)
+
+const catalogArchiveName = "archive"
+
+// CatalogArchiveAdapter - Loads the specs of a catalog archive, for clusters
+// that can not reach the registries the archive was exported from. The URL
+// is the archive file, e.g. file:///srv/catalog.tar.gz. The images of the
+// specs are the ones of the registries, a mirror of the archive registry
+// rewrites them.
+type CatalogArchiveAdapter struct {
+	Config Configuration
+	specs  map[string][]*bundle.Spec
+}
+
+// RegistryName - Retrieve the registry name
+func (r *CatalogArchiveAdapter) RegistryName() string {
+	return catalogArchiveName
+}
+
+// GetImageNames - the names of the bundles in the archive.
+func (r *CatalogArchiveAdapter) GetImageNames() ([]string, error) {
+	if r.Config.URL == nil || r.Config.URL.Host+r.Config.URL.Path == "" {
+		return nil, fmt.Errorf("the archive registry requires the archive file as url")
+	}
+	f, err := os.Open(filepath.FromSlash(r.Config.URL.Host + r.Config.URL.Path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	archive, err := ReadCatalogArchive(f)
+	if err != nil {
+		return nil, err
+	}
+
+	r.specs = map[string][]*bundle.Spec{}
+	for _, reg := range archive.Registries {
+		for _, spec := range reg.Specs {
+			if spec.ImageDigest == "" {
+				spec.ImageDigest = reg.Digests[spec.Image]
+			}
+			r.specs[spec.FQName] = append(r.specs[spec.FQName], spec)
+		}
+	}
+	names := []string{}
+	for name, specs := range r.specs {
+		sort.Slice(specs, func(i, j int) bool { return specs[i].ID < specs[j].ID })
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	log.Infof("Loaded catalog archive created %v with [ %d ] bundles from [ %d ] registries",
+		archive.Created, len(names), len(archive.Registries))
+	return names, nil
+}
+
+// FetchSpecs - the archived specs of the bundles.
+func (r *CatalogArchiveAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
+	specs := []*bundle.Spec{}
+	for _, name := range imageNames {
+		for _, archived := range r.specs[name] {
+			spec := *archived
+			specs = append(specs, &spec)
+		}
+	}
+	return specs, nil
+}
@@ -0,0 +1,124 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/ghodss/yaml"
+)
+
+// CatalogIndexAPIVersion - the version of the catalog index format.
+const CatalogIndexAPIVersion = "v1"
+
+// CatalogIndex - A static index of bundle specs, like the index.yaml of a
+// helm repository. Loading specs from an index does not need the catalog
+// API of a registry, which is slow and often disabled.
+type CatalogIndex struct {
+	APIVersion string    `json:"apiVersion"`
+	Generated  time.Time `json:"generated"`
+	// Entries - the versions of every bundle, by bundle name.
+	Entries map[string][]CatalogIndexEntry `json:"entries"`
+}
+
+// CatalogIndexEntry - A version of a bundle in a catalog index.
+type CatalogIndexEntry struct {
+	// Image - the image the bundle runs.
+	Image string `json:"image"`
+	// Digest - the manifest digest of the image, when known.
+	Digest string `json:"digest,omitempty"`
+	// Runtime - the runtime version of the bundle.
+	Runtime int `json:"runtime"`
+	// Version - the version of the bundle, when the registry loads more
+	// than one.
+	Version string `json:"version,omitempty"`
+	// Registry - the name of the registry the spec was exported from.
+	Registry string       `json:"registry,omitempty"`
+	Spec     *bundle.Spec `json:"spec"`
+}
+
+// NewCatalogIndex - Create an empty catalog index of the current version.
+func NewCatalogIndex() *CatalogIndex {
+	return &CatalogIndex{
+		APIVersion: CatalogIndexAPIVersion,
+		Generated:  time.Now().UTC(),
+		Entries:    map[string][]CatalogIndexEntry{},
+	}
+}
+
+// Add - add the spec to the index, exported from the registry at the digest.
+func (i *CatalogIndex) Add(registry string, spec *bundle.Spec, digest string) {
+	i.Entries[spec.FQName] = append(i.Entries[spec.FQName], CatalogIndexEntry{
+		Image:    spec.Image,
+		Digest:   digest,
+		Runtime:  spec.Runtime,
+		Version:  spec.BundleVersion,
+		Registry: registry,
+		Spec:     spec,
+	})
+}
+
+// Names - the names of the bundles in the index, sorted.
+func (i *CatalogIndex) Names() []string {
+	names := []string{}
+	for name := range i.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Specs - the specs of the versions of the bundle. The image, runtime and
+// version of the entry win over the ones of its spec.
+func (i *CatalogIndex) Specs(name string) []*bundle.Spec {
+	specs := []*bundle.Spec{}
+	for _, entry := range i.Entries[name] {
+		if entry.Spec == nil {
+			continue
+		}
+		spec := *entry.Spec
+		spec.Image = entry.Image
+		spec.Runtime = entry.Runtime
+		spec.BundleVersion = entry.Version
+		spec.ImageDigest = entry.Digest
+		specs = append(specs, &spec)
+	}
+	return specs
+}
+
+// Marshal - the index as yaml.
+func (i *CatalogIndex) Marshal() ([]byte, error) {
+	return yaml.Marshal(i)
+}
+
+// ParseCatalogIndex - read a catalog index, yaml or json.
+func ParseCatalogIndex(data []byte) (*CatalogIndex, error) {
+	index := &CatalogIndex{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("unable to read the catalog index - %v", err)
+	}
+	if index.APIVersion != CatalogIndexAPIVersion {
+		return nil, fmt.Errorf("unsupported catalog index apiVersion %q", index.APIVersion)
+	}
+	if index.Entries == nil {
+		index.Entries = map[string][]CatalogIndexEntry{}
+	}
+	return index, nil
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	catalogIndexName = "catalog_index"
+	catalogIndexFile = "index.yaml"
+)
+
+// CatalogIndexAdapter - Loads specs from a catalog index served over http
+// or read from disk. The URL is the index, or the directory holding an
+// index.yaml, e.g. https://bundles.example.com or file:///srv/index.yaml.
+type CatalogIndexAdapter struct {
+	Config Configuration
+	index  *CatalogIndex
+}
+
+// RegistryName - Retrieve the registry name
+func (r *CatalogIndexAdapter) RegistryName() string {
+	return catalogIndexName
+}
+
+// GetImageNames - the names of the bundles in the index.
+func (r *CatalogIndexAdapter) GetImageNames() ([]string, error) {
+	data, err := r.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	index, err := ParseCatalogIndex(data)
+	if err != nil {
+		return nil, err
+	}
+	r.index = index
+	log.Infof("Loaded catalog index generated %v with [ %d ] bundles", index.Generated, len(index.Entries))
+	return index.Names(), nil
+}
+
+// FetchSpecs - the specs of every version of the bundles in the index.
+func (r *CatalogIndexAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
+	specs := []*bundle.Spec{}
+	if r.index == nil {
+		return specs, nil
+	}
+	for _, name := range imageNames {
+		specs = append(specs, r.index.Specs(name)...)
+	}
+	return specs, nil
+}
+
+// readIndex - the index from disk for file urls and urls without a host,
+// over http otherwise.
+func (r *CatalogIndexAdapter) readIndex() ([]byte, error) {
+	u := *r.Config.URL
+	if !strings.HasSuffix(u.Path, ".yaml") && !strings.HasSuffix(u.Path, ".json") {
+		u.Path = path.Join("/", u.Path, catalogIndexFile)
+	}
+
+	if u.Scheme == "file" || u.Host == "" {
+		return ioutil.ReadFile(filepath.FromSlash(u.Host + u.Path))
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.Config.User != "" {
+		req.SetBasicAuth(r.Config.User, r.Config.Pass)
+	}
+	client := http.DefaultClient
+	if r.Config.SkipVerifyTLS {
+		client = &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	return registryResponseHandler(resp)
+}
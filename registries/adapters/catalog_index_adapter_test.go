@@ -0,0 +1,116 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	ft "github.com/stretchr/testify/assert"
+)
+
+const catalogIndexYaml = `apiVersion: v1
+generated: 2018-06-01T10:00:00Z
+entries:
+  mariadb-apb:
+  - image: docker.io/bundles/mariadb-apb:2.0
+    digest: sha256:b
+    runtime: 2
+    version: "2.0"
+    registry: dh
+    spec:
+      name: mariadb-apb
+      version: "1.0"
+      plans:
+      - name: default
+  - image: docker.io/bundles/mariadb-apb:1.0
+    digest: sha256:a
+    runtime: 1
+    version: "1.0"
+    spec:
+      name: mariadb-apb
+      version: "1.0"
+      plans:
+      - name: default
+  mysql-apb:
+  - image: docker.io/bundles/mysql-apb:latest
+    runtime: 2
+    spec:
+      name: mysql-apb
+      version: "1.0"
+      plans:
+      - name: default
+`
+
+func TestCatalogIndexAdapter(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bundles/index.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, catalogIndexYaml)
+	}))
+	defer serv.Close()
+
+	u, err := url.Parse(serv.URL + "/bundles")
+	ft.NoError(t, err)
+	a := &CatalogIndexAdapter{Config: Configuration{URL: u}}
+	ft.Equal(t, "catalog_index", a.RegistryName())
+
+	names, err := a.GetImageNames()
+	ft.NoError(t, err)
+	ft.Equal(t, []string{"mariadb-apb", "mysql-apb"}, names)
+
+	specs, err := a.FetchSpecs([]string{"mariadb-apb"})
+	ft.NoError(t, err)
+	if !ft.Equal(t, 2, len(specs)) {
+		return
+	}
+	ft.Equal(t, "docker.io/bundles/mariadb-apb:2.0", specs[0].Image)
+	ft.Equal(t, 2, specs[0].Runtime)
+	ft.Equal(t, "2.0", specs[0].BundleVersion)
+	ft.Equal(t, "1.0", specs[1].BundleVersion)
+	ft.Equal(t, 1, specs[1].Runtime)
+
+	// a missing index fails the registry
+	u, _ = url.Parse(serv.URL + "/missing/index.yaml")
+	a = &CatalogIndexAdapter{Config: Configuration{URL: u}}
+	_, err = a.GetImageNames()
+	ft.Error(t, err)
+}
+
+func TestCatalogIndexRoundTrip(t *testing.T) {
+	index := NewCatalogIndex()
+	spec := &bundle.Spec{FQName: "mariadb-apb", Image: "mariadb-apb:1.0", Runtime: 2, BundleVersion: "1.0"}
+	index.Add("dh", spec, "sha256:a")
+
+	data, err := index.Marshal()
+	ft.NoError(t, err)
+	parsed, err := ParseCatalogIndex(data)
+	ft.NoError(t, err)
+	ft.Equal(t, index.Entries["mariadb-apb"][0].Digest, parsed.Entries["mariadb-apb"][0].Digest)
+	ft.Equal(t, "mariadb-apb:1.0", parsed.Specs("mariadb-apb")[0].Image)
+
+	_, err = ParseCatalogIndex([]byte("apiVersion: v2\nentries: {}"))
+	ft.Error(t, err)
+	_, err = ParseCatalogIndex([]byte("entries: ["))
+	ft.Error(t, err)
+}
@@ -25,6 +25,7 @@ import (
 	"net/http"
 
 	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -33,7 +34,10 @@ var (
 	dockerHubLoginURL    = "https://hub.docker.com/v2/users/login/"
 	dockerHubRepoImages  = "https://hub.docker.com/v2/repositories/%v/?page_size=100"
 	dockerHubManifestURL = "https://registry.hub.docker.com/v2/%v/manifests/%v"
+	dockerHubTagsURL     = "https://registry.hub.docker.com/v2/%v/tags/list"
 	dockerBearerTokenURL = "https://auth.docker.io/token"
+	// dockerHubRegistryHost - the host of dockerHubManifestURL.
+	dockerHubRegistryHost = "registry.hub.docker.com"
 )
 
 // DockerHubAdapter - Docker Hub Adapter
@@ -109,17 +113,17 @@ func (r DockerHubAdapter) GetImageNames() ([]string, error) {
 
 // FetchSpecs - retrieve the spec for the image names.
 func (r DockerHubAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
-	specs := []*bundle.Spec{}
-	for _, imageName := range imageNames {
-		spec, err := r.loadSpec(imageName)
-		if err != nil {
-			log.Errorf("Failed to retrieve spec data for image %s - %v", imageName, err)
-		}
-		if spec != nil {
-			specs = append(specs, spec)
-		}
-	}
-	return specs, nil
+	return r.fetcher().fetch(imageNames), nil
+}
+
+// FetchImageSpecs - retrieve the specs of the image, with the error of a
+// tag that could not be loaded.
+func (r DockerHubAdapter) FetchImageSpecs(imageName string) ([]*bundle.Spec, error) {
+	return r.fetcher().fetchImage(imageName)
+}
+
+func (r DockerHubAdapter) fetcher() specFetcher {
+	return newSpecFetcher(r.Config, dockerHubRegistryHost, r.listTags, r.loadSpec)
 }
 
 // getDockerHubToken - will retrieve the docker hub token.
@@ -216,17 +220,68 @@ func (r DockerHubAdapter) getNextImages(ctx context.Context,
 	return &iResp, nil
 }
 
-func (r DockerHubAdapter) loadSpec(imageName string) (*bundle.Spec, error) {
-	if r.Config.Tag == "" {
-		r.Config.Tag = "latest"
+// ImageDigest - retrieve the manifest digest of the selected tags of the
+// image with HEAD requests.
+func (r DockerHubAdapter) ImageDigest(imageName string) (string, error) {
+	return r.fetcher().digest(imageName, r.tagDigest)
+}
+
+// listTags - the tags of the image from the registry tags list.
+func (r DockerHubAdapter) listTags(imageName string) ([]string, error) {
+	token, err := r.getBearerToken(imageName)
+	if err != nil {
+		return nil, err
 	}
 
+	req, err := http.NewRequest("GET", fmt.Sprintf(dockerHubTagsURL, imageName), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := registryResponseHandler(resp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to list the tags of image %v", imageName)
+	}
+	tags := apiV2TagsResponse{}
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, err
+	}
+	return tags.Tags, nil
+}
+
+func (r DockerHubAdapter) tagDigest(imageName, tag string) (string, error) {
+	token, err := r.getBearerToken(imageName)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodHead, fmt.Sprintf(dockerHubManifestURL, imageName, tag), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	req.Header.Add("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	return manifestDigest(resp, imageName)
+}
+
+func (r DockerHubAdapter) loadSpec(imageName, tag string) (*bundle.Spec, error) {
 	token, err := r.getBearerToken(imageName)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf(dockerHubManifestURL, imageName, r.Config.Tag), nil)
+	req, err := http.NewRequest("GET", fmt.Sprintf(dockerHubManifestURL, imageName, tag), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -241,9 +296,16 @@ func (r DockerHubAdapter) loadSpec(imageName string) (*bundle.Spec, error) {
 
 	body, err := registryResponseHandler(resp)
 	if err != nil {
-		return nil, fmt.Errorf("DockerHubAdapter::error handling dockerhub registery response %s", err)
+		return nil, errors.Wrap(err, "DockerHubAdapter::error handling dockerhub registery response")
+	}
+	spec, err := responseToSpec(body, fmt.Sprintf("%s/%s:%s", r.RegistryName(), imageName, tag))
+	if err != nil || spec == nil {
+		return nil, err
 	}
-	return responseToSpec(body, fmt.Sprintf("%s/%s:%s", r.RegistryName(), imageName, r.Config.Tag))
+	// The digest of a signed schema 1 manifest is not the digest of its
+	// body, only the digest the registry returns is recorded.
+	spec.ImageDigest = resp.Header.Get(contentDigestHeader)
+	return spec, nil
 }
 
 func (r DockerHubAdapter) getBearerToken(imageName string) (string, error) {
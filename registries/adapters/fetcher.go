@@ -0,0 +1,310 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultFetchWorkers - how many specs are loaded at once when the
+	// registry does not configure it.
+	DefaultFetchWorkers = 4
+	// DefaultFetchRetries - how often a transient error is retried when the
+	// registry does not configure it.
+	DefaultFetchRetries = 3
+
+	fetchBackoffBase = 500 * time.Millisecond
+	fetchBackoffMax  = 30 * time.Second
+)
+
+var (
+	// fetchSleep and fetchNow are replaced by the tests.
+	fetchSleep = time.Sleep
+	fetchNow   = time.Now
+
+	hostLimitersMutex sync.Mutex
+	hostLimiters      = map[string]*hostLimiter{}
+)
+
+// specLoader - loads the spec of an image at a tag.
+type specLoader func(imageName, tag string) (*bundle.Spec, error)
+
+// specFetcher - Loads the specs of many images with a bounded pool of
+// workers, one spec per tag the tag policy selects. Every request waits for
+// the rate limit of the host, transient errors are retried with an
+// exponential backoff that honors Retry-After.
+type specFetcher struct {
+	config  Configuration
+	workers int
+	retries int
+	limiter *hostLimiter
+	list    tagLister
+	load    specLoader
+}
+
+// newSpecFetcher - the fetcher for the adapter configuration, host is the
+// registry host the requests are sent to. list is only used by the tag
+// policies that select from the tags of the image.
+func newSpecFetcher(config Configuration, host string, list tagLister, load specLoader) specFetcher {
+	workers := config.FetchWorkers
+	if workers <= 0 {
+		workers = DefaultFetchWorkers
+	}
+	retries := config.FetchRetries
+	if retries == 0 {
+		retries = DefaultFetchRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+	return specFetcher{
+		config:  config,
+		workers: workers,
+		retries: retries,
+		limiter: limiterForHost(host, config.RateLimit),
+		list:    list,
+		load:    load,
+	}
+}
+
+// fetch - the specs of the images, in the order of the images and their
+// selected tags. Images that fail to load are logged and skipped.
+func (f specFetcher) fetch(imageNames []string) []*bundle.Spec {
+	results := make([][]*bundle.Spec, len(imageNames))
+	ForEachImage(f.workers, imageNames, func(idx int, imageName string) {
+		results[idx], _ = f.fetchImage(imageName)
+	})
+
+	specs := []*bundle.Spec{}
+	for _, loaded := range results {
+		specs = append(specs, loaded...)
+	}
+	return specs
+}
+
+// fetchImage - the specs of the selected tags of the image. The error of
+// the last tag that could not be loaded is returned with the specs of the
+// other tags, every failure is logged.
+func (f specFetcher) fetchImage(imageName string) ([]*bundle.Spec, error) {
+	var tags []string
+	err := f.withRetries(imageName, func() error {
+		var err error
+		tags, err = selectTags(f.config, imageName, f.list)
+		return err
+	})
+	if err != nil {
+		log.Errorf("Failed to select the tags of image %s - %v", imageName, err)
+		return nil, err
+	}
+	specs := []*bundle.Spec{}
+	var loadErr error
+	for _, tag := range tags {
+		var spec *bundle.Spec
+		err := f.withRetries(imageName, func() error {
+			var err error
+			spec, err = f.load(imageName, tag)
+			return err
+		})
+		if err != nil {
+			log.Errorf("Failed to retrieve spec data for image %s:%s - %v", imageName, tag, err)
+			loadErr = err
+		}
+		if spec == nil {
+			continue
+		}
+		// Specs of the tags a policy selects are versions of the bundle.
+		if f.config.TagPolicy.listsTags() && spec.BundleVersion == "" {
+			spec.BundleVersion = tag
+		}
+		specs = append(specs, spec)
+	}
+	return specs, loadErr
+}
+
+// digest - the digest of the selected tags of the image, see policyDigest.
+// Listing the tags and every digest request wait for the rate limit of the
+// host and are retried like the spec requests.
+func (f specFetcher) digest(imageName string, digest tagDigester) (string, error) {
+	list := func(imageName string) ([]string, error) {
+		var tags []string
+		err := f.withRetries(imageName, func() error {
+			var err error
+			tags, err = f.list(imageName)
+			return err
+		})
+		return tags, err
+	}
+	tagDigest := func(imageName, tag string) (string, error) {
+		var d string
+		err := f.withRetries(imageName, func() error {
+			var err error
+			d, err = digest(imageName, tag)
+			return err
+		})
+		return d, err
+	}
+	return policyDigest(f.config, imageName, list, tagDigest)
+}
+
+// ForEachImage - Calls fn with the index and name of every image, at most
+// workers calls run at once. Returns when all the calls are done.
+func ForEachImage(workers int, imageNames []string, fn func(int, string)) {
+	if workers <= 0 {
+		workers = DefaultFetchWorkers
+	}
+	if workers > len(imageNames) {
+		workers = len(imageNames)
+	}
+	work := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range work {
+				fn(idx, imageNames[idx])
+			}
+		}()
+	}
+	for idx := range imageNames {
+		work <- idx
+	}
+	close(work)
+	wg.Wait()
+}
+
+// withRetries - calls fn within the rate limit of the host until it
+// succeeds, fails with an error that is not transient or runs out of
+// retries.
+func (f specFetcher) withRetries(imageName string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		f.limiter.wait()
+		err := fn()
+		if err == nil || attempt >= f.retries || !isTransientError(err) {
+			return err
+		}
+		delay := fetchBackoff(attempt, err)
+		if rre, ok := errors.Cause(err).(*registryResponseError); ok && rre.code == http.StatusTooManyRequests {
+			// The registry throttles every client of the host, not just
+			// this image.
+			f.limiter.pause(delay)
+		}
+		log.Warningf("retrying image %s in %v - %v", imageName, delay, err)
+		fetchSleep(delay)
+	}
+}
+
+// isTransientError - errors that might not happen again, throttling, server
+// errors and network timeouts.
+func isTransientError(err error) bool {
+	switch e := errors.Cause(err).(type) {
+	case *registryResponseError:
+		return e.code == http.StatusTooManyRequests || e.code >= http.StatusInternalServerError
+	case net.Error:
+		return e.Timeout() || e.Temporary()
+	}
+	return false
+}
+
+// fetchBackoff - how long to wait before the retry, the Retry-After of the
+// registry when it is longer than the exponential backoff.
+func fetchBackoff(attempt int, err error) time.Duration {
+	delay := fetchBackoffBase << uint(attempt)
+	if delay > fetchBackoffMax || delay <= 0 {
+		delay = fetchBackoffMax
+	}
+	if rre, ok := errors.Cause(err).(*registryResponseError); ok && rre.retryAfter > delay {
+		delay = rre.retryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter - the Retry-After header, in seconds or as a http date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(fetchNow())
+	}
+	return 0
+}
+
+// hostLimiter - Spaces the loads sent to a host, shared by all registries
+// using the host.
+type hostLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// limiterForHost - the limiter of the host, a registry asking for a lower
+// rate slows down every registry of the host.
+func limiterForHost(host string, rate float64) *hostLimiter {
+	hostLimitersMutex.Lock()
+	defer hostLimitersMutex.Unlock()
+	l, ok := hostLimiters[host]
+	if !ok {
+		l = &hostLimiter{}
+		hostLimiters[host] = l
+	}
+	if rate > 0 {
+		interval := time.Duration(float64(time.Second) / rate)
+		l.mutex.Lock()
+		if interval > l.interval {
+			l.interval = interval
+		}
+		l.mutex.Unlock()
+	}
+	return l
+}
+
+// wait - blocks until the next load may be sent to the host.
+func (l *hostLimiter) wait() {
+	l.mutex.Lock()
+	now := fetchNow()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mutex.Unlock()
+	if d := start.Sub(now); d > 0 {
+		fetchSleep(d)
+	}
+}
+
+// pause - holds every load to the host for the duration.
+func (l *hostLimiter) pause(d time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	until := fetchNow().Add(d)
+	if l.next.Before(until) {
+		l.next = until
+	}
+}
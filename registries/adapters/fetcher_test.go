@@ -0,0 +1,245 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/pkg/errors"
+	ft "github.com/stretchr/testify/assert"
+)
+
+// fakeClock - replaces fetchSleep and fetchNow, sleeping moves the clock.
+type fakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func useFakeClock() (*fakeClock, func()) {
+	c := &fakeClock{now: time.Now()}
+	fetchSleep = func(d time.Duration) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.sleeps = append(c.sleeps, d)
+		c.now = c.now.Add(d)
+	}
+	fetchNow = func() time.Time {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.now
+	}
+	return c, func() {
+		fetchSleep = time.Sleep
+		fetchNow = time.Now
+	}
+}
+
+func TestForEachImage(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g"}
+	mutex := sync.Mutex{}
+	running, maxRunning := 0, 0
+	seen := make([]string, len(names))
+	ForEachImage(3, names, func(idx int, name string) {
+		mutex.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mutex.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		seen[idx] = name
+		mutex.Lock()
+		running--
+		mutex.Unlock()
+	})
+	ft.Equal(t, names, seen)
+	ft.True(t, maxRunning <= 3)
+
+	// no images, no calls
+	ForEachImage(3, []string{}, func(int, string) { t.Fail() })
+}
+
+func TestSpecFetcherRetries(t *testing.T) {
+	throttled := &registryResponseError{code: http.StatusTooManyRequests, retryAfter: 10 * time.Second}
+	testCases := []struct {
+		name     string
+		retries  int
+		failures []error
+		attempts int
+		sleeps   []time.Duration
+		loaded   bool
+	}{
+		{
+			name:     "success",
+			attempts: 1,
+			sleeps:   []time.Duration{},
+			loaded:   true,
+		},
+		{
+			name:     "server error is retried with backoff",
+			failures: []error{&registryResponseError{code: 503}, &registryResponseError{code: 502}},
+			attempts: 3,
+			sleeps:   []time.Duration{fetchBackoffBase, 2 * fetchBackoffBase},
+			loaded:   true,
+		},
+		{
+			name:     "retry after is honored",
+			failures: []error{errors.Wrap(throttled, "loading image")},
+			attempts: 2,
+			sleeps:   []time.Duration{10 * time.Second},
+			loaded:   true,
+		},
+		{
+			name:     "not found is not retried",
+			failures: []error{&registryResponseError{code: 404}},
+			attempts: 1,
+			sleeps:   []time.Duration{},
+		},
+		{
+			name:     "other errors are not retried",
+			failures: []error{fmt.Errorf("Spec not found")},
+			attempts: 1,
+			sleeps:   []time.Duration{},
+		},
+		{
+			name:     "retries are exhausted",
+			retries:  1,
+			failures: []error{&registryResponseError{code: 500}, &registryResponseError{code: 500}, nil},
+			attempts: 2,
+			sleeps:   []time.Duration{fetchBackoffBase},
+		},
+		{
+			name:     "retries are disabled",
+			retries:  -1,
+			failures: []error{&registryResponseError{code: 500}},
+			attempts: 1,
+			sleeps:   []time.Duration{},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock, restore := useFakeClock()
+			defer restore()
+			clock.sleeps = []time.Duration{}
+
+			attempts := 0
+			load := func(name, tag string) (*bundle.Spec, error) {
+				attempts++
+				if attempts <= len(tc.failures) && tc.failures[attempts-1] != nil {
+					return nil, tc.failures[attempts-1]
+				}
+				return &bundle.Spec{FQName: name}, nil
+			}
+			config := Configuration{FetchRetries: tc.retries}
+			f := newSpecFetcher(config, fmt.Sprintf("retries-%d", i), nil, load)
+			specs := f.fetch([]string{"foo"})
+			ft.Equal(t, tc.attempts, attempts)
+			ft.Equal(t, tc.sleeps, clock.sleeps)
+			if tc.loaded {
+				ft.Equal(t, 1, len(specs))
+			} else {
+				ft.Equal(t, 0, len(specs))
+			}
+		})
+	}
+}
+
+func TestSpecFetcherDigestRetries(t *testing.T) {
+	clock, restore := useFakeClock()
+	defer restore()
+	clock.sleeps = []time.Duration{}
+
+	listed, digested := 0, 0
+	list := func(imageName string) ([]string, error) {
+		listed++
+		if listed == 1 {
+			return nil, &registryResponseError{code: http.StatusTooManyRequests, retryAfter: 5 * time.Second}
+		}
+		return []string{"1.0", "2.0"}, nil
+	}
+	digest := func(imageName, tag string) (string, error) {
+		digested++
+		if digested == 1 {
+			return "", &registryResponseError{code: http.StatusServiceUnavailable}
+		}
+		return "sha256:" + tag, nil
+	}
+	config := Configuration{TagPolicy: TagPolicy{Type: TagPolicyRange, Constraint: ">=1.0"}}
+	f := newSpecFetcher(config, "digest-retries", list, nil)
+	d, err := f.digest("foo", digest)
+	ft.NoError(t, err)
+	ft.Equal(t, 2, listed)
+	ft.Equal(t, 3, digested)
+	ft.Equal(t, []time.Duration{5 * time.Second, fetchBackoffBase}, clock.sleeps)
+
+	expected, err := policyDigest(config, "foo", list, digest)
+	ft.NoError(t, err)
+	ft.Equal(t, expected, d)
+}
+
+func TestHostLimiter(t *testing.T) {
+	clock, restore := useFakeClock()
+	defer restore()
+
+	l := limiterForHost("limited.example.com", 2)
+	ft.Equal(t, l, limiterForHost("limited.example.com", 4))
+	ft.Equal(t, 500*time.Millisecond, l.interval)
+	// a lower rate slows the host down
+	limiterForHost("limited.example.com", 1)
+	ft.Equal(t, time.Second, l.interval)
+
+	l.wait()
+	l.wait()
+	l.wait()
+	ft.Equal(t, []time.Duration{time.Second, time.Second}, clock.sleeps)
+
+	l.pause(time.Minute)
+	l.wait()
+	ft.Equal(t, time.Minute, clock.sleeps[2])
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	_, restore := useFakeClock()
+	defer restore()
+
+	ft.Equal(t, time.Duration(0), parseRetryAfter(""))
+	ft.Equal(t, 120*time.Second, parseRetryAfter("120"))
+	ft.Equal(t, time.Duration(0), parseRetryAfter("soon"))
+	date := fetchNow().Add(time.Hour).UTC().Format(http.TimeFormat)
+	ft.InDelta(t, float64(time.Hour), float64(parseRetryAfter(date)), float64(time.Second))
+}
+
+func TestRegistryResponseRetryAfter(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer serv.Close()
+
+	resp, err := http.Get(serv.URL)
+	ft.NoError(t, err)
+	_, err = registryResponseHandler(resp)
+	ft.True(t, isTransientError(err))
+	ft.Equal(t, 30*time.Second, fetchBackoff(0, err))
+}
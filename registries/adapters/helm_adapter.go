@@ -31,6 +31,7 @@ import (
 	"github.com/Masterminds/semver"
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -164,15 +165,14 @@ func (r *HelmAdapter) GetImageNames() ([]string, error) {
 	return imageNames, nil
 }
 
-// FetchSpecs - retrieve the spec for the image names.
+// FetchSpecs - retrieve the spec for the image names. The latest version of
+// every chart is used, unless the tag policy selects from the chart versions,
+// then every selected version becomes a spec.
 func (r *HelmAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
 	var specs []*bundle.Spec
 
 	for _, name := range imageNames {
-		var (
-			chartVersions []string
-			values        string
-		)
+		var chartVersions []string
 
 		charts, ok := r.Charts[name]
 		if !ok {
@@ -186,85 +186,127 @@ func (r *HelmAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
 		// Use the latest chart for creating the bundle:
 		// This works works because we previously sorted the chart's versions
 		// and excluded charts w/o at least one chart version.
-		chart := charts[0]
-
-		if len(chart.URLs) > 0 {
-			resp, err := http.Get(chart.URLs[0])
+		selected := charts[:1]
+		if r.Config.TagPolicy.listsTags() {
+			versions, err := r.Config.TagPolicy.Select(chartVersions)
 			if err != nil {
-				continue
+				return nil, err
 			}
-			defer resp.Body.Close()
+			selected = selectChartVersions(charts, versions)
+		}
+
+		for _, chart := range selected {
+			specs = append(specs, r.chartToSpec(chart, chartVersions))
+		}
+	}
+
+	return specs, nil
+}
+
+// selectChartVersions - the charts of the versions, in the order of the
+// versions.
+func selectChartVersions(charts ChartVersions, versions []string) ChartVersions {
+	byVersion := map[string]*ChartVersion{}
+	for _, chart := range charts {
+		byVersion[chart.Version] = chart
+	}
+	selected := ChartVersions{}
+	for _, version := range versions {
+		if chart, ok := byVersion[version]; ok {
+			selected = append(selected, chart)
+		}
+	}
+	return selected
+}
+
+// chartToSpec - the spec running the chart. Specs of a single chart version
+// can only be updated to other versions through their spec. Only those
+// carry a bundle version, the single spec of the latest chart keeps its ID
+// across chart releases. When the chart can not be downloaded the spec is
+// published without default values.
+func (r *HelmAdapter) chartToSpec(chart *ChartVersion, chartVersions []string) *bundle.Spec {
+	var values, bundleVersion string
+	versions, versionUpdatable := chartVersions, true
+	if r.Config.TagPolicy.listsTags() {
+		versions, versionUpdatable = []string{chart.Version}, false
+		bundleVersion = chart.Version
+	}
 
+	if len(chart.URLs) > 0 {
+		resp, err := http.Get(chart.URLs[0])
+		if err != nil {
+			log.Warningf("Unable to download chart [ %s ] version [ %s ]: %v", chart.Name, chart.Version, err)
+		} else {
+			defer resp.Body.Close()
 			values = r.loadArchive(resp.Body)
 		}
+	}
 
-		// Convert chart to Bundle Spec
-		spec := &bundle.Spec{
-			Runtime:     2,
-			Version:     "1.0",
-			Async:       "optional",
-			Bindable:    false,
-			Image:       r.Config.Runner,
-			FQName:      chart.Name,
-			Tags:        chart.Keywords,
-			Description: chart.Description,
-			Metadata: map[string]interface{}{
-				//"longDescription":  chart.Description,
-				"displayName":      fmt.Sprintf("%s (Helm)", chart.Name),
-				"documentationUrl": chart.Home,
-				"dependencies":     chart.Sources,
-				"imageUrl":         chart.Icon,
-			},
-			Plans: []bundle.Plan{
-				bundle.Plan{
-					Name:        "default",
-					Description: "Default plan for running helm charts",
-					Parameters: []bundle.ParameterDescriptor{
-						bundle.ParameterDescriptor{
-							Name:      "repo",
-							Title:     "Helm Chart Repository URL",
-							Type:      "string",
-							Default:   r.Config.URL.String(),
-							Pattern:   fmt.Sprintf("^%s$", r.Config.URL.String()),
-							Updatable: false,
-							Required:  false,
-						},
-						bundle.ParameterDescriptor{
-							Name:      "chart",
-							Title:     "Helm Chart",
-							Type:      "string",
-							Default:   chart.Name,
-							Pattern:   fmt.Sprintf("^%s$", chart.Name),
-							Updatable: false,
-							Required:  false,
-						},
-						bundle.ParameterDescriptor{
-							Name:      "version",
-							Title:     "Helm Chart Version",
-							Type:      "enum",
-							Enum:      chartVersions,
-							Default:   chart.Version,
-							Updatable: true,
-							Required:  false,
-						},
-						bundle.ParameterDescriptor{
-							Name:        "values",
-							Title:       "Values",
-							Type:        "string",
-							DisplayType: "textarea",
-							Default:     values,
-							Updatable:   true,
-							Required:    false,
-						},
+	// Convert chart to Bundle Spec
+	spec := &bundle.Spec{
+		Runtime:       2,
+		Version:       "1.0",
+		Async:         "optional",
+		Bindable:      false,
+		Image:         r.Config.Runner,
+		BundleVersion: bundleVersion,
+		FQName:        chart.Name,
+		Tags:          chart.Keywords,
+		Description:   chart.Description,
+		Metadata: map[string]interface{}{
+			//"longDescription":  chart.Description,
+			"displayName":      fmt.Sprintf("%s (Helm)", chart.Name),
+			"documentationUrl": chart.Home,
+			"dependencies":     chart.Sources,
+			"imageUrl":         chart.Icon,
+		},
+		Plans: []bundle.Plan{
+			bundle.Plan{
+				Name:        "default",
+				Description: "Default plan for running helm charts",
+				Parameters: []bundle.ParameterDescriptor{
+					bundle.ParameterDescriptor{
+						Name:      "repo",
+						Title:     "Helm Chart Repository URL",
+						Type:      "string",
+						Default:   r.Config.URL.String(),
+						Pattern:   fmt.Sprintf("^%s$", r.Config.URL.String()),
+						Updatable: false,
+						Required:  false,
+					},
+					bundle.ParameterDescriptor{
+						Name:      "chart",
+						Title:     "Helm Chart",
+						Type:      "string",
+						Default:   chart.Name,
+						Pattern:   fmt.Sprintf("^%s$", chart.Name),
+						Updatable: false,
+						Required:  false,
+					},
+					bundle.ParameterDescriptor{
+						Name:      "version",
+						Title:     "Helm Chart Version",
+						Type:      "enum",
+						Enum:      versions,
+						Default:   chart.Version,
+						Updatable: versionUpdatable,
+						Required:  false,
+					},
+					bundle.ParameterDescriptor{
+						Name:        "values",
+						Title:       "Values",
+						Type:        "string",
+						DisplayType: "textarea",
+						Default:     values,
+						Updatable:   true,
+						Required:    false,
 					},
 				},
 			},
-		}
-
-		specs = append(specs, spec)
+		},
 	}
 
-	return specs, nil
+	return spec
 }
 
 // getHelmIndex returns a helm repository IndexFile object
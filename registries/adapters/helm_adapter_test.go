@@ -127,4 +127,78 @@ func TestHelmFetchSpecs(t *testing.T) {
 	ft.Equal(t, spec.Version, "1.0")
 	ft.Equal(t, spec.Image, "runner_image")
 	ft.Equal(t, spec.Metadata["displayName"], "mariadb (Helm)")
+	// without a tag policy the spec is not versioned, it keeps its ID when
+	// the chart is released again
+	ft.Equal(t, spec.BundleVersion, "")
+	ft.Equal(t, spec.Plans[0].Parameters[2].Default, "2.1.4")
+}
+
+func TestHelmFetchSpecsVersions(t *testing.T) {
+	url, err := url.Parse("http://charts.example.com")
+	if err != nil {
+		t.Fatal("ERROR: ", err)
+	}
+	charts := ChartVersions{
+		&ChartVersion{Name: MariaDB, Version: "3.0.0"},
+		&ChartVersion{Name: MariaDB, Version: "2.1.4"},
+		&ChartVersion{Name: MariaDB, Version: "2.0.0"},
+		&ChartVersion{Name: MariaDB, Version: "1.0.0"},
+	}
+	ha := HelmAdapter{
+		Config: Configuration{
+			URL:       url,
+			TagPolicy: TagPolicy{Type: TagPolicyRange, Constraint: ">=2.0"},
+		},
+		Charts: map[string]ChartVersions{MariaDB: charts},
+	}
+
+	specs, err := ha.FetchSpecs([]string{MariaDB})
+	if err != nil {
+		t.Fatal("ERROR: ", err)
+	}
+	versions := []string{}
+	for _, spec := range specs {
+		versions = append(versions, spec.BundleVersion)
+		// every spec runs its own version of the chart
+		version := spec.Plans[0].Parameters[2]
+		ft.Equal(t, []string{spec.BundleVersion}, version.Enum)
+		ft.False(t, version.Updatable)
+	}
+	ft.Equal(t, []string{"3.0.0", "2.1.4", "2.0.0"}, versions)
+
+	// without a policy only the latest version is published
+	ha.Config.TagPolicy = TagPolicy{}
+	specs, err = ha.FetchSpecs([]string{MariaDB})
+	if err != nil {
+		t.Fatal("ERROR: ", err)
+	}
+	ft.Equal(t, 1, len(specs))
+	ft.Equal(t, "", specs[0].BundleVersion)
+	ft.Equal(t, "3.0.0", specs[0].Plans[0].Parameters[2].Default)
+	ft.Equal(t, []string{"3.0.0", "2.1.4", "2.0.0", "1.0.0"}, specs[0].Plans[0].Parameters[2].Enum)
+}
+
+func TestHelmFetchSpecsFailedDownload(t *testing.T) {
+	serv := httptest.NewServer(http.NotFoundHandler())
+	chartURL := serv.URL + MariaDBPath
+	serv.Close()
+
+	url, err := url.Parse("http://charts.example.com")
+	if err != nil {
+		t.Fatal("ERROR: ", err)
+	}
+	ha := HelmAdapter{
+		Config: Configuration{URL: url},
+		Charts: map[string]ChartVersions{MariaDB: ChartVersions{
+			&ChartVersion{Name: MariaDB, Version: "2.1.4", URLs: []string{chartURL}},
+		}},
+	}
+
+	// the chart is still published, without default values
+	specs, err := ha.FetchSpecs([]string{MariaDB})
+	if err != nil {
+		t.Fatal("ERROR: ", err)
+	}
+	ft.Equal(t, 1, len(specs))
+	ft.Equal(t, "", specs[0].Plans[0].Parameters[3].Default)
 }
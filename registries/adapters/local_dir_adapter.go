@@ -0,0 +1,384 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	log "github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v1"
+)
+
+const (
+	localDirName = "local_dir"
+
+	ociLayoutFile        = "oci-layout"
+	ociIndexFile         = "index.json"
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+	archiveManifestFile  = "manifest.json"
+	// maxArchiveEntry - entries of a saved image larger than this are
+	// layers, not manifests or configs, and are not read.
+	maxArchiveEntry = 1 << 20
+
+	localBundleSpec    = "spec"
+	localBundleOCI     = "oci"
+	localBundleArchive = "archive"
+)
+
+// specFileNames - the files holding the spec of a bundle directory.
+var specFileNames = []string{"apb.yml", "apb.yaml"}
+
+// LocalDirAdapter - Loads specs from a directory tree, for air-gapped
+// installs and bundle development. Bundles are directories with an apb.yml
+// or apb.yaml, images saved as OCI layout directories or `docker save`
+// tarballs. The URL of the registry is the directory, e.g. file:///srv/bundles.
+type LocalDirAdapter struct {
+	Config  Configuration
+	bundles map[string]localBundle
+}
+
+// localBundle - a bundle found in the directory tree.
+type localBundle struct {
+	kind string
+	path string
+}
+
+// ociLayoutIndex - the index.json of an OCI layout.
+type ociLayoutIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// imageManifest - the config of an image manifest, schema 2 or OCI.
+type imageManifest struct {
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+}
+
+// archiveManifest - an image in the manifest.json of a saved image.
+type archiveManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+}
+
+// RegistryName - Retrieve the registry name
+func (r *LocalDirAdapter) RegistryName() string {
+	return localDirName
+}
+
+// GetImageNames - the bundles in the directory tree, named by their path
+// relative to the directory.
+func (r *LocalDirAdapter) GetImageNames() ([]string, error) {
+	root := r.root()
+	if root == "" {
+		return nil, fmt.Errorf("the local_dir registry requires the directory as url")
+	}
+
+	r.bundles = map[string]localBundle{}
+	imageNames := []string{}
+	add := func(name string, b localBundle) {
+		if name == "." {
+			name = filepath.Base(root)
+		}
+		r.bundles[name] = b
+		imageNames = append(imageNames, name)
+	}
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if info.IsDir() {
+			if isRegularFile(filepath.Join(p, ociLayoutFile)) {
+				add(name, localBundle{kind: localBundleOCI, path: p})
+				return filepath.SkipDir
+			}
+			for _, specFile := range specFileNames {
+				if isRegularFile(filepath.Join(p, specFile)) {
+					add(name, localBundle{kind: localBundleSpec, path: filepath.Join(p, specFile)})
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+
+		for _, ext := range []string{".tar", ".tar.gz"} {
+			if strings.HasSuffix(name, ext) {
+				add(strings.TrimSuffix(name, ext), localBundle{kind: localBundleArchive, path: p})
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Found [ %d ] bundles in %s", len(imageNames), root)
+	return imageNames, nil
+}
+
+// FetchSpecs - read the specs of the bundles. Saved images hold a spec per
+// image, bundles that fail to load are logged and skipped.
+func (r *LocalDirAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
+	specs := []*bundle.Spec{}
+	for _, name := range imageNames {
+		b, ok := r.bundles[name]
+		if !ok {
+			continue
+		}
+		var (
+			loaded []*bundle.Spec
+			err    error
+		)
+		switch b.kind {
+		case localBundleSpec:
+			var spec *bundle.Spec
+			spec, err = r.loadSpecFile(name, b.path)
+			loaded = []*bundle.Spec{spec}
+		case localBundleOCI:
+			loaded, err = r.loadOCILayout(name, b.path)
+		case localBundleArchive:
+			loaded, err = r.loadArchive(name, b.path)
+		}
+		if err != nil {
+			log.Errorf("Failed to load spec for bundle %s from %s - %v", name, b.path, err)
+			continue
+		}
+		for _, spec := range loaded {
+			if spec != nil {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	return specs, nil
+}
+
+// root - the directory of the registry.
+func (r *LocalDirAdapter) root() string {
+	if r.Config.URL == nil {
+		return ""
+	}
+	return filepath.FromSlash(r.Config.URL.Host + r.Config.URL.Path)
+}
+
+// imageName - the image of a bundle at the reference. A reference with a
+// repository is used as it is, otherwise it is the tag of the bundle name,
+// in the configured org when set.
+func (r *LocalDirAdapter) imageName(name, ref string) string {
+	if strings.ContainsAny(ref, "/:") {
+		return ref
+	}
+	if ref == "" {
+		ref = configuredTag(r.Config)
+	}
+	if r.Config.Org != "" {
+		name = fmt.Sprintf("%s/%s", r.Config.Org, path.Base(name))
+	}
+	return fmt.Sprintf("%s:%s", name, ref)
+}
+
+// loadSpecFile - the spec of a bundle directory, encoded in a config as the
+// label of an image would be so it is converted like the specs of images.
+func (r *LocalDirAdapter) loadSpecFile(name, specFile string) (*bundle.Spec, error) {
+	data, err := ioutil.ReadFile(specFile)
+	if err != nil {
+		return nil, err
+	}
+	// The runtime is a label of the image, a spec file may set it.
+	fields := struct {
+		Runtime int `yaml:"runtime"`
+	}{}
+	if err := yaml.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	label := imageLabel{Spec: b64.StdEncoding.EncodeToString(data)}
+	if fields.Runtime != 0 {
+		label.BundleRuntime = strconv.Itoa(fields.Runtime)
+	}
+	conf, err := json.Marshal(manifestConfig{Config: config{Label: label}})
+	if err != nil {
+		return nil, err
+	}
+	return configToSpec(conf, r.imageName(name, ""))
+}
+
+// loadOCILayout - the specs of the images in an OCI layout directory, the
+// image of the configured architecture for multi-arch images.
+func (r *LocalDirAdapter) loadOCILayout(name, dir string) ([]*bundle.Spec, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ociIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	index := ociLayoutIndex{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	architecture := r.Config.Architecture
+	if architecture == "" {
+		architecture = goruntime.GOARCH
+	}
+	specs := []*bundle.Spec{}
+	for _, desc := range index.Manifests {
+		digest := desc.Digest
+		if isManifestList(desc.MediaType) {
+			list, err := readOCIBlob(dir, digest)
+			if err != nil {
+				return nil, err
+			}
+			if digest, err = selectPlatformManifest(list, architecture); err != nil {
+				return nil, err
+			}
+		}
+		data, err := readOCIBlob(dir, digest)
+		if err != nil {
+			return nil, err
+		}
+		manifest := imageManifest{}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, err
+		}
+		conf, err := readOCIBlob(dir, manifest.Config.Digest)
+		if err != nil {
+			return nil, err
+		}
+		spec, err := configToSpec(conf, r.imageName(name, desc.Annotations[ociRefNameAnnotation]))
+		if err != nil {
+			return nil, err
+		}
+		if spec == nil {
+			continue
+		}
+		spec.ImageDigest = desc.Digest
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// readOCIBlob - the blob of the digest in an OCI layout directory.
+func readOCIBlob(dir, digest string) ([]byte, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || strings.ContainsAny(parts[0]+parts[1], "/\\.") {
+		return nil, fmt.Errorf("invalid digest %v", digest)
+	}
+	return ioutil.ReadFile(filepath.Join(dir, "blobs", parts[0], parts[1]))
+}
+
+// loadArchive - the specs of the images of a `docker save` tarball.
+func (r *LocalDirAdapter) loadArchive(name, archive string) ([]*bundle.Spec, error) {
+	files, err := readArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := files[archiveManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%v is not a saved image, %v is missing", archive, archiveManifestFile)
+	}
+	manifests := []archiveManifest{}
+	if err := json.Unmarshal(data, &manifests); err != nil {
+		return nil, err
+	}
+
+	specs := []*bundle.Spec{}
+	for _, m := range manifests {
+		conf, ok := files[m.Config]
+		if !ok {
+			return nil, fmt.Errorf("config %v is missing from %v", m.Config, archive)
+		}
+		image := r.imageName(name, "")
+		if len(m.RepoTags) > 0 {
+			image = m.RepoTags[0]
+		}
+		spec, err := configToSpec(conf, image)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// readArchive - the small files of a tarball by name, gzip compressed when
+// it ends with .gz.
+func readArchive(archive string) (map[string][]byte, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var in io.Reader = f
+	if strings.HasSuffix(archive, ".gz") {
+		unzipped, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer unzipped.Close()
+		in = unzipped
+	}
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(in)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() || hdr.Size > maxArchiveEntry {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+}
+
+func isRegularFile(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	b64 "encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ft "github.com/stretchr/testify/assert"
+)
+
+const localSpecYaml = `version: 1.0
+name: %s
+description: %s
+bindable: false
+async: optional
+runtime: %d
+plans:
+  - name: default
+    description: default plan
+    free: true
+    parameters: []
+`
+
+func localSpec(name string, runtime int) []byte {
+	return []byte(fmt.Sprintf(localSpecYaml, name, name+" bundle", runtime))
+}
+
+// labeledConfig - an image config with the spec label.
+func labeledConfig(spec []byte) []byte {
+	return []byte(fmt.Sprintf(`{"architecture": "amd64", "config": {"Labels": {"com.redhat.apb.spec": "%s", "com.redhat.bundle.runtime": "2"}}}`,
+		b64.StdEncoding.EncodeToString(spec)))
+}
+
+func writeFile(t *testing.T, p string, data []byte) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeBlob - adds the blob to the OCI layout, returns its digest.
+func writeBlob(t *testing.T, dir string, data []byte) string {
+	hex := fmt.Sprintf("%x", sha256.Sum256(data))
+	writeFile(t, filepath.Join(dir, "blobs", "sha256", hex), data)
+	return "sha256:" + hex
+}
+
+func writeArchive(t *testing.T, p string, files map[string][]byte) {
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for name, data := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalDirAdapter(t *testing.T) {
+	root, err := ioutil.TempDir("", "local-dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// a bundle in development
+	writeFile(t, filepath.Join(root, "dev", "mariadb-apb", "apb.yml"), localSpec("mariadb-apb", 2))
+	writeFile(t, filepath.Join(root, "dev", "mariadb-apb", "playbooks", "provision.yml"), []byte("---"))
+
+	// an OCI layout with a multi-arch image
+	oci := filepath.Join(root, "oci", "postgresql-apb")
+	writeFile(t, filepath.Join(oci, "oci-layout"), []byte(`{"imageLayoutVersion": "1.0.0"}`))
+	conf := writeBlob(t, oci, labeledConfig(localSpec("postgresql-apb", 2)))
+	manifest := writeBlob(t, oci, []byte(fmt.Sprintf(`{"schemaVersion": 2, "config": {"digest": "%s"}}`, conf)))
+	list := writeBlob(t, oci, []byte(fmt.Sprintf(`{"schemaVersion": 2, "manifests": [
+		{"digest": "sha256:other", "platform": {"architecture": "s390x", "os": "linux"}},
+		{"digest": "%s", "platform": {"architecture": "amd64", "os": "linux"}}]}`, manifest)))
+	writeFile(t, filepath.Join(oci, "index.json"), []byte(fmt.Sprintf(`{"schemaVersion": 2, "manifests": [
+		{"mediaType": "%s", "digest": "%s", "annotations": {"%s": "1.0"}}]}`, ociIndexCt, list, ociRefNameAnnotation)))
+
+	// a saved image
+	writeArchive(t, filepath.Join(root, "mysql-apb.tar"), map[string][]byte{
+		"manifest.json": []byte(`[{"Config": "abc.json", "RepoTags": ["docker.io/ansibleplaybookbundle/mysql-apb:latest"], "Layers": ["layer.tar"]}]`),
+		"abc.json":      labeledConfig(localSpec("mysql-apb", 2)),
+		"layer.tar":     []byte("layer"),
+	})
+
+	// not bundles
+	writeFile(t, filepath.Join(root, "README.md"), []byte("bundles"))
+	writeArchive(t, filepath.Join(root, "broken.tar"), map[string][]byte{"README.md": []byte("nothing")})
+
+	u, err := url.Parse("file://" + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &LocalDirAdapter{Config: Configuration{URL: u, Architecture: "amd64"}}
+	ft.Equal(t, "local_dir", a.RegistryName())
+
+	names, err := a.GetImageNames()
+	ft.NoError(t, err)
+	ft.Equal(t, []string{"broken", "dev/mariadb-apb", "mysql-apb", "oci/postgresql-apb"}, names)
+
+	specs, err := a.FetchSpecs(names)
+	ft.NoError(t, err)
+	if !ft.Equal(t, 3, len(specs)) {
+		return
+	}
+	ft.Equal(t, "mariadb-apb", specs[0].FQName)
+	ft.Equal(t, "dev/mariadb-apb:latest", specs[0].Image)
+	ft.Equal(t, 2, specs[0].Runtime)
+	ft.Equal(t, "mysql-apb", specs[1].FQName)
+	ft.Equal(t, "docker.io/ansibleplaybookbundle/mysql-apb:latest", specs[1].Image)
+	ft.Equal(t, "postgresql-apb", specs[2].FQName)
+	ft.Equal(t, "oci/postgresql-apb:1.0", specs[2].Image)
+
+	// bundles in development are tagged in the org
+	a.Config.Org = "docker.io/dev"
+	a.Config.Tag = "canary"
+	specs, err = a.FetchSpecs([]string{"dev/mariadb-apb"})
+	ft.NoError(t, err)
+	ft.Equal(t, "docker.io/dev/mariadb-apb:canary", specs[0].Image)
+}
+
+func TestLocalDirAdapterNoDir(t *testing.T) {
+	a := &LocalDirAdapter{Config: Configuration{URL: &url.URL{}}}
+	_, err := a.GetImageNames()
+	ft.Error(t, err)
+
+	a.Config.URL = &url.URL{Path: "/does/not/exist"}
+	_, err = a.GetImageNames()
+	ft.Error(t, err)
+}
+
+func TestReadOCIBlob(t *testing.T) {
+	_, err := readOCIBlob("/tmp", "sha256:../../etc/passwd")
+	ft.Error(t, err)
+	_, err = readOCIBlob("/tmp", "nodigest")
+	ft.Error(t, err)
+}
@@ -0,0 +1,59 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultImageDomain = "docker.io"
+	officialImageOrg   = "library"
+)
+
+// splitImageDomain - the registry domain and the repository of an image
+// reference. References without a domain are docker hub images, official
+// images are in the library org.
+func splitImageDomain(image string) (string, string) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1]
+	}
+	if len(parts) == 1 {
+		return defaultImageDomain, fmt.Sprintf("%s/%s", officialImageOrg, image)
+	}
+	return defaultImageDomain, image
+}
+
+// NormalizeImage - the image reference with its registry domain, e.g.
+// mysql:5.7 is docker.io/library/mysql:5.7.
+func NormalizeImage(image string) string {
+	domain, repository := splitImageDomain(image)
+	return fmt.Sprintf("%s/%s", domain, repository)
+}
+
+// MirrorImage - the image pulled from the mirror, a registry domain with an
+// optional path, e.g. registry.internal:5000 or registry.internal/mirror.
+// The repository, tag and digest of the image are kept.
+func MirrorImage(image, mirror string) string {
+	if mirror == "" || image == "" {
+		return image
+	}
+	_, repository := splitImageDomain(image)
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(mirror, "/"), repository)
+}
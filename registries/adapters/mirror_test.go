@@ -0,0 +1,41 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"testing"
+
+	ft "github.com/stretchr/testify/assert"
+)
+
+func TestMirrorImage(t *testing.T) {
+	testCases := []struct {
+		image    string
+		mirror   string
+		expected string
+	}{
+		{"docker.io/ansibleplaybookbundle/mysql-apb:latest", "registry.internal:5000", "registry.internal:5000/ansibleplaybookbundle/mysql-apb:latest"},
+		{"ansibleplaybookbundle/mysql-apb:latest", "registry.internal:5000", "registry.internal:5000/ansibleplaybookbundle/mysql-apb:latest"},
+		{"mysql:5.7", "registry.internal", "registry.internal/library/mysql:5.7"},
+		{"localhost/bundles/foo", "registry.internal/mirror/", "registry.internal/mirror/bundles/foo"},
+		{"quay.io/org/foo@sha256:abc", "registry.internal", "registry.internal/org/foo@sha256:abc"},
+		{"quay.io/org/foo:1.0", "", "quay.io/org/foo:1.0"},
+	}
+	for _, tc := range testCases {
+		ft.Equal(t, tc.expected, MirrorImage(tc.image, tc.mirror), tc.image)
+	}
+}
@@ -131,40 +131,73 @@ func (r QuayAdapter) GetImageNames() ([]string, error) {
 
 // FetchSpecs - retrieve the spec for the image names.
 func (r QuayAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
-	specs := []*bundle.Spec{}
-	for _, imageName := range imageNames {
-		spec, err := r.loadSpec(imageName)
-		if err != nil {
-			log.Errorf("Failed to retrieve spec data for image %s - %v", imageName, err)
-		}
-		if spec != nil {
-			specs = append(specs, spec)
-		}
+	return r.fetcher().fetch(imageNames), nil
+}
+
+// FetchImageSpecs - retrieve the specs of the image, with the error of a
+// tag that could not be loaded.
+func (r QuayAdapter) FetchImageSpecs(imageName string) ([]*bundle.Spec, error) {
+	return r.fetcher().fetchImage(imageName)
+}
+
+func (r QuayAdapter) fetcher() specFetcher {
+	return newSpecFetcher(r.config, r.config.URL.Host, r.listTags, r.loadSpec)
+}
+
+// ImageDigest - retrieve the manifest digest of the selected tags.
+func (r QuayAdapter) ImageDigest(imageName string) (string, error) {
+	return r.fetcher().digest(imageName, r.getDigest)
+}
+
+func (r QuayAdapter) loadSpec(imageName, tag string) (*bundle.Spec, error) {
+	digest, err := r.getDigest(imageName, tag)
+	if err != nil {
+		return nil, err
 	}
-	return specs, nil
+	return r.digestToSpec(digest, imageName, tag)
 }
 
-func (r QuayAdapter) loadSpec(imageName string) (*bundle.Spec, error) {
-	digest, err := r.getDigest(imageName)
+func (r QuayAdapter) listTags(imageName string) ([]string, error) {
+	digests, err := r.getTagDigests(imageName)
 	if err != nil {
 		return nil, err
 	}
-	return r.digestToSpec(digest, imageName)
+	tags := []string{}
+	for tag := range digests {
+		tags = append(tags, tag)
+	}
+	return tags, nil
 }
 
-func (r QuayAdapter) getDigest(imageName string) (string, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf(quayDigestURL, r.config.URL, r.config.Org, imageName), nil)
+func (r QuayAdapter) getDigest(imageName, tag string) (string, error) {
+	digests, err := r.getTagDigests(imageName)
 	if err != nil {
 		return "", err
 	}
+	digest, ok := digests[tag]
+	if !ok || digest == "" {
+		return "", errors.New("unable to get manifest_digest")
+	}
+	return digest, nil
+}
+
+// getTagDigests - the manifest digest of every tag of the repository.
+func (r QuayAdapter) getTagDigests(imageName string) (map[string]string, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf(quayDigestURL, r.config.URL, r.config.Org, imageName), nil)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", r.config.Token))
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newRegistryResponseError(resp, fmt.Sprintf("unable to get repository info for image %v", imageName))
+	}
 
 	type repoResponse struct {
 		Tags map[string]interface{} `json:"tags"`
@@ -174,27 +207,21 @@ func (r QuayAdapter) getDigest(imageName string) (string, error) {
 	err = json.NewDecoder(resp.Body).Decode(&digestResp)
 	if err != nil {
 		log.Errorf("unable to get repository Info for image: %s - %v", imageName, err)
-		return "", err
+		return nil, err
 	}
 
-	var digest string
+	digests := map[string]string{}
 	for key, item := range digestResp.Tags {
-		if key == r.config.Tag {
-			if tag, ok := item.(map[string]interface{}); ok {
-				digest = tag["manifest_digest"].(string)
-				break
+		if tag, ok := item.(map[string]interface{}); ok {
+			if digest, ok := tag["manifest_digest"].(string); ok {
+				digests[key] = digest
 			}
 		}
 	}
-
-	if digest == "" {
-		return "", errors.New("unable to get manifest_digest")
-	}
-
-	return digest, nil
+	return digests, nil
 }
 
-func (r QuayAdapter) digestToSpec(digest string, imageName string) (*bundle.Spec, error) {
+func (r QuayAdapter) digestToSpec(digest string, imageName string, tag string) (*bundle.Spec, error) {
 	if digest == "" {
 		return nil, errors.New("digest is nil")
 	}
@@ -212,6 +239,9 @@ func (r QuayAdapter) digestToSpec(digest string, imageName string) (*bundle.Spec
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, newRegistryResponseError(resp, fmt.Sprintf("unable to get labels for image %v", imageName))
+	}
 
 	type label struct {
 		Key   string `json:"key"`
@@ -263,7 +293,8 @@ func (r QuayAdapter) digestToSpec(digest string, imageName string) (*bundle.Spec
 		registryName = fmt.Sprintf("%s:%s", r.config.URL.Hostname(), r.config.URL.Port())
 	}
 
-	spec.Image = fmt.Sprintf("%s/%s/%s:%s", registryName, r.config.Org, imageName, r.config.Tag)
+	spec.Image = fmt.Sprintf("%s/%s/%s:%s", registryName, r.config.Org, imageName, tag)
+	spec.ImageDigest = digest
 
 	log.Debugf("adapter::imageToSpec -> Got plans %+v", spec.Plans)
 	log.Debugf("Successfully converted Image '%s' into Spec", spec.Image)
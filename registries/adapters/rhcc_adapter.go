@@ -18,12 +18,12 @@ package adapters
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 
 	"github.com/automationbroker/bundle-lib/bundle"
 	"github.com/automationbroker/bundle-lib/registries/adapters/oauth"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -83,18 +83,20 @@ func (r RHCCAdapter) GetImageNames() ([]string, error) {
 // FetchSpecs - retrieve the spec from the image names
 func (r RHCCAdapter) FetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
 	log.Debug("RHCCAdapter::FetchSpecs")
-	specs := []*bundle.Spec{}
-	for _, imageName := range imageNames {
-		log.Debugf("%v", imageName)
-		spec, err := r.loadSpec(imageName)
-		if err != nil {
-			log.Errorf("Failed to retrieve spec data for image %s - %v", imageName, err)
-		}
-		if spec != nil {
-			specs = append(specs, spec)
-		}
+	return r.fetcher().fetch(imageNames), nil
+}
+
+// FetchImageSpecs - retrieve the specs of the image, with the error of a
+// tag that could not be loaded.
+func (r RHCCAdapter) FetchImageSpecs(imageName string) ([]*bundle.Spec, error) {
+	return r.fetcher().fetchImage(imageName)
+}
+
+func (r RHCCAdapter) fetcher() specFetcher {
+	list := func(imageName string) ([]string, error) {
+		return listV2Tags(r.client, imageName)
 	}
-	return specs, nil
+	return newSpecFetcher(r.Config, r.Config.URL.Host, list, r.loadSpec)
 }
 
 // LoadImages - Get all the images for a particular query
@@ -130,12 +132,9 @@ func (r RHCCAdapter) loadImages(query string) (RHCCImageResponse, error) {
 	return imageResp, nil
 }
 
-func (r RHCCAdapter) loadSpec(imageName string) (*bundle.Spec, error) {
+func (r RHCCAdapter) loadSpec(imageName, tag string) (*bundle.Spec, error) {
 	log.Debug("RHCCAdapter::LoadSpec")
-	if r.Config.Tag == "" {
-		r.Config.Tag = "latest"
-	}
-	req, err := r.client.NewRequest(fmt.Sprintf("/v2/%v/manifests/%v", imageName, r.Config.Tag))
+	req, err := r.client.NewRequest(fmt.Sprintf("/v2/%v/manifests/%v", imageName, tag))
 	if err != nil {
 		return nil, err
 	}
@@ -146,8 +145,15 @@ func (r RHCCAdapter) loadSpec(imageName string) (*bundle.Spec, error) {
 	}
 	body, err := registryResponseHandler(resp)
 	if err != nil {
-		return nil, fmt.Errorf("RHCCAdapter::error handling openshift registery response %s", err)
+		return nil, errors.Wrap(err, "RHCCAdapter::error handling openshift registery response")
 	}
 
-	return responseToSpec(body, fmt.Sprintf("%s/%s:%s", r.RegistryName(), imageName, r.Config.Tag))
+	spec, err := responseToSpec(body, fmt.Sprintf("%s/%s:%s", r.RegistryName(), imageName, tag))
+	if err != nil || spec == nil {
+		return nil, err
+	}
+	// The digest of a signed schema 1 manifest is not the digest of its
+	// body, only the digest the registry returns is recorded.
+	spec.ImageDigest = resp.Header.Get(contentDigestHeader)
+	return spec, nil
 }
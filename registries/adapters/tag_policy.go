@@ -0,0 +1,209 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/Masterminds/semver"
+)
+
+const (
+	// TagPolicyFixed - the configured tag, latest by default.
+	TagPolicyFixed = ""
+	// TagPolicySemver - the highest semver tag, pre-releases excluded.
+	TagPolicySemver = "semver"
+	// TagPolicyRange - the semver tags within the constraint, e.g. ~1.2 or
+	// >=1.0, <2.0.
+	TagPolicyRange = "range"
+	// TagPolicyRegex - the tags matching the regex.
+	TagPolicyRegex = "regex"
+	// TagPolicyList - the listed tags the image has.
+	TagPolicyList = "list"
+
+	defaultTag = "latest"
+)
+
+// TagPolicy - Selects the tags of an image that specs are loaded from.
+type TagPolicy struct {
+	// Type - fixed when empty, semver, range, regex or list.
+	Type string
+	// Constraint - the semver constraint of the range policy.
+	Constraint string
+	// Regex - the pattern of the regex policy, matching the whole tag.
+	Regex string
+	// Tags - the tags of the list policy.
+	Tags []string
+	// Max - keep only the highest tags, all when zero.
+	Max int
+}
+
+// Validate - makes sure the policy can select tags.
+func (p TagPolicy) Validate() error {
+	switch p.Type {
+	case TagPolicyFixed, TagPolicySemver:
+	case TagPolicyRange:
+		if _, err := semver.NewConstraint(p.Constraint); err != nil {
+			return fmt.Errorf("invalid tag constraint %v - %v", p.Constraint, err)
+		}
+	case TagPolicyRegex:
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			return fmt.Errorf("invalid tag regex %v - %v", p.Regex, err)
+		}
+	case TagPolicyList:
+		if len(p.Tags) == 0 {
+			return fmt.Errorf("the list tag policy requires tags")
+		}
+	default:
+		return fmt.Errorf("unknown tag policy %v", p.Type)
+	}
+	return nil
+}
+
+// listsTags - whether the policy selects from the tags of the image.
+func (p TagPolicy) listsTags() bool {
+	return p.Type != TagPolicyFixed
+}
+
+// Select - the tags of the image the policy selects, highest first. Semver
+// tags sort by version, above the tags that are not semver.
+func (p TagPolicy) Select(tags []string) ([]string, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	selected := []string{}
+	switch p.Type {
+	case TagPolicySemver, TagPolicyRange:
+		var constraint *semver.Constraints
+		if p.Type == TagPolicyRange {
+			constraint, _ = semver.NewConstraint(p.Constraint)
+		}
+		for _, tag := range tags {
+			v, err := semver.NewVersion(tag)
+			if err != nil {
+				continue
+			}
+			if constraint == nil && v.Prerelease() != "" {
+				continue
+			}
+			if constraint != nil && !constraint.Check(v) {
+				continue
+			}
+			selected = append(selected, tag)
+		}
+	case TagPolicyRegex:
+		re := regexp.MustCompile(fmt.Sprintf("^(?:%s)$", p.Regex))
+		for _, tag := range tags {
+			if re.MatchString(tag) {
+				selected = append(selected, tag)
+			}
+		}
+	case TagPolicyList:
+		listed := map[string]bool{}
+		for _, tag := range tags {
+			listed[tag] = true
+		}
+		for _, tag := range p.Tags {
+			if listed[tag] {
+				selected = append(selected, tag)
+			}
+		}
+	}
+
+	SortTags(selected)
+	max := p.Max
+	if p.Type == TagPolicySemver {
+		max = 1
+	}
+	if max > 0 && len(selected) > max {
+		selected = selected[:max]
+	}
+	return selected, nil
+}
+
+// SortTags - sorts the tags highest first, semver tags by version above the
+// tags that are not semver.
+func SortTags(tags []string) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		vi, erri := semver.NewVersion(tags[i])
+		vj, errj := semver.NewVersion(tags[j])
+		switch {
+		case erri == nil && errj == nil:
+			if vi.Equal(vj) {
+				return tags[i] > tags[j]
+			}
+			return vi.GreaterThan(vj)
+		case erri == nil:
+			return true
+		case errj == nil:
+			return false
+		}
+		return tags[i] > tags[j]
+	})
+}
+
+// configuredTag - the tag of the fixed policy.
+func configuredTag(config Configuration) string {
+	if config.Tag == "" {
+		return defaultTag
+	}
+	return config.Tag
+}
+
+// tagLister - lists the tags of an image.
+type tagLister func(imageName string) ([]string, error)
+
+// tagDigester - the manifest digest of an image at a tag.
+type tagDigester func(imageName, tag string) (string, error)
+
+// selectTags - the tags of the image the configured policy selects.
+func selectTags(config Configuration, imageName string, list tagLister) ([]string, error) {
+	if !config.TagPolicy.listsTags() {
+		return []string{configuredTag(config)}, nil
+	}
+	tags, err := list(imageName)
+	if err != nil {
+		return nil, err
+	}
+	return config.TagPolicy.Select(tags)
+}
+
+// policyDigest - the digest of the selected tags of an image. The manifest
+// digest of the configured tag for the fixed policy. For the other policies
+// it is a digest over every selected tag and its manifest digest, so it
+// changes when a tag moves, appears or goes away.
+func policyDigest(config Configuration, imageName string, list tagLister, digest tagDigester) (string, error) {
+	if !config.TagPolicy.listsTags() {
+		return digest(imageName, configuredTag(config))
+	}
+	tags, err := selectTags(config, imageName, list)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, tag := range tags {
+		d, err := digest(imageName, tag)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s@%s\n", tag, d)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
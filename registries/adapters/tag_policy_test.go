@@ -0,0 +1,170 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package adapters
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters/adaptertest"
+	ft "github.com/stretchr/testify/assert"
+)
+
+var policyTags = []string{"latest", "1.0.0", "v1.2.0", "1.10.1", "2.0.0", "2.1.0-rc1", "release-1.2", "release-1.10", "nightly"}
+
+func TestTagPolicySelect(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      TagPolicy
+		expected    []string
+		shouldError bool
+	}{
+		{
+			name:     "highest semver skips pre-releases",
+			policy:   TagPolicy{Type: TagPolicySemver},
+			expected: []string{"2.0.0"},
+		},
+		{
+			name:     "range",
+			policy:   TagPolicy{Type: TagPolicyRange, Constraint: ">=1.2, <2.0"},
+			expected: []string{"1.10.1", "v1.2.0"},
+		},
+		{
+			name:     "range with max",
+			policy:   TagPolicy{Type: TagPolicyRange, Constraint: "^1.0", Max: 1},
+			expected: []string{"1.10.1"},
+		},
+		{
+			name:     "regex matches the whole tag",
+			policy:   TagPolicy{Type: TagPolicyRegex, Regex: "release-1\\.[0-9]+"},
+			expected: []string{"release-1.2", "release-1.10"},
+		},
+		{
+			name:     "list keeps the tags the image has",
+			policy:   TagPolicy{Type: TagPolicyList, Tags: []string{"nightly", "latest", "3.0.0"}},
+			expected: []string{"nightly", "latest"},
+		},
+		{
+			name:        "invalid constraint",
+			policy:      TagPolicy{Type: TagPolicyRange, Constraint: "one"},
+			shouldError: true,
+		},
+		{
+			name:        "invalid regex",
+			policy:      TagPolicy{Type: TagPolicyRegex, Regex: "("},
+			shouldError: true,
+		},
+		{
+			name:        "list without tags",
+			policy:      TagPolicy{Type: TagPolicyList},
+			shouldError: true,
+		},
+		{
+			name:        "unknown policy",
+			policy:      TagPolicy{Type: "newest"},
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tags, err := tc.policy.Select(policyTags)
+			if tc.shouldError {
+				ft.Error(t, err)
+				return
+			}
+			ft.NoError(t, err)
+			ft.Equal(t, tc.expected, tags)
+		})
+	}
+}
+
+func TestSpecFetcherTagPolicy(t *testing.T) {
+	list := func(name string) ([]string, error) {
+		return policyTags, nil
+	}
+	load := func(name, tag string) (*bundle.Spec, error) {
+		return &bundle.Spec{FQName: name, Image: fmt.Sprintf("%s:%s", name, tag)}, nil
+	}
+
+	config := Configuration{TagPolicy: TagPolicy{Type: TagPolicyRange, Constraint: "^1.0"}, FetchRetries: -1}
+	specs := newSpecFetcher(config, "policy", list, load).fetch([]string{"foo", "bar"})
+	images := []string{}
+	for _, spec := range specs {
+		images = append(images, spec.Image)
+	}
+	ft.Equal(t, []string{"foo:1.10.1", "foo:v1.2.0", "foo:1.0.0", "bar:1.10.1", "bar:v1.2.0", "bar:1.0.0"}, images)
+	ft.Equal(t, "v1.2.0", specs[1].BundleVersion)
+
+	// the fixed policy does not list tags
+	config = Configuration{Tag: "stable"}
+	specs = newSpecFetcher(config, "policy", nil, load).fetch([]string{"foo"})
+	ft.Equal(t, "foo:stable", specs[0].Image)
+	ft.Equal(t, "", specs[0].BundleVersion)
+}
+
+func TestPolicyDigest(t *testing.T) {
+	digests := map[string]string{"1.0.0": "sha256:a", "2.0.0": "sha256:b", "latest": "sha256:b"}
+	list := func(name string) ([]string, error) {
+		tags := []string{}
+		for tag := range digests {
+			tags = append(tags, tag)
+		}
+		return tags, nil
+	}
+	digest := func(name, tag string) (string, error) {
+		return digests[tag], nil
+	}
+
+	fixed, err := policyDigest(Configuration{}, "foo", list, digest)
+	ft.NoError(t, err)
+	ft.Equal(t, "sha256:b", fixed)
+
+	config := Configuration{TagPolicy: TagPolicy{Type: TagPolicyRange, Constraint: ">=1.0"}}
+	first, err := policyDigest(config, "foo", list, digest)
+	ft.NoError(t, err)
+	digests["1.0.0"] = "sha256:c"
+	moved, err := policyDigest(config, "foo", list, digest)
+	ft.NoError(t, err)
+	ft.NotEqual(t, first, moved)
+	digests["3.0.0"] = "sha256:d"
+	added, err := policyDigest(config, "foo", list, digest)
+	ft.NoError(t, err)
+	ft.NotEqual(t, moved, added)
+}
+
+func TestListV2Tags(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+		case r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", "</v2/foo/bar/tags/list?n=2&last=1.1.0>; rel=\"next\"")
+			fmt.Fprint(w, `{"name": "foo/bar", "tags": ["1.0.0", "1.1.0"]}`)
+		default:
+			fmt.Fprint(w, `{"name": "foo/bar", "tags": ["2.0.0"]}`)
+		}
+	}))
+	defer serv.Close()
+
+	apiv2a, err := NewAPIV2Adapter(Configuration{URL: adaptertest.GetURL(t, serv)})
+	ft.NoError(t, err)
+	tags, err := apiv2a.listTags("foo/bar")
+	ft.NoError(t, err)
+	ft.Equal(t, []string{"1.0.0", "1.1.0", "2.0.0"}, tags)
+}
@@ -0,0 +1,84 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"io"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotCatalog - Snapshot the loaded, filtered and validated specs of the
+// registries with the registry metadata and the digests of the images. Specs
+// without an ID get the deterministic ID of their bundle and version, so
+// the specs of a registry form a SpecManifest. A registry that fails is
+// skipped, unless it is configured to fail.
+func SnapshotCatalog(registries []Registry) (*adapters.CatalogArchive, error) {
+	archive := adapters.NewCatalogArchive()
+	for _, r := range registries {
+		images, imageCount, err := r.exportImages()
+		if err != nil {
+			log.Errorf("unable to snapshot the specs of registry %v - %v", r.config.Name, err)
+			if r.Fail(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		specs := []*bundle.Spec{}
+		digests := map[string]string{}
+		for _, image := range images {
+			for _, spec := range image.specs {
+				if digest := image.specDigest(spec); digest != "" {
+					digests[spec.Image] = digest
+				}
+				specs = append(specs, spec)
+			}
+		}
+		specs = publishVersions(specs)
+		for i, spec := range specs {
+			if spec.ID == "" {
+				s := *spec
+				s.ID = bundle.SpecID(s.BundleName(), s.BundleVersion)
+				specs[i] = &s
+			}
+		}
+
+		archive.Registries = append(archive.Registries, adapters.CatalogArchiveRegistry{
+			Name:       r.config.Name,
+			Type:       r.config.Type,
+			URL:        r.config.URL,
+			Org:        r.config.Org,
+			ImageCount: imageCount,
+			Specs:      bundle.NewSpecManifest(specs),
+			Digests:    digests,
+		})
+	}
+	return archive, nil
+}
+
+// ExportCatalogArchive - Write a snapshot of the registries to w, for an
+// archive registry on a cluster that can not reach them.
+func ExportCatalogArchive(registries []Registry, w io.Writer) error {
+	archive, err := SnapshotCatalog(registries)
+	if err != nil {
+		return err
+	}
+	return archive.Write(w)
+}
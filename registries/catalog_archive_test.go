@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogArchive(t *testing.T) {
+	config := Config{Name: "dh", Type: "dockerhub", Org: "bundles", WhiteList: []string{"^mariadb"}}
+	registries := []Registry{
+		{
+			adapter: indexAdapter{digests: map[string]string{"mariadb-apb": "sha256:a", "mysql-apb": "sha256:b"}},
+			filter:  createFilter(config),
+			config:  config,
+		},
+	}
+
+	snapshot, err := SnapshotCatalog(registries)
+	assert.NoError(t, err)
+	if !assert.Equal(t, 1, len(snapshot.Registries)) {
+		return
+	}
+	reg := snapshot.Registries[0]
+	assert.Equal(t, "dh", reg.Name)
+	assert.Equal(t, 2, reg.ImageCount)
+	// only the filtered specs are archived, by a deterministic ID
+	id := bundle.SpecID("mariadb-apb", "")
+	assert.Equal(t, []string{id}, func() []string {
+		ids := []string{}
+		for id := range reg.Specs {
+			ids = append(ids, id)
+		}
+		return ids
+	}())
+	assert.Equal(t, map[string]string{"docker.io/bundles/mariadb-apb:latest": "sha256:a"}, reg.Digests)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, ExportCatalogArchive(registries, buf))
+	read, err := adapters.ReadCatalogArchive(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, reg.Digests, read.Registries[0].Digests)
+	assert.Equal(t, "docker.io/bundles/mariadb-apb:latest", read.Registries[0].Specs[id].Image)
+
+	// the archive is loaded on the other side, pulling from the mirror
+	dir, err := ioutil.TempDir("", "catalog-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archivePath := filepath.Join(dir, "catalog.tar.gz")
+	if err := ioutil.WriteFile(archivePath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := NewRegistry(Config{
+		Type:      "archive",
+		Name:      "offline",
+		URL:       "file://" + archivePath,
+		Mirror:    "registry.internal:5000",
+		WhiteList: []string{".*"},
+	}, "")
+	assert.NoError(t, err)
+	specs, count, err := imported.LoadSpecs()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	if assert.Equal(t, 1, len(specs)) {
+		assert.Equal(t, id, specs[0].ID)
+		assert.Equal(t, "registry.internal:5000/bundles/mariadb-apb:latest", specs[0].Image)
+		assert.Equal(t, "docker.io/bundles/mariadb-apb:latest", specs[0].Metadata[bundle.OriginalImageMetadataKey])
+	}
+}
+
+func TestCatalogArchiveErrors(t *testing.T) {
+	_, err := adapters.ReadCatalogArchive(bytes.NewReader([]byte("not an archive")))
+	assert.Error(t, err)
+
+	a := &adapters.CatalogArchiveAdapter{}
+	_, err = a.GetImageNames()
+	assert.Error(t, err)
+}
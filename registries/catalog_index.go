@@ -0,0 +1,105 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	log "github.com/sirupsen/logrus"
+)
+
+// GenerateCatalogIndex - Export the specs of the registries to a catalog
+// index, which a catalog_index registry loads without the catalog API of
+// the registries. Specs are exported as the adapters load them, the
+// registry loading the index publishes their versions. A registry that
+// fails is skipped, unless it is configured to fail.
+func GenerateCatalogIndex(registries []Registry) (*adapters.CatalogIndex, error) {
+	index := adapters.NewCatalogIndex()
+	for _, r := range registries {
+		if err := r.exportSpecs(index); err != nil {
+			log.Errorf("unable to export the specs of registry %v - %v", r.config.Name, err)
+			if r.Fail(err) {
+				return nil, err
+			}
+		}
+	}
+	return index, nil
+}
+
+// exportSpecs - add the valid specs of the registry to the index.
+func (r Registry) exportSpecs(index *adapters.CatalogIndex) error {
+	images, _, err := r.exportImages()
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		for _, spec := range image.specs {
+			index.Add(r.config.Name, spec, image.specDigest(spec))
+		}
+	}
+	return nil
+}
+
+// exportedImage - the valid specs of an image and its manifest digest.
+type exportedImage struct {
+	specs  []*bundle.Spec
+	digest string
+}
+
+// specDigest - the digest of the image of the spec, recorded by the adapter
+// when it loaded the spec or looked up for the image.
+func (i exportedImage) specDigest(spec *bundle.Spec) string {
+	if spec.ImageDigest != "" {
+		return spec.ImageDigest
+	}
+	return i.digest
+}
+
+// exportImages - the valid specs of the images passing the filter, with the
+// manifest digest of their image when the adapter can look it up, and the
+// count of images in the registry.
+func (r Registry) exportImages() ([]exportedImage, int, error) {
+	imageNames, err := r.adapter.GetImageNames()
+	if err != nil {
+		return nil, 0, err
+	}
+	validNames, _ := r.filter.Run(imageNames)
+
+	// The digest of a tag policy covers every selected tag, it is not the
+	// digest of an image.
+	digester, _ := r.adapter.(adapters.DigestAdapter)
+	if r.config.TagPolicy.Type != adapters.TagPolicyFixed {
+		digester = nil
+	}
+
+	images := make([]exportedImage, len(validNames))
+	adapters.ForEachImage(r.config.FetchWorkers, validNames, func(idx int, imageName string) {
+		specs, err := r.adapter.FetchSpecs([]string{imageName})
+		if err != nil {
+			log.Errorf("unable to fetch spec for image %v - %v", imageName, err)
+			return
+		}
+		digest := ""
+		if digester != nil && len(specs) > 0 {
+			if digest, err = digester.ImageDigest(imageName); err != nil {
+				log.Warningf("unable to get the digest of image %v - %v", imageName, err)
+			}
+		}
+		images[idx] = exportedImage{specs: validateSpecs(specs), digest: digest}
+	})
+	return images, len(imageNames), nil
+}
@@ -0,0 +1,121 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	"github.com/stretchr/testify/assert"
+)
+
+// indexAdapter - serves valid specs, one per image, at a digest.
+type indexAdapter struct {
+	digests map[string]string
+}
+
+func (i indexAdapter) GetImageNames() ([]string, error) {
+	names := []string{}
+	for name := range i.digests {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (i indexAdapter) FetchSpecs(names []string) ([]*bundle.Spec, error) {
+	specs := []*bundle.Spec{}
+	for _, name := range names {
+		specs = append(specs, &bundle.Spec{
+			FQName:  name,
+			Image:   "docker.io/bundles/" + name + ":latest",
+			Version: "1.0",
+			Runtime: 2,
+			Plans:   []bundle.Plan{{Name: "default"}},
+		})
+	}
+	return specs, nil
+}
+
+func (i indexAdapter) RegistryName() string {
+	return "index"
+}
+
+func (i indexAdapter) ImageDigest(name string) (string, error) {
+	return i.digests[name], nil
+}
+
+func TestGenerateCatalogIndex(t *testing.T) {
+	config := Config{Name: "dh", WhiteList: []string{".*"}}
+	registries := []Registry{
+		{
+			adapter: indexAdapter{digests: map[string]string{"mariadb-apb": "sha256:a", "mysql-apb": "sha256:b"}},
+			filter:  createFilter(config),
+			config:  config,
+		},
+		// a registry that fails is skipped
+		{
+			adapter: errorAdapter{errGetImageNames: true},
+			filter:  createFilter(Config{}),
+			config:  Config{Name: "broken"},
+		},
+	}
+
+	index, err := GenerateCatalogIndex(registries)
+	assert.NoError(t, err)
+	assert.Equal(t, adapters.CatalogIndexAPIVersion, index.APIVersion)
+	assert.Equal(t, []string{"mariadb-apb", "mysql-apb"}, index.Names())
+	entry := index.Entries["mariadb-apb"][0]
+	assert.Equal(t, "docker.io/bundles/mariadb-apb:latest", entry.Image)
+	assert.Equal(t, "sha256:a", entry.Digest)
+	assert.Equal(t, 2, entry.Runtime)
+	assert.Equal(t, "dh", entry.Registry)
+
+	// unless it is configured to fail
+	registries[1].config.Fail = true
+	_, err = GenerateCatalogIndex(registries)
+	assert.Error(t, err)
+
+	// the index is loaded by a catalog_index registry
+	dir, err := ioutil.TempDir("", "catalog-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := index.Marshal()
+	assert.NoError(t, err)
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err := NewRegistry(Config{Type: "catalog_index", Name: "index", URL: "file://" + dir, WhiteList: []string{".*"}}, "")
+	assert.NoError(t, err)
+	specs, count, err := reg.LoadSpecs()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	images := map[string]string{}
+	for _, spec := range specs {
+		images[spec.FQName] = spec.Image
+	}
+	assert.Equal(t, map[string]string{
+		"mariadb-apb": "docker.io/bundles/mariadb-apb:latest",
+		"mysql-apb":   "docker.io/bundles/mysql-apb:latest",
+	}, images)
+}
@@ -0,0 +1,149 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	log "github.com/sirupsen/logrus"
+)
+
+var globalImageRewrites = struct {
+	sync.RWMutex
+	rules []ImageRewriteRule
+}{}
+
+// ImageRewriteRule - Rewrites the images starting with Prefix to start with
+// Replacement, e.g. registry.access.redhat.com to mirror.internal:5000. The
+// prefix matches whole parts of the reference, registry.access.redhat.com
+// does not match registry.access.redhat.com.cn/foo. Images without a
+// registry domain are docker.io images, matched as docker.io/<org>/<name>.
+type ImageRewriteRule struct {
+	Prefix      string
+	Replacement string
+}
+
+// rewrite - the image with the prefix replaced, false when the prefix does
+// not match.
+func (r ImageRewriteRule) rewrite(image string) (string, bool) {
+	for _, ref := range []string{image, adapters.NormalizeImage(image)} {
+		if !strings.HasPrefix(ref, r.Prefix) {
+			continue
+		}
+		rest := ref[len(r.Prefix):]
+		if rest != "" && !strings.HasSuffix(r.Prefix, "/") && !strings.ContainsAny(rest[:1], "/:@") {
+			continue
+		}
+		return r.Replacement + rest, true
+	}
+	return "", false
+}
+
+// validateImageRewrites - makes sure every rule has a prefix.
+func validateImageRewrites(rules []ImageRewriteRule) error {
+	for _, rule := range rules {
+		if rule.Prefix == "" {
+			return fmt.Errorf("image rewrite rule to %q requires a prefix", rule.Replacement)
+		}
+	}
+	return nil
+}
+
+// InitializeImageRewrites - Sets the image rewrite rules of every registry,
+// tried after the rules of the registry.
+func InitializeImageRewrites(rules []ImageRewriteRule) error {
+	if err := validateImageRewrites(rules); err != nil {
+		return err
+	}
+	globalImageRewrites.Lock()
+	defer globalImageRewrites.Unlock()
+	globalImageRewrites.rules = rules
+	return nil
+}
+
+// rewriteImage - the image rewritten by the first matching rule of the
+// registry or the global rules, pulled from the mirror of the registry when
+// no rule matches. False when the image is not rewritten.
+func (r Registry) rewriteImage(image string) (string, bool) {
+	globalImageRewrites.RLock()
+	rules := append(append([]ImageRewriteRule{}, r.config.ImageRewrites...), globalImageRewrites.rules...)
+	globalImageRewrites.RUnlock()
+
+	for _, rule := range rules {
+		if rewritten, ok := rule.rewrite(image); ok {
+			return rewritten, rewritten != image
+		}
+	}
+	if r.config.Mirror != "" {
+		mirrored := adapters.MirrorImage(image, r.config.Mirror)
+		return mirrored, mirrored != image
+	}
+	return image, false
+}
+
+// rewriteImages - the specs with their image rewritten. The loaded image is
+// kept in the metadata of the spec for provenance, specs are copied so the
+// adapters and the spec cache keep the loaded image.
+func (r Registry) rewriteImages(specs []*bundle.Spec) []*bundle.Spec {
+	rewritten := make([]*bundle.Spec, 0, len(specs))
+	for _, spec := range specs {
+		image, ok := r.rewriteImage(spec.Image)
+		if !ok {
+			rewritten = append(rewritten, spec)
+			continue
+		}
+		log.Debugf("Rewrote image %s of spec %s to %s", spec.Image, spec.FQName, image)
+		rewritten = append(rewritten, withImage(spec, image))
+	}
+	return rewritten
+}
+
+// pinImages - the specs with their image pinned to the digest the adapter
+// loaded them from, so a moved tag does not change the code the broker
+// runs. Specs without a digest keep their image.
+func pinImages(specs []*bundle.Spec) []*bundle.Spec {
+	pinned := make([]*bundle.Spec, 0, len(specs))
+	for _, spec := range specs {
+		if spec.ImageDigest == "" {
+			log.Warningf("unable to pin image %s of spec %s, the digest of the image is unknown", spec.Image, spec.FQName)
+			pinned = append(pinned, spec)
+			continue
+		}
+		pinned = append(pinned, withImage(spec, bundle.PinImage(spec.Image, spec.ImageDigest)))
+	}
+	return pinned
+}
+
+// withImage - a copy of the spec with the image, the first image of the spec
+// is kept in its metadata.
+func withImage(spec *bundle.Spec, image string) *bundle.Spec {
+	s := *spec
+	metadata := map[string]interface{}{}
+	for key, value := range spec.Metadata {
+		metadata[key] = value
+	}
+	if _, ok := metadata[bundle.OriginalImageMetadataKey]; !ok {
+		metadata[bundle.OriginalImageMetadataKey] = spec.Image
+	}
+	s.Metadata = metadata
+	s.Image = image
+	return &s
+}
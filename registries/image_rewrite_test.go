@@ -0,0 +1,133 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageRewriteRule(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     ImageRewriteRule
+		image    string
+		expected string
+		matched  bool
+	}{
+		{
+			name:     "domain prefix",
+			rule:     ImageRewriteRule{Prefix: "registry.access.redhat.com", Replacement: "mirror.internal:5000"},
+			image:    "registry.access.redhat.com/rhscl/mysql-57-rhel7:latest",
+			expected: "mirror.internal:5000/rhscl/mysql-57-rhel7:latest",
+			matched:  true,
+		},
+		{
+			name:  "prefix matches whole parts",
+			rule:  ImageRewriteRule{Prefix: "registry.access.redhat.com", Replacement: "mirror.internal:5000"},
+			image: "registry.access.redhat.com.cn/rhscl/mysql",
+		},
+		{
+			name:     "docker hub images without a domain",
+			rule:     ImageRewriteRule{Prefix: "docker.io/ansibleplaybookbundle", Replacement: "mirror.internal/apb"},
+			image:    "ansibleplaybookbundle/mysql-apb:latest",
+			expected: "mirror.internal/apb/mysql-apb:latest",
+			matched:  true,
+		},
+		{
+			name:     "repository prefix keeps the digest",
+			rule:     ImageRewriteRule{Prefix: "quay.io/org/foo", Replacement: "mirror.internal/foo"},
+			image:    "quay.io/org/foo@sha256:abc",
+			expected: "mirror.internal/foo@sha256:abc",
+			matched:  true,
+		},
+		{
+			name:  "other registries are kept",
+			rule:  ImageRewriteRule{Prefix: "quay.io", Replacement: "mirror.internal"},
+			image: "docker.io/org/foo:latest",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			image, ok := tc.rule.rewrite(tc.image)
+			assert.Equal(t, tc.matched, ok)
+			if tc.matched {
+				assert.Equal(t, tc.expected, image)
+			}
+		})
+	}
+}
+
+func TestRegistryRewriteImages(t *testing.T) {
+	err := InitializeImageRewrites([]ImageRewriteRule{
+		{Prefix: "quay.io", Replacement: "global.internal"},
+		{Prefix: "docker.io", Replacement: "global.internal"},
+	})
+	assert.NoError(t, err)
+	defer InitializeImageRewrites(nil)
+
+	r := Registry{config: Config{
+		ImageRewrites: []ImageRewriteRule{{Prefix: "quay.io/org", Replacement: "registry.internal/org"}},
+		Mirror:        "mirror.internal:5000",
+	}}
+	loaded := []*bundle.Spec{
+		{FQName: "foo", Image: "quay.io/org/foo:1.0", Metadata: map[string]interface{}{"displayName": "Foo"}},
+		{FQName: "bar", Image: "quay.io/other/bar:1.0"},
+		{FQName: "baz", Image: "registry.example.com/baz:1.0"},
+		{FQName: "local", Image: "mirror.internal:5000/local:1.0"},
+	}
+	specs := r.rewriteImages(loaded)
+
+	// the rules of the registry win over the global ones
+	assert.Equal(t, "registry.internal/org/foo:1.0", specs[0].Image)
+	assert.Equal(t, "quay.io/org/foo:1.0", specs[0].Metadata[bundle.OriginalImageMetadataKey])
+	assert.Equal(t, "Foo", specs[0].Metadata["displayName"])
+	assert.Equal(t, "global.internal/other/bar:1.0", specs[1].Image)
+	// the mirror when no rule matches
+	assert.Equal(t, "mirror.internal:5000/baz:1.0", specs[2].Image)
+	// images already on the mirror are not changed
+	assert.Equal(t, loaded[3], specs[3])
+
+	// the loaded specs keep their image
+	assert.Equal(t, "quay.io/org/foo:1.0", loaded[0].Image)
+	assert.Nil(t, loaded[0].Metadata[bundle.OriginalImageMetadataKey])
+
+	assert.Error(t, InitializeImageRewrites([]ImageRewriteRule{{Replacement: "mirror.internal"}}))
+}
+
+func TestPinImages(t *testing.T) {
+	loaded := []*bundle.Spec{
+		{FQName: "foo", Image: "quay.io/org/foo:1.0", ImageDigest: "sha256:a"},
+		{FQName: "bar", Image: "quay.io/org/bar:1.0"},
+	}
+	specs := pinImages(loaded)
+
+	assert.Equal(t, "quay.io/org/foo@sha256:a", specs[0].Image)
+	assert.Equal(t, "quay.io/org/foo:1.0", specs[0].Metadata[bundle.OriginalImageMetadataKey])
+	// the loaded specs are not changed
+	assert.Equal(t, "quay.io/org/foo:1.0", loaded[0].Image)
+	// specs without a digest keep their image
+	assert.Equal(t, loaded[1], specs[1])
+
+	// a mirror rewrites the pinned image, the tag is kept as the original
+	r := Registry{config: Config{Mirror: "mirror.internal:5000"}}
+	specs = r.rewriteImages(specs)
+	assert.Equal(t, "mirror.internal:5000/org/foo@sha256:a", specs[0].Image)
+	assert.Equal(t, "quay.io/org/foo:1.0", specs[0].Metadata[bundle.OriginalImageMetadataKey])
+}
@@ -61,6 +61,32 @@ type Config struct {
 	WhiteList     []string `yaml:"white_list"`
 	BlackList     []string `yaml:"black_list"`
 	SkipVerifyTLS bool     `yaml:"skip_verify_tls"`
+	// FetchWorkers is how many specs are loaded at once, FetchRetries how
+	// often transient errors, like 429 Too Many Requests, are retried and
+	// RateLimit the spec loads per second sent to the registry host.
+	FetchWorkers int     `yaml:"fetch_workers"`
+	FetchRetries int     `yaml:"fetch_retries"`
+	RateLimit    float64 `yaml:"rate_limit"`
+	// Architecture is the platform of the cluster, used to pick the image
+	// from multi-arch manifest lists. Defaults to the broker's.
+	Architecture string
+	// TagPolicy selects the tags of every image specs are loaded from,
+	// e.g. the highest semver tag or the tags of a release line. Tag is
+	// used when it is empty.
+	TagPolicy adapters.TagPolicy `yaml:"tag_policy"`
+	// ImageRewrites rewrite the images of the specs after loading, e.g. to
+	// pull from a mirror. They are tried before the global rules.
+	ImageRewrites []ImageRewriteRule `yaml:"image_rewrites"`
+	// Mirror is the registry the images are pulled from when no rewrite
+	// rule matches, e.g. registry.internal:5000.
+	Mirror string
+	// PinDigests pins the images of the specs to the manifest digest they
+	// were loaded from, e.g. docker.io/org/foo@sha256:..., so a moved tag
+	// does not change the code the broker runs.
+	PinDigests bool `yaml:"pin_digests"`
+	// Cache will keep the specs of the registry between loads and only
+	// refetch the images whose manifest digest changed.
+	Cache SpecCacheConfig
 }
 
 // Validate - makes sure the registry config is valid.
@@ -105,6 +131,7 @@ type Registry struct {
 	adapter adapters.Adapter
 	filter  Filter
 	config  Config
+	cache   *SpecCache
 }
 
 // LoadSpecs - Load the specs for the registry.
@@ -136,7 +163,7 @@ func (r Registry) LoadSpecs() ([]*bundle.Spec, int, error) {
 	}
 
 	// Debug output filtered out names.
-	specs, err := r.adapter.FetchSpecs(validNames)
+	specs, err := r.fetchSpecs(validNames)
 	if err != nil {
 		log.Errorf("unable to fetch specs for registry %v - %v",
 			r.config.Name, err)
@@ -146,6 +173,11 @@ func (r Registry) LoadSpecs() ([]*bundle.Spec, int, error) {
 	log.Infof("Validating specs...")
 	validatedSpecs := validateSpecs(specs)
 	failedSpecsCount := len(specs) - len(validatedSpecs)
+	validatedSpecs = publishVersions(validatedSpecs)
+	if r.config.PinDigests {
+		validatedSpecs = pinImages(validatedSpecs)
+	}
+	validatedSpecs = r.rewriteImages(validatedSpecs)
 
 	if failedSpecsCount != 0 {
 		log.Warningf(
@@ -158,6 +190,23 @@ func (r Registry) LoadSpecs() ([]*bundle.Spec, int, error) {
 	return validatedSpecs, len(imageNames), nil
 }
 
+// fetchSpecs - fetch the specs through the spec cache when it is enabled.
+func (r Registry) fetchSpecs(imageNames []string) ([]*bundle.Spec, error) {
+	if r.cache == nil {
+		return r.adapter.FetchSpecs(imageNames)
+	}
+	return r.cache.FetchSpecs(r.adapter, imageNames)
+}
+
+// CacheStats - the hits and misses of the spec cache of the registry, false
+// when the cache is disabled.
+func (r Registry) CacheStats() (SpecCacheStats, bool) {
+	if r.cache == nil {
+		return SpecCacheStats{}, false
+	}
+	return r.cache.Stats(), true
+}
+
 // Fail - will determine if the registry should cause a failure.
 func (r Registry) Fail(err error) bool {
 	if r.config.Fail {
@@ -176,6 +225,12 @@ func NewCustomRegistry(configuration Config, adapter adapters.Adapter, asbNamesp
 	if !configuration.Validate() {
 		return Registry{}, errors.New("unable to validate registry name")
 	}
+	if err := configuration.TagPolicy.Validate(); err != nil {
+		return Registry{}, err
+	}
+	if err := validateImageRewrites(configuration.ImageRewrites); err != nil {
+		return Registry{}, err
+	}
 
 	// Retrieve registry auth if defined.
 	configuration, err := retrieveRegistryAuth(configuration, asbNamespace)
@@ -212,6 +267,11 @@ func NewCustomRegistry(configuration Config, adapter adapters.Adapter, asbNamesp
 			Tag:           configuration.Tag,
 			SkipVerifyTLS: configuration.SkipVerifyTLS,
 			AdapterName:   configuration.Name,
+			FetchWorkers:  configuration.FetchWorkers,
+			FetchRetries:  configuration.FetchRetries,
+			RateLimit:     configuration.RateLimit,
+			Architecture:  configuration.Architecture,
+			TagPolicy:     configuration.TagPolicy,
 		}
 
 		switch strings.ToLower(configuration.Type) {
@@ -223,6 +283,12 @@ func NewCustomRegistry(configuration Config, adapter adapters.Adapter, asbNamesp
 			adapter = &adapters.MockAdapter{Config: c}
 		case "local_openshift":
 			adapter = &adapters.LocalOpenShiftAdapter{Config: c}
+		case "local_dir":
+			adapter = &adapters.LocalDirAdapter{Config: c}
+		case "catalog_index":
+			adapter = &adapters.CatalogIndexAdapter{Config: c}
+		case "archive":
+			adapter = &adapters.CatalogArchiveAdapter{Config: c}
 		case "helm":
 			adapter = &adapters.HelmAdapter{Config: c}
 		case "openshift":
@@ -246,10 +312,17 @@ func NewCustomRegistry(configuration Config, adapter adapters.Adapter, asbNamesp
 		log.Infof("Using custom adapter, %v", adapter.RegistryName())
 	}
 
+	cache, err := newSpecCache(configuration, asbNamespace)
+	if err != nil {
+		log.Errorf("Unable to create the spec cache: %v", err)
+		return Registry{}, err
+	}
+
 	return Registry{
 		adapter: adapter,
 		filter:  createFilter(configuration),
 		config:  configuration,
+		cache:   cache,
 	}, nil
 }
 
@@ -349,6 +422,10 @@ func validateSpecFormat(spec *bundle.Spec) (bool, string) {
 		dupes[plan.Name] = true
 	}
 
+	if err := spec.ValidateCredentialDescriptors(); err != nil {
+		return false, err.Error()
+	}
+
 	return true, ""
 }
 
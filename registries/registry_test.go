@@ -575,6 +575,30 @@ func TestNewRegistry(t *testing.T) {
 				return ok
 			},
 		},
+		{
+			name: "local_dir should return a LocalDirAdapter",
+			c: Config{
+				Type: "local_dir",
+				Name: "localdir",
+				URL:  "file:///srv/bundles",
+			},
+			validate: func(reg Registry) bool {
+				_, ok := reg.adapter.(*adapters.LocalDirAdapter)
+				return ok
+			},
+		},
+		{
+			name: "catalog_index should return a CatalogIndexAdapter",
+			c: Config{
+				Type: "catalog_index",
+				Name: "index",
+				URL:  "https://bundles.example.com/index.yaml",
+			},
+			validate: func(reg Registry) bool {
+				_, ok := reg.adapter.(*adapters.CatalogIndexAdapter)
+				return ok
+			},
+		},
 		{
 			name: "helm should return a HelmAdapter",
 			c: Config{
@@ -0,0 +1,407 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/metrics"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SpecCacheStoreFile - persist the spec cache in a json file.
+	SpecCacheStoreFile = "file"
+	// SpecCacheStoreConfigMap - persist the spec cache in a config map.
+	SpecCacheStoreConfigMap = "configmap"
+
+	// specCacheConfigMapKey - key of the config map holding the spec cache.
+	specCacheConfigMapKey = "specs"
+)
+
+// SpecCacheConfig - Configuration of the spec cache of a registry.
+type SpecCacheConfig struct {
+	Enabled bool
+	// TTL - how long a cached spec is used before it is refetched, e.g.
+	// 1h. Specs of adapters that can not look up the image digest are only
+	// cached with a TTL.
+	TTL string `yaml:"ttl"`
+	// Store - where the cache is persisted between restarts, `file`,
+	// `configmap` or empty to keep it in memory.
+	Store string
+	// Location - the path of the file or the name of the config map.
+	// The config map defaults to <registry name>-spec-cache.
+	Location string
+}
+
+// SpecCacheEntry - The specs of an image at a manifest digest.
+type SpecCacheEntry struct {
+	Digest  string         `json:"digest"`
+	Specs   []*bundle.Spec `json:"specs"`
+	Fetched time.Time      `json:"fetched"`
+}
+
+// SpecCacheStats - Hits and misses of a spec cache.
+type SpecCacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// HitRate - the share of the specs loaded from the cache.
+func (s SpecCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// SpecCacheStore - Persists the entries of a spec cache, keyed by image name.
+type SpecCacheStore interface {
+	Load() (map[string]SpecCacheEntry, error)
+	Save(map[string]SpecCacheEntry) error
+}
+
+// SpecCache - Caches the specs of a registry by image and manifest digest so
+// only the images whose digest changed are refetched.
+type SpecCache struct {
+	registry string
+	ttl      time.Duration
+	store    SpecCacheStore
+	now      func() time.Time
+	// workers - how many images are checked and fetched at once.
+	workers int
+
+	mutex   sync.Mutex
+	entries map[string]SpecCacheEntry
+	stats   SpecCacheStats
+}
+
+// NewSpecCache - Create a spec cache for the registry, loading the entries
+// persisted in the store. A nil store keeps the cache in memory.
+func NewSpecCache(registry string, ttl time.Duration, store SpecCacheStore) *SpecCache {
+	c := &SpecCache{
+		registry: registry,
+		ttl:      ttl,
+		store:    store,
+		now:      time.Now,
+		entries:  map[string]SpecCacheEntry{},
+	}
+	if store != nil {
+		entries, err := store.Load()
+		if err != nil {
+			log.Warningf("unable to load the spec cache of registry %v - %v", registry, err)
+		} else if entries != nil {
+			c.entries = entries
+		}
+	}
+	return c
+}
+
+// Stats - the hits and misses of the cache since it was created.
+func (c *SpecCache) Stats() SpecCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
+
+// FetchSpecs - Retrieve the specs of the images, fetching them from the
+// adapter only when they are not cached, their digest changed or they
+// expired.
+func (c *SpecCache) FetchSpecs(adapter adapters.Adapter, imageNames []string) ([]*bundle.Spec, error) {
+	digester, _ := adapter.(adapters.DigestAdapter)
+	results := make([][]*bundle.Spec, len(imageNames))
+	run := SpecCacheStats{}
+	runMutex := sync.Mutex{}
+
+	adapters.ForEachImage(c.workers, imageNames, func(idx int, imageName string) {
+		digest := ""
+		digestFailed := false
+		if digester != nil {
+			d, err := digester.ImageDigest(imageName)
+			if err != nil {
+				log.Warningf("unable to get the digest of image %v - %v", imageName, err)
+				digestFailed = true
+			}
+			digest = d
+		}
+
+		var cached []*bundle.Spec
+		ok := false
+		if !digestFailed {
+			cached, ok = c.lookup(imageName, digest)
+		}
+		runMutex.Lock()
+		if ok {
+			run.Hits++
+		} else {
+			run.Misses++
+		}
+		runMutex.Unlock()
+		if ok {
+			metrics.SpecCacheHit(c.registry)
+			results[idx] = cached
+			return
+		}
+
+		metrics.SpecCacheMiss(c.registry)
+		fetched, cacheable, err := fetchImageSpecs(adapter, imageName)
+		if err != nil {
+			log.Errorf("unable to fetch spec for image %v - %v", imageName, err)
+		}
+		results[idx] = fetched
+		// The entry of an image whose digest is unknown is kept, it is
+		// replaced once the digest can be looked up again.
+		if cacheable && !digestFailed {
+			c.add(imageName, digest, fetched)
+		}
+	})
+
+	specs := []*bundle.Spec{}
+	for _, fetched := range results {
+		specs = append(specs, fetched...)
+	}
+	c.finish(imageNames, run)
+	log.Infof("spec cache of registry %v: %d hits, %d misses, %.0f%% hit rate",
+		c.registry, run.Hits, run.Misses, run.HitRate()*100)
+	return specs, nil
+}
+
+// fetchImageSpecs - the specs of the image and whether they can be cached.
+// Only specs fetched without an error are cached. Adapters that do not
+// report the errors of an image log and skip it, so an image without specs
+// is not cached as it may have failed to load.
+func fetchImageSpecs(adapter adapters.Adapter, imageName string) ([]*bundle.Spec, bool, error) {
+	if a, ok := adapter.(adapters.ImageSpecAdapter); ok {
+		specs, err := a.FetchImageSpecs(imageName)
+		return specs, err == nil, err
+	}
+	specs, err := adapter.FetchSpecs([]string{imageName})
+	if err != nil {
+		return nil, false, err
+	}
+	return specs, len(specs) > 0, nil
+}
+
+// lookup - the cached specs of the image, copied so callers can not change
+// the cache.
+func (c *SpecCache) lookup(imageName, digest string) ([]*bundle.Spec, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[imageName]
+	if !ok || entry.Digest != digest {
+		return nil, false
+	}
+	// Without a digest only the TTL tells that the spec might have changed.
+	if digest == "" && c.ttl == 0 {
+		return nil, false
+	}
+	if c.ttl > 0 && c.now().Sub(entry.Fetched) > c.ttl {
+		return nil, false
+	}
+	specs := []*bundle.Spec{}
+	for _, spec := range entry.Specs {
+		s := *spec
+		specs = append(specs, &s)
+	}
+	return specs, true
+}
+
+func (c *SpecCache) add(imageName, digest string, specs []*bundle.Spec) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cached := []*bundle.Spec{}
+	for _, spec := range specs {
+		s := *spec
+		cached = append(cached, &s)
+	}
+	c.entries[imageName] = SpecCacheEntry{Digest: digest, Specs: cached, Fetched: c.now()}
+}
+
+// finish - drops the images that are no longer in the registry and saves
+// the cache.
+func (c *SpecCache) finish(imageNames []string, run SpecCacheStats) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.stats.Hits += run.Hits
+	c.stats.Misses += run.Misses
+
+	listed := map[string]bool{}
+	for _, imageName := range imageNames {
+		listed[imageName] = true
+	}
+	for imageName := range c.entries {
+		if !listed[imageName] {
+			delete(c.entries, imageName)
+		}
+	}
+
+	if c.store == nil || run.Misses == 0 {
+		return
+	}
+	if err := c.store.Save(c.entries); err != nil {
+		log.Warningf("unable to save the spec cache of registry %v - %v", c.registry, err)
+	}
+}
+
+// FileSpecCacheStore - Persists the spec cache in a json file.
+type FileSpecCacheStore struct {
+	Path string
+}
+
+// Load - read the entries from the file, a missing file is an empty cache.
+func (f FileSpecCacheStore) Load() (map[string]SpecCacheEntry, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := map[string]SpecCacheEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Save - write the entries to the file.
+func (f FileSpecCacheStore) Save(entries map[string]SpecCacheEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	// Write and rename so a crash does not leave a truncated cache behind.
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), filepath.Base(f.Path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// ConfigMapSpecCacheStore - Persists the spec cache in a config map. Config
+// maps are limited to 1MiB, large registries should use a file.
+type ConfigMapSpecCacheStore struct {
+	Name      string
+	Namespace string
+}
+
+// Load - read the entries from the config map, a missing config map is an
+// empty cache.
+func (s ConfigMapSpecCacheStore) Load() (map[string]SpecCacheEntry, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := k8scli.Client.CoreV1().ConfigMaps(s.Namespace).Get(s.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := cm.Data[specCacheConfigMapKey]
+	if !ok {
+		return nil, nil
+	}
+	entries := map[string]SpecCacheEntry{}
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Save - write the entries to the config map, creating it when missing.
+func (s ConfigMapSpecCacheStore) Save(entries map[string]SpecCacheEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.Name,
+			Namespace: s.Namespace,
+		},
+		Data: map[string]string{specCacheConfigMapKey: string(data)},
+	}
+	_, err = k8scli.Client.CoreV1().ConfigMaps(s.Namespace).Update(cm)
+	if k8serrors.IsNotFound(err) {
+		_, err = k8scli.Client.CoreV1().ConfigMaps(s.Namespace).Create(cm)
+	}
+	return err
+}
+
+// newSpecCache - the spec cache configured for the registry, nil when the
+// cache is disabled.
+func newSpecCache(config Config, namespace string) (*SpecCache, error) {
+	if !config.Cache.Enabled {
+		return nil, nil
+	}
+	var ttl time.Duration
+	if config.Cache.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(config.Cache.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec cache ttl %v - %v", config.Cache.TTL, err)
+		}
+	}
+
+	var store SpecCacheStore
+	switch config.Cache.Store {
+	case "":
+	case SpecCacheStoreFile:
+		if config.Cache.Location == "" {
+			return nil, fmt.Errorf("the spec cache file store requires a location")
+		}
+		store = FileSpecCacheStore{Path: config.Cache.Location}
+	case SpecCacheStoreConfigMap:
+		name := config.Cache.Location
+		if name == "" {
+			name = fmt.Sprintf("%s-spec-cache", config.Name)
+		}
+		store = ConfigMapSpecCacheStore{Name: name, Namespace: namespace}
+	default:
+		return nil, fmt.Errorf("unknown spec cache store %v", config.Cache.Store)
+	}
+	c := NewSpecCache(config.Name, ttl, store)
+	c.workers = config.FetchWorkers
+	return c, nil
+}
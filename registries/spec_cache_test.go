@@ -0,0 +1,343 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/stretchr/testify/assert"
+)
+
+type digestAdapter struct {
+	mutex   sync.Mutex
+	digests map[string]string
+	fetched map[string]int
+}
+
+func (d *digestAdapter) GetImageNames() ([]string, error) {
+	names := []string{}
+	for name := range d.digests {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (d *digestAdapter) FetchSpecs(names []string) ([]*bundle.Spec, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	specs := []*bundle.Spec{}
+	for _, name := range names {
+		d.fetched[name]++
+		specs = append(specs, &bundle.Spec{FQName: name, Version: d.digests[name]})
+	}
+	return specs, nil
+}
+
+func (d *digestAdapter) RegistryName() string {
+	return "digest"
+}
+
+func (d *digestAdapter) ImageDigest(name string) (string, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	digest, ok := d.digests[name]
+	if !ok {
+		return "", fmt.Errorf("unknown image %v", name)
+	}
+	return digest, nil
+}
+
+func TestSpecCacheDigests(t *testing.T) {
+	a := &digestAdapter{
+		digests: map[string]string{"foo": "sha256:1", "bar": "sha256:2"},
+		fetched: map[string]int{},
+	}
+	c := NewSpecCache("test", 0, nil)
+	names := []string{"foo", "bar"}
+
+	specs, err := c.FetchSpecs(a, names)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(specs))
+	assert.Equal(t, SpecCacheStats{Hits: 0, Misses: 2}, c.Stats())
+
+	specs, err = c.FetchSpecs(a, names)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(specs))
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 1}, a.fetched)
+	assert.Equal(t, 0.5, c.Stats().HitRate())
+
+	// changing a returned spec does not change the cache
+	specs[0].Version = "changed"
+	a.digests["bar"] = "sha256:3"
+	specs, err = c.FetchSpecs(a, names)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"foo": 1, "bar": 2}, a.fetched)
+	assert.Equal(t, "sha256:1", specs[0].Version)
+	assert.Equal(t, "sha256:3", specs[1].Version)
+
+	// images that left the registry are dropped
+	_, err = c.FetchSpecs(a, []string{"foo"})
+	assert.NoError(t, err)
+	_, ok := c.entries["bar"]
+	assert.False(t, ok)
+}
+
+func TestRegistryLoadSpecsCached(t *testing.T) {
+	a := &digestAdapter{
+		digests: map[string]string{"foo-apb": "sha256:1"},
+		fetched: map[string]int{},
+	}
+	reg, err := NewCustomRegistry(Config{Name: "cached", WhiteList: []string{".*"}, Cache: SpecCacheConfig{Enabled: true}}, a, "")
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, count, err := reg.LoadSpecs()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+	}
+	assert.Equal(t, 1, a.fetched["foo-apb"])
+	stats, ok := reg.CacheStats()
+	assert.True(t, ok)
+	assert.Equal(t, SpecCacheStats{Hits: 2, Misses: 1}, stats)
+
+	_, ok = Registry{}.CacheStats()
+	assert.False(t, ok)
+}
+
+func TestSpecCacheTTL(t *testing.T) {
+	now := time.Now()
+	a := &digestAdapter{digests: map[string]string{"foo": "sha256:1"}, fetched: map[string]int{}}
+	c := NewSpecCache("test", time.Hour, nil)
+	c.now = func() time.Time { return now }
+
+	c.FetchSpecs(a, []string{"foo"})
+	now = now.Add(30 * time.Minute)
+	c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, 1, a.fetched["foo"])
+	now = now.Add(time.Hour)
+	c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, 2, a.fetched["foo"])
+}
+
+func TestSpecCacheWithoutDigests(t *testing.T) {
+	a := &TestingAdapter{
+		Images: []string{"foo"},
+		Specs:  []*bundle.Spec{{FQName: "foo"}},
+		Called: map[string]bool{},
+	}
+
+	// without a digest or a ttl nothing is cached
+	c := NewSpecCache("test", 0, nil)
+	c.FetchSpecs(a, []string{"foo"})
+	c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, SpecCacheStats{Misses: 2}, c.Stats())
+
+	c = NewSpecCache("test", time.Hour, nil)
+	c.FetchSpecs(a, []string{"foo"})
+	specs, _ := c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, SpecCacheStats{Hits: 1, Misses: 1}, c.Stats())
+	assert.Equal(t, "foo", specs[0].FQName)
+}
+
+// imageSpecAdapter - a digestAdapter reporting the errors of an image.
+type imageSpecAdapter struct {
+	digestAdapter
+	fetchErr  error
+	digestErr error
+}
+
+func (i *imageSpecAdapter) FetchImageSpecs(name string) ([]*bundle.Spec, error) {
+	if i.fetchErr != nil {
+		i.fetched[name]++
+		return nil, i.fetchErr
+	}
+	return i.FetchSpecs([]string{name})
+}
+
+func (i *imageSpecAdapter) ImageDigest(name string) (string, error) {
+	if i.digestErr != nil {
+		return "", i.digestErr
+	}
+	return i.digestAdapter.ImageDigest(name)
+}
+
+func TestSpecCacheFailedFetch(t *testing.T) {
+	a := &imageSpecAdapter{
+		digestAdapter: digestAdapter{digests: map[string]string{"foo": "sha256:1"}, fetched: map[string]int{}},
+		fetchErr:      fmt.Errorf("too many requests"),
+	}
+	c := NewSpecCache("test", 0, nil)
+
+	// a failed fetch is not cached
+	specs, err := c.FetchSpecs(a, []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(specs))
+	_, ok := c.entries["foo"]
+	assert.False(t, ok)
+
+	a.fetchErr = nil
+	specs, _ = c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, 1, len(specs))
+	specs, _ = c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, 1, len(specs))
+	assert.Equal(t, 2, a.fetched["foo"])
+
+	// the entry is kept when the digest can not be looked up
+	a.digestErr = fmt.Errorf("unavailable")
+	specs, _ = c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, 1, len(specs))
+	assert.Equal(t, 3, a.fetched["foo"])
+	assert.Equal(t, "sha256:1", c.entries["foo"].Digest)
+
+	a.digestErr = nil
+	c.FetchSpecs(a, []string{"foo"})
+	assert.Equal(t, 3, a.fetched["foo"])
+}
+
+func TestSpecCacheSkipsEmptyResults(t *testing.T) {
+	a := &TestingAdapter{
+		Images: []string{"foo"},
+		Specs:  []*bundle.Spec{},
+		Called: map[string]bool{},
+	}
+	// an adapter that does not report errors may have failed to load
+	c := NewSpecCache("test", time.Hour, nil)
+	c.FetchSpecs(a, []string{"foo"})
+	_, ok := c.entries["foo"]
+	assert.False(t, ok)
+}
+
+func TestFileSpecCacheStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spec-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := FileSpecCacheStore{Path: filepath.Join(dir, "specs.json")}
+
+	entries, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, entries)
+
+	a := &digestAdapter{digests: map[string]string{"foo": "sha256:1"}, fetched: map[string]int{}}
+	NewSpecCache("test", 0, store).FetchSpecs(a, []string{"foo"})
+
+	c := NewSpecCache("test", 0, store)
+	specs, err := c.FetchSpecs(a, []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", specs[0].FQName)
+	assert.Equal(t, 1, a.fetched["foo"])
+}
+
+func TestConfigMapSpecCacheStore(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset()
+	store := ConfigMapSpecCacheStore{Name: "test-spec-cache", Namespace: "broker"}
+
+	entries, err := store.Load()
+	assert.NoError(t, err)
+	assert.Nil(t, entries)
+
+	saved := map[string]SpecCacheEntry{
+		"foo": {Digest: "sha256:1", Specs: []*bundle.Spec{{FQName: "foo"}}},
+	}
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, store.Save(saved))
+		entries, err = store.Load()
+		assert.NoError(t, err)
+		assert.Equal(t, "sha256:1", entries["foo"].Digest)
+		assert.Equal(t, "foo", entries["foo"].Specs[0].FQName)
+	}
+}
+
+func TestNewSpecCache(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cache       SpecCacheConfig
+		store       SpecCacheStore
+		ttl         time.Duration
+		disabled    bool
+		shouldError bool
+	}{
+		{
+			name:     "disabled",
+			cache:    SpecCacheConfig{TTL: "1h"},
+			disabled: true,
+		},
+		{
+			name:  "in memory",
+			cache: SpecCacheConfig{Enabled: true, TTL: "1h"},
+			ttl:   time.Hour,
+		},
+		{
+			name:  "config map",
+			cache: SpecCacheConfig{Enabled: true, Store: "configmap"},
+			store: ConfigMapSpecCacheStore{Name: "test-spec-cache", Namespace: "broker"},
+		},
+		{
+			name:  "file",
+			cache: SpecCacheConfig{Enabled: true, Store: "file", Location: "/tmp/specs.json"},
+			store: FileSpecCacheStore{Path: "/tmp/specs.json"},
+		},
+		{
+			name:        "file without location",
+			cache:       SpecCacheConfig{Enabled: true, Store: "file"},
+			shouldError: true,
+		},
+		{
+			name:        "invalid ttl",
+			cache:       SpecCacheConfig{Enabled: true, TTL: "soon"},
+			shouldError: true,
+		},
+		{
+			name:        "unknown store",
+			cache:       SpecCacheConfig{Enabled: true, Store: "etcd"},
+			shouldError: true,
+		},
+	}
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := newSpecCache(Config{Name: "test", Cache: tc.cache}, "broker")
+			if tc.shouldError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if tc.disabled {
+				assert.Nil(t, c)
+				return
+			}
+			assert.Equal(t, tc.ttl, c.ttl)
+			assert.Equal(t, tc.store, c.store)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/automationbroker/bundle-lib/registries/adapters"
+	log "github.com/sirupsen/logrus"
+)
+
+// versionSuffixRegex - the characters of a version that can not be part of
+// a service name.
+var versionSuffixRegex = regexp.MustCompile("[^a-zA-Z0-9.-]+")
+
+// publishVersions - Publishes the versions of a bundle next to each other.
+// Every spec with a bundle version gets a deterministic ID from the bundle
+// name and version, so its ID does not change when another version of the
+// bundle shows up. Every version is named after the bundle suffixed with
+// the version, so the names stay unique in the catalog and a spec keeps its
+// name when a newer version is released; credentials are labeled with it.
+// Specs without a bundle version are published as they are.
+func publishVersions(specs []*bundle.Spec) []*bundle.Spec {
+	names := []string{}
+	versioned := map[string][]*bundle.Spec{}
+	published := []*bundle.Spec{}
+	for _, spec := range specs {
+		if spec.BundleVersion == "" {
+			published = append(published, spec)
+			continue
+		}
+		name := spec.BundleName()
+		if _, ok := versioned[name]; !ok {
+			names = append(names, name)
+		}
+		versioned[name] = append(versioned[name], spec)
+	}
+
+	for _, name := range names {
+		published = append(published, versionSpecs(name, versioned[name])...)
+	}
+	return published
+}
+
+// versionSpecs - the specs of the versions of the bundle, highest first.
+// Specs are copied, the adapters and the spec cache keep theirs.
+func versionSpecs(name string, specs []*bundle.Spec) []*bundle.Spec {
+	versions := []string{}
+	byVersion := map[string]*bundle.Spec{}
+	for _, spec := range specs {
+		if _, ok := byVersion[spec.BundleVersion]; ok {
+			log.Warningf("Spec [ %s ] was loaded more than once at version [ %s ], the first one is published",
+				name, spec.BundleVersion)
+			continue
+		}
+		versions = append(versions, spec.BundleVersion)
+		byVersion[spec.BundleVersion] = spec
+	}
+	adapters.SortTags(versions)
+
+	published := []*bundle.Spec{}
+	for _, version := range versions {
+		spec := *byVersion[version]
+		metadata := map[string]interface{}{}
+		for key, value := range spec.Metadata {
+			metadata[key] = value
+		}
+		metadata[bundle.BundleNameMetadataKey] = name
+		metadata[bundle.BundleVersionsMetadataKey] = versions
+		spec.Metadata = metadata
+		spec.ID = bundle.SpecID(name, version)
+		spec.FQName = fmt.Sprintf("%s-%s", name, versionSuffixRegex.ReplaceAllString(version, "-"))
+		if displayName, ok := metadata["displayName"].(string); ok {
+			metadata["displayName"] = fmt.Sprintf("%s (%s)", displayName, version)
+		}
+		published = append(published, &spec)
+	}
+	return published
+}
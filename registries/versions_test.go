@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package registries
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/bundle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishVersions(t *testing.T) {
+	loaded := []*bundle.Spec{
+		&bundle.Spec{FQName: "mariadb", BundleVersion: "v1.2.0", Metadata: map[string]interface{}{"displayName": "MariaDB"}},
+		&bundle.Spec{FQName: "postgresql"},
+		&bundle.Spec{FQName: "mariadb", BundleVersion: "2.0.0", Metadata: map[string]interface{}{"displayName": "MariaDB"}},
+		&bundle.Spec{FQName: "mariadb", BundleVersion: "v1.2.0", Description: "again"},
+		&bundle.Spec{FQName: "mysql", BundleVersion: "5.7"},
+	}
+
+	specs := publishVersions(loaded)
+	names := []string{}
+	for _, spec := range specs {
+		names = append(names, spec.FQName)
+	}
+	assert.Equal(t, []string{"postgresql", "mariadb-2.0.0", "mariadb-v1.2.0", "mysql-5.7"}, names)
+
+	// specs without a version are published as they were loaded
+	assert.Equal(t, loaded[1], specs[0])
+	// a single version gets the deterministic ID as well
+	assert.Equal(t, bundle.SpecID("mysql", "5.7"), specs[3].ID)
+	assert.Equal(t, "mysql-5.7", specs[3].FQName)
+	assert.Equal(t, "", loaded[4].ID)
+
+	latest, older := specs[1], specs[2]
+	assert.Equal(t, bundle.SpecID("mariadb", "2.0.0"), latest.ID)
+	assert.Equal(t, bundle.SpecID("mariadb", "v1.2.0"), older.ID)
+	assert.Equal(t, "", older.Description)
+	assert.Equal(t, "MariaDB (2.0.0)", latest.Metadata["displayName"])
+	assert.Equal(t, "MariaDB (v1.2.0)", older.Metadata["displayName"])
+	assert.Equal(t, "mariadb", older.BundleName())
+	assert.Equal(t, []string{"2.0.0", "v1.2.0"}, older.Metadata[bundle.BundleVersionsMetadataKey])
+
+	// the loaded specs are not changed
+	assert.Equal(t, "mariadb", loaded[0].FQName)
+	assert.Equal(t, "MariaDB", loaded[0].Metadata["displayName"])
+	assert.Equal(t, "", loaded[0].ID)
+
+	// publishing again gives the same IDs
+	again := publishVersions(loaded)
+	assert.Equal(t, older.ID, again[2].ID)
+}
+
+func TestPublishVersionsSecondVersion(t *testing.T) {
+	first := publishVersions([]*bundle.Spec{
+		&bundle.Spec{FQName: "mariadb", BundleVersion: "1.0.0"},
+	})
+	if !assert.Equal(t, 1, len(first)) {
+		return
+	}
+
+	// instances provisioned from 1.0.0 keep finding their spec once 2.0.0
+	// is published
+	second := publishVersions([]*bundle.Spec{
+		&bundle.Spec{FQName: "mariadb", BundleVersion: "1.0.0"},
+		&bundle.Spec{FQName: "mariadb", BundleVersion: "2.0.0"},
+	})
+	if !assert.Equal(t, 2, len(second)) {
+		return
+	}
+	assert.Equal(t, first[0].ID, second[1].ID)
+	// the name of the spec does not move to the new version either
+	assert.Equal(t, first[0].FQName, second[1].FQName)
+	assert.Equal(t, "mariadb-1.0.0", second[1].FQName)
+	assert.Equal(t, "mariadb-2.0.0", second[0].FQName)
+	assert.Equal(t, bundle.SpecID("mariadb", "2.0.0"), second[0].ID)
+}
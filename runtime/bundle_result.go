@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BundleResultKey - key of the secret named after the bundle pod that
+	// holds the result of a runtime 3 bundle.
+	BundleResultKey = "result"
+	// ResultRuntimeVersion - the first runtime version writing a result.
+	ResultRuntimeVersion = 3
+)
+
+// BundleResult - The result a runtime 3 bundle writes once its action is
+// done, as json in the result key of a secret named after the pod.
+type BundleResult struct {
+	// Credentials - the extracted credentials.
+	Credentials map[string]interface{} `json:"credentials,omitempty"`
+	// BindingCredentials - the credentials of the bindings keyed by the id
+	// of the binding, returned by the rotate action.
+	BindingCredentials map[string]map[string]interface{} `json:"binding_credentials,omitempty"`
+	// DashboardURL - the dashboard of the service instance.
+	DashboardURL string `json:"dashboard_url,omitempty"`
+	// Outputs - values the bundle returns that are not credentials.
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// Warnings - problems that did not fail the action.
+	Warnings []string `json:"warnings,omitempty"`
+	// Error - why the action failed, an action with an error fails even
+	// when the bundle exits successfully.
+	Error *BundleResultError `json:"error,omitempty"`
+}
+
+// BundleResultError - The details of a failed action.
+type BundleResultError struct {
+	Message string                 `json:"message"`
+	Reason  string                 `json:"reason,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *BundleResultError) Error() string {
+	if e.Reason == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+// GetBundleResult - Returns the result written by the bundle pod, nil when
+// the bundle did not write a result.
+func (p provider) GetBundleResult(podname string, ns string) (*BundleResult, error) {
+	return readBundleResult(podname, ns)
+}
+
+func readBundleResult(podname string, namespace string) (*BundleResult, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrive kubernetes client - %v", err)
+	}
+	secret, err := k8s.Client.CoreV1().Secrets(namespace).Get(podname, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Unable to retrieve secret [ %v ] - %v", podname, err)
+	}
+	data, ok := secret.Data[BundleResultKey]
+	if !ok {
+		return nil, nil
+	}
+	result := &BundleResult{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("invalid result in secret [ %v ] - %v", podname, err)
+	}
+	return result, nil
+}
+
+// extractCredentialsFromResult - Extract credentials from the result of a
+// runtime 3 bundle.
+func extractCredentialsFromResult(podname string, namespace string) ([]byte, error) {
+	result, err := readBundleResult(podname, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("bundle [ %v ] did not write a result", podname)
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.Credentials == nil {
+		result.Credentials = map[string]interface{}{}
+	}
+	return json.Marshal(result.Credentials)
+}
+
+// applyBundleResult - reports the result of a bundle to the watcher. Returns
+// the error of a failed action.
+func applyBundleResult(podName string, result *BundleResult, updateFunc UpdateDescriptionFn) error {
+	for _, w := range result.Warnings {
+		log.Warningf("Pod [ %s ] warning: %s", podName, w)
+	}
+	if result.Error != nil {
+		return ErrorCustomMsg{msg: result.Error.Error()}
+	}
+	if result.DashboardURL != "" {
+		updateFunc("", result.DashboardURL)
+	}
+	return nil
+}
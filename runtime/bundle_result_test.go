@@ -0,0 +1,114 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetBundleResult(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	secret := func(data map[string][]byte) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"},
+			Data:       data,
+		}
+	}
+	testCases := []struct {
+		name        string
+		client      *fake.Clientset
+		expected    *BundleResult
+		shouldError bool
+	}{
+		{
+			name:   "no secret",
+			client: fake.NewSimpleClientset(),
+		},
+		{
+			name:   "runtime 2 secret",
+			client: fake.NewSimpleClientset(secret(map[string][]byte{"fields": []byte(`{}`)})),
+		},
+		{
+			name: "result",
+			client: fake.NewSimpleClientset(secret(map[string][]byte{BundleResultKey: []byte(`{
+				"credentials": {"user": "admin"},
+				"dashboard_url": "http://dashboard",
+				"outputs": {"replicas": 3},
+				"warnings": ["slow"],
+				"error": {"message": "failed", "reason": "Quota", "details": {"limit": 2}}
+			}`)})),
+			expected: &BundleResult{
+				Credentials:  map[string]interface{}{"user": "admin"},
+				DashboardURL: "http://dashboard",
+				Outputs:      map[string]interface{}{"replicas": float64(3)},
+				Warnings:     []string{"slow"},
+				Error: &BundleResultError{
+					Message: "failed",
+					Reason:  "Quota",
+					Details: map[string]interface{}{"limit": float64(2)},
+				},
+			},
+		},
+		{
+			name:        "invalid result",
+			client:      fake.NewSimpleClientset(secret(map[string][]byte{BundleResultKey: []byte(`{`)})),
+			shouldError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k.Client = tc.client
+			result, err := provider{}.GetBundleResult("pod", "ns")
+			if tc.shouldError {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("expected %#v got %#v", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestApplyBundleResult(t *testing.T) {
+	var dashboard string
+	update := func(_, url string) { dashboard = url }
+
+	err := applyBundleResult("pod", &BundleResult{DashboardURL: "http://dashboard", Warnings: []string{"slow"}}, update)
+	if err != nil || dashboard != "http://dashboard" {
+		t.Fatalf("expected the dashboard to be reported, got %q - %v", dashboard, err)
+	}
+
+	err = applyBundleResult("pod", &BundleResult{Error: &BundleResultError{Message: "quota exceeded", Reason: "Quota"}}, update)
+	if !IsErrorCustomMsg(err) || err.Error() != "Quota: quota exceeded" {
+		t.Fatalf("expected a custom message error, got %v", err)
+	}
+}
@@ -49,13 +49,21 @@ const (
 type extractCredentialsFunc func(string, string) ([]byte, error)
 
 // ExtractCredentials - Extract credentials from pod in a certain namespace.
-// needs the podname, namespace and the runtime version.
+// needs the podname, namespace and the runtime version. A runtime 1 bundle
+// is not watched, its run is over once its credentials are extracted and
+// the post run bundle hooks are run here.
 func (p provider) ExtractCredentials(podname string, ns string, runtime int) ([]byte, error) {
 	extractCredsFunc, err := getExtractCreds(runtime)
 	if err != nil {
 		return nil, err
 	}
-	return extractCredsFunc(podname, ns)
+	creds, err := extractCredsFunc(podname, ns)
+	if runtime == 1 {
+		if err := p.bundleFinished(podname, ns, err); err != nil {
+			return nil, err
+		}
+	}
+	return creds, err
 }
 
 // ExtractCredentialsAsFile - Extract credentials from running APB using exec
@@ -161,14 +169,16 @@ func getExtractCreds(runtimeVersion int) (extractCredentialsFunc, error) {
 	if runtimeVersion == 1 {
 		log.Infof("Runtime version 1 is being deprecated.\nYou should move the Bundle to use the latest bundle base")
 		return extractCredentialsAsFile, nil
-	} else if runtimeVersion >= 2 {
+	} else if runtimeVersion == 2 {
 		return extractCredentialsAsSecret, nil
+	} else if runtimeVersion == ResultRuntimeVersion {
+		return extractCredentialsFromResult, nil
 	} else {
 		return nil, fmt.Errorf(
 			"Unexpected runtime version [%v], support %v <= runtimeVersion <= %v",
 			runtimeVersion,
 			1,
-			2,
+			ResultRuntimeVersion,
 		)
 	}
 }
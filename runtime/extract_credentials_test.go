@@ -123,6 +123,67 @@ func TestExtractCredentials(t *testing.T) {
 			podname:   "foo",
 			namespace: "bar",
 		},
+		{
+			name:     "runtime 3 result",
+			expected: []byte(`{"db":"name"}`),
+			runtime:  3,
+			client: fake.NewSimpleClientset(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Data: map[string][]byte{"result": []byte(`{"credentials": {"db": "name"}, "outputs": {"replicas": 3}}`)},
+			}),
+			podname:   "foo",
+			namespace: "bar",
+		},
+		{
+			name:     "runtime 3 result without credentials",
+			expected: []byte(`{}`),
+			runtime:  3,
+			client: fake.NewSimpleClientset(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Data: map[string][]byte{"result": []byte(`{"dashboard_url": "http://dashboard"}`)},
+			}),
+			podname:   "foo",
+			namespace: "bar",
+		},
+		{
+			name:      "runtime 3 result with error",
+			shouldErr: true,
+			runtime:   3,
+			client: fake.NewSimpleClientset(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Data: map[string][]byte{"result": []byte(`{"error": {"message": "quota exceeded"}}`)},
+			}),
+			podname:   "foo",
+			namespace: "bar",
+		},
+		{
+			name:      "runtime 3 without result",
+			shouldErr: true,
+			runtime:   3,
+			client: fake.NewSimpleClientset(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+				Data: map[string][]byte{"fields": []byte(`{"db": "name"}`)},
+			}),
+			podname:   "foo",
+			namespace: "bar",
+		},
+		{
+			name:      "unsupported runtime",
+			shouldErr: true,
+			runtime:   4,
+		},
 		{
 			name:      "invalid runtime",
 			expected:  []byte{},
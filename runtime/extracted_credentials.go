@@ -18,6 +18,10 @@ package runtime
 
 import (
 	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/automationbroker/bundle-lib/clients"
 	log "github.com/sirupsen/logrus"
@@ -39,6 +43,153 @@ type ExtractedCredential interface {
 	GetExtractedCredential(string, string) (map[string]interface{}, error)
 	// DeleteExtractedCredentials - takes id, namespace and deletes the credentials.
 	DeleteExtractedCredential(string, string) error
+	// ListExtractedCredentials - takes namespace and returns the labels of
+	// every credential by id.
+	ListExtractedCredentials(string) (map[string]map[string]string, error)
+	// QueryExtractedCredentials - takes namespace and labels, returns the
+	// labels by id of the credentials having all of the labels.
+	QueryExtractedCredentials(string, map[string]string) (map[string]map[string]string, error)
+}
+
+// CredentialCreatedLabel - label added to the labels returned when listing
+// extracted credentials, holds the unix time the credentials were created.
+// It is not stored with the credentials.
+const CredentialCreatedLabel = "bundleCreated"
+
+// matchesLabels - true when labels contain every label of the selector.
+func matchesLabels(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// credentialListEntry - the labels returned for credentials created at the
+// given time.
+func credentialListEntry(labels map[string]string, created time.Time) map[string]string {
+	entry := map[string]string{}
+	for k, v := range labels {
+		entry[k] = v
+	}
+	if !created.IsZero() {
+		entry[CredentialCreatedLabel] = strconv.FormatInt(created.Unix(), 10)
+	}
+	return entry
+}
+
+const (
+	// CredentialBackendSecret - keeps the extracted credentials in secrets.
+	CredentialBackendSecret = "secret"
+	// CredentialBackendEtcd - keeps the extracted credentials in etcd.
+	CredentialBackendEtcd = "etcd"
+	// CredentialBackendCRD - keeps the extracted credentials as custom
+	// resources.
+	CredentialBackendCRD = "crd"
+)
+
+// NewExtractedCredential - Returns the built-in backend with the name, an
+// empty name returns the secret backend.
+func NewExtractedCredential(backend string) (ExtractedCredential, error) {
+	switch backend {
+	case "", CredentialBackendSecret:
+		return NewSecretExtractedCredential(), nil
+	case CredentialBackendEtcd:
+		return NewEtcdExtractedCredential(nil, ""), nil
+	case CredentialBackendCRD:
+		return NewCRDExtractedCredential(), nil
+	}
+	return nil, fmt.Errorf("unknown extracted credential backend %v", backend)
+}
+
+// NewSecretExtractedCredential - Keeps the extracted credentials in secrets,
+// this is the default backend.
+func NewSecretExtractedCredential() ExtractedCredential {
+	return defaultExtractedCredential{}
+}
+
+// MigrateExtractedCredentials - Moves the credentials with the ids from one
+// backend to another. Credentials that are missing in the source are
+// skipped, a credential is only deleted from the source once it is written
+// to the destination. The labels of the credentials are kept, the given
+// labels are added to them. Encrypting or decrypting the credentials of a
+// backend in place rewrites them without deleting them.
+func MigrateExtractedCredentials(from, to ExtractedCredential, ns string, ids []string, labels map[string]string) error {
+	inPlace := sameCredentialBackend(from, to)
+	sourceLabels, err := from.QueryExtractedCredentials(ns, nil)
+	if err != nil {
+		return fmt.Errorf("unable to list extracted credentials - %v", err)
+	}
+	for _, id := range ids {
+		creds, err := from.GetExtractedCredential(id, ns)
+		if err == ErrCredentialsNotFound {
+			log.Debugf("no extracted credentials to migrate for %v", id)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read extracted credentials %v - %v", id, err)
+		}
+		migrated := map[string]string{}
+		for k, v := range sourceLabels[id] {
+			if k != CredentialCreatedLabel {
+				migrated[k] = v
+			}
+		}
+		for k, v := range labels {
+			migrated[k] = v
+		}
+		_, err = to.GetExtractedCredential(id, ns)
+		if _, ok := err.(notEncryptedError); ok {
+			// The plain credentials are there, they are encrypted by the update.
+			err = nil
+		}
+		switch {
+		case err == nil:
+			err = to.UpdateExtractedCredential(id, ns, creds, migrated)
+		case err == ErrCredentialsNotFound:
+			err = to.CreateExtractedCredential(id, ns, creds, migrated)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write extracted credentials %v - %v", id, err)
+		}
+		if inPlace {
+			log.Infof("migrated extracted credentials %v in place", id)
+			continue
+		}
+		if err := from.DeleteExtractedCredential(id, ns); err != nil {
+			return fmt.Errorf("unable to delete migrated extracted credentials %v - %v", id, err)
+		}
+		log.Infof("migrated extracted credentials %v", id)
+	}
+	return nil
+}
+
+// credentialBackend - the backend that keeps the credentials, encryption
+// only wraps a backend.
+func credentialBackend(ec ExtractedCredential) ExtractedCredential {
+	if e, ok := ec.(encryptedExtractedCredential); ok {
+		return credentialBackend(e.backend)
+	}
+	return ec
+}
+
+// sameCredentialBackend - true if both keep their credentials in the same
+// backend, the credentials of one are the credentials of the other.
+func sameCredentialBackend(a, b ExtractedCredential) bool {
+	a, b = credentialBackend(a), credentialBackend(b)
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) {
+		return false
+	}
+	if ta.Comparable() {
+		return a == b
+	}
+	switch ta.Kind() {
+	case reflect.Map, reflect.Ptr, reflect.Slice:
+		return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+	}
+	return false
 }
 
 type defaultExtractedCredential struct{}
@@ -108,3 +259,25 @@ func (d defaultExtractedCredential) DeleteExtractedCredential(ID, ns string) err
 	}
 	return nil
 }
+
+func (d defaultExtractedCredential) ListExtractedCredentials(ns string) (map[string]map[string]string, error) {
+	return d.QueryExtractedCredentials(ns, nil)
+}
+
+func (d defaultExtractedCredential) QueryExtractedCredentials(ns string, selector map[string]string) (map[string]map[string]string, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		log.Errorf("Unable to get kubernetes client - %v", err)
+		return nil, err
+	}
+	secrets, err := k8scli.ListExtractedCredentialSecrets(ns, selector)
+	if err != nil {
+		log.Errorf("unable to list extracted credentials - %v", err)
+		return nil, err
+	}
+	creds := map[string]map[string]string{}
+	for _, s := range secrets {
+		creds[s.Name] = credentialListEntry(s.Labels, s.CreationTimestamp.Time)
+	}
+	return creds, nil
+}
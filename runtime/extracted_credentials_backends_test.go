@@ -0,0 +1,394 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	etcd "github.com/coreos/etcd/client"
+)
+
+// fakeKeysAPI - an in memory etcd KeysAPI supporting Set, Get and Delete.
+type fakeKeysAPI struct {
+	etcd.KeysAPI
+	values map[string]string
+}
+
+func (f *fakeKeysAPI) Set(ctx context.Context, key, value string, opts *etcd.SetOptions) (*etcd.Response, error) {
+	_, ok := f.values[key]
+	if opts != nil && opts.PrevExist == etcd.PrevExist && !ok {
+		return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound}
+	}
+	if opts != nil && opts.PrevExist == etcd.PrevNoExist && ok {
+		return nil, etcd.Error{Code: etcd.ErrorCodeNodeExist}
+	}
+	f.values[key] = value
+	return &etcd.Response{Node: &etcd.Node{Key: key, Value: value}}, nil
+}
+
+func (f *fakeKeysAPI) Get(ctx context.Context, key string, opts *etcd.GetOptions) (*etcd.Response, error) {
+	if opts != nil && opts.Recursive {
+		dir := &etcd.Node{Key: key, Dir: true}
+		for k, v := range f.values {
+			if strings.HasPrefix(k, key+"/") {
+				dir.Nodes = append(dir.Nodes, &etcd.Node{Key: k, Value: v})
+			}
+		}
+		if len(dir.Nodes) == 0 {
+			return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound}
+		}
+		return &etcd.Response{Node: dir}, nil
+	}
+	value, ok := f.values[key]
+	if !ok {
+		return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound}
+	}
+	return &etcd.Response{Node: &etcd.Node{Key: key, Value: value}}, nil
+}
+
+func (f *fakeKeysAPI) Delete(ctx context.Context, key string, opts *etcd.DeleteOptions) (*etcd.Response, error) {
+	if _, ok := f.values[key]; !ok {
+		return nil, etcd.Error{Code: etcd.ErrorCodeKeyNotFound}
+	}
+	delete(f.values, key)
+	return &etcd.Response{}, nil
+}
+
+// memoryExtractedCredential - an in memory ExtractedCredential, labels are
+// not kept.
+type memoryExtractedCredential map[string]map[string]interface{}
+
+func (m memoryExtractedCredential) CreateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+	m[ns+"/"+ID] = extCreds
+	return nil
+}
+
+func (m memoryExtractedCredential) UpdateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+	if _, ok := m[ns+"/"+ID]; !ok {
+		return ErrCredentialsNotFound
+	}
+	m[ns+"/"+ID] = extCreds
+	return nil
+}
+
+func (m memoryExtractedCredential) GetExtractedCredential(ID, ns string) (map[string]interface{}, error) {
+	creds, ok := m[ns+"/"+ID]
+	if !ok {
+		return nil, ErrCredentialsNotFound
+	}
+	return creds, nil
+}
+
+func (m memoryExtractedCredential) DeleteExtractedCredential(ID, ns string) error {
+	delete(m, ns+"/"+ID)
+	return nil
+}
+
+func (m memoryExtractedCredential) ListExtractedCredentials(ns string) (map[string]map[string]string, error) {
+	return m.QueryExtractedCredentials(ns, nil)
+}
+
+func (m memoryExtractedCredential) QueryExtractedCredentials(ns string, selector map[string]string) (map[string]map[string]string, error) {
+	creds := map[string]map[string]string{}
+	for key := range m {
+		if strings.HasPrefix(key, ns+"/") {
+			creds[strings.TrimPrefix(key, ns+"/")] = map[string]string{}
+		}
+	}
+	return creds, nil
+}
+
+func TestEtcdExtractedCredential(t *testing.T) {
+	kapi := &fakeKeysAPI{values: map[string]string{}}
+	ec := NewEtcdExtractedCredential(kapi, "/creds")
+	creds := map[string]interface{}{"user": "admin"}
+
+	if err := ec.UpdateExtractedCredential("id-1", "ns", creds, nil); err != ErrCredentialsNotFound {
+		t.Fatalf("expected not found on update of missing credentials, got %v", err)
+	}
+	if err := ec.CreateExtractedCredential("id-1", "ns", creds, map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if _, ok := kapi.values["/creds/ns/id-1"]; !ok {
+		t.Fatalf("credentials not stored under the prefix - %v", kapi.values)
+	}
+	if err := ec.CreateExtractedCredential("id-1", "ns", creds, nil); err == nil {
+		t.Fatalf("expected create of existing credentials to fail")
+	}
+	updated := map[string]interface{}{"user": "root"}
+	if err := ec.UpdateExtractedCredential("id-1", "ns", updated, nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	got, err := ec.GetExtractedCredential("id-1", "ns")
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if !reflect.DeepEqual(got, updated) {
+		t.Fatalf("expected %v got %v", updated, got)
+	}
+	if err := ec.DeleteExtractedCredential("id-1", "ns"); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if err := ec.DeleteExtractedCredential("id-1", "ns"); err != nil {
+		t.Fatalf("delete of missing credentials should not fail - %v", err)
+	}
+	if _, err := ec.GetExtractedCredential("id-1", "ns"); err != ErrCredentialsNotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestEtcdQueryExtractedCredentials(t *testing.T) {
+	kapi := &fakeKeysAPI{values: map[string]string{}}
+	ec := NewEtcdExtractedCredential(kapi, "")
+
+	creds, err := ec.ListExtractedCredentials("ns")
+	if err != nil || len(creds) != 0 {
+		t.Fatalf("expected no credentials, got %v - %v", creds, err)
+	}
+	ec.CreateExtractedCredential("id-1", "ns", nil, map[string]string{"bundleAction": "provision", "bundleName": "mysql"})
+	ec.CreateExtractedCredential("id-2", "ns", nil, map[string]string{"bundleAction": "bind", "bundleName": "mysql"})
+	ec.CreateExtractedCredential("id-3", "ns", nil, map[string]string{"bundleAction": "bind", "bundleName": "redis"})
+	ec.CreateExtractedCredential("id-4", "other", nil, map[string]string{"bundleAction": "bind", "bundleName": "mysql"})
+
+	testCases := []struct {
+		name     string
+		selector map[string]string
+		expected []string
+	}{
+		{name: "all", expected: []string{"id-1", "id-2", "id-3"}},
+		{name: "bundle", selector: map[string]string{"bundleName": "mysql"}, expected: []string{"id-1", "id-2"}},
+		{name: "bundle and action", selector: map[string]string{"bundleName": "mysql", "bundleAction": "bind"}, expected: []string{"id-2"}},
+		{name: "no match", selector: map[string]string{"bundleName": "postgres"}, expected: []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			creds, err := ec.QueryExtractedCredentials("ns", tc.selector)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+			ids := []string{}
+			for id, labels := range creds {
+				if _, ok := labels[CredentialCreatedLabel]; !ok {
+					t.Fatalf("missing creation time for %v - %v", id, labels)
+				}
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			if !reflect.DeepEqual(ids, tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, ids)
+			}
+		})
+	}
+}
+
+func TestEncryptedExtractedCredential(t *testing.T) {
+	keys := StaticStateKeyProvider{Current: "k1", Keys: map[string][]byte{
+		"k1": []byte("0123456789abcdef"),
+		"k2": []byte("fedcba9876543210"),
+	}}
+	backend := memoryExtractedCredential{}
+	ec := NewEncryptedExtractedCredential(backend, keys)
+	creds := map[string]interface{}{"password": "changeme"}
+
+	if err := ec.CreateExtractedCredential("id-1", "ns", creds, nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	raw := backend["ns/id-1"]
+	if raw[envelopeKeyIDKey] != "k1" || strings.Contains(raw[envelopeDataKey].(string), "changeme") {
+		t.Fatalf("credentials are not encrypted - %v", raw)
+	}
+
+	// Credentials written with an older key can still be read.
+	rotated := NewEncryptedExtractedCredential(backend, StaticStateKeyProvider{Current: "k2", Keys: keys.Keys})
+	got, err := rotated.GetExtractedCredential("id-1", "ns")
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if !reflect.DeepEqual(got, creds) {
+		t.Fatalf("expected %v got %v", creds, got)
+	}
+
+	wrong := NewEncryptedExtractedCredential(backend, StaticStateKeyProvider{Current: "k1", Keys: map[string][]byte{
+		"k1": []byte("aaaaaaaaaaaaaaaa"),
+	}})
+	if _, err := wrong.GetExtractedCredential("id-1", "ns"); err == nil {
+		t.Fatalf("expected decryption with the wrong key to fail")
+	}
+
+	// Credentials moved to another id must not decrypt.
+	backend["ns/id-2"] = raw
+	if _, err := ec.GetExtractedCredential("id-2", "ns"); err == nil {
+		t.Fatalf("expected swapped credentials to fail")
+	}
+
+	backend["ns/id-3"] = creds
+	if _, err := ec.GetExtractedCredential("id-3", "ns"); err == nil {
+		t.Fatalf("expected plain credentials to fail")
+	}
+}
+
+func TestMigrateExtractedCredentials(t *testing.T) {
+	from := memoryExtractedCredential{
+		"ns/id-1": {"user": "one"},
+		"ns/id-2": {"user": "two"},
+	}
+	to := memoryExtractedCredential{
+		"ns/id-2": {"user": "stale"},
+	}
+	err := MigrateExtractedCredentials(from, to, "ns", []string{"id-1", "id-2", "id-3"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if len(from) != 0 {
+		t.Fatalf("expected the source to be empty - %v", from)
+	}
+	expected := memoryExtractedCredential{
+		"ns/id-1": {"user": "one"},
+		"ns/id-2": {"user": "two"},
+	}
+	if !reflect.DeepEqual(to, expected) {
+		t.Fatalf("expected %v got %v", expected, to)
+	}
+}
+
+// labelledExtractedCredential - an in memory ExtractedCredential that keeps
+// the labels.
+type labelledExtractedCredential struct {
+	memoryExtractedCredential
+	labels map[string]map[string]string
+}
+
+func (l labelledExtractedCredential) CreateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+	l.labels[ns+"/"+ID] = labels
+	return l.memoryExtractedCredential.CreateExtractedCredential(ID, ns, extCreds, labels)
+}
+
+func (l labelledExtractedCredential) QueryExtractedCredentials(ns string, selector map[string]string) (map[string]map[string]string, error) {
+	creds := map[string]map[string]string{}
+	for key, labels := range l.labels {
+		if strings.HasPrefix(key, ns+"/") {
+			creds[strings.TrimPrefix(key, ns+"/")] = labels
+		}
+	}
+	return creds, nil
+}
+
+func TestMigrateExtractedCredentialsKeepsLabels(t *testing.T) {
+	from := labelledExtractedCredential{
+		memoryExtractedCredential: memoryExtractedCredential{"ns/id-1": {"user": "one"}},
+		labels: map[string]map[string]string{
+			"ns/id-1": {"bundleAction": "bind", "bundleInstance": "instance-1", CredentialCreatedLabel: "1"},
+		},
+	}
+	to := labelledExtractedCredential{
+		memoryExtractedCredential: memoryExtractedCredential{},
+		labels:                    map[string]map[string]string{},
+	}
+	err := MigrateExtractedCredentials(from, to, "ns", []string{"id-1"}, map[string]string{"migrated": "true"})
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	expected := map[string]string{"bundleAction": "bind", "bundleInstance": "instance-1", "migrated": "true"}
+	if !reflect.DeepEqual(to.labels["ns/id-1"], expected) {
+		t.Fatalf("expected %v got %v", expected, to.labels["ns/id-1"])
+	}
+}
+
+func TestMigrateExtractedCredentialsInPlace(t *testing.T) {
+	keys := StaticStateKeyProvider{Current: "k1", Keys: map[string][]byte{"k1": []byte("0123456789abcdef")}}
+	backend := memoryExtractedCredential{"ns/id-1": {"user": "one"}}
+	encrypted := NewEncryptedExtractedCredential(backend, keys)
+
+	// Encrypting the credentials of a backend rewrites them in place.
+	if err := MigrateExtractedCredentials(backend, encrypted, "ns", []string{"id-1"}, nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if _, ok := backend["ns/id-1"][envelopeDataKey]; !ok {
+		t.Fatalf("expected the credentials to be encrypted - %v", backend["ns/id-1"])
+	}
+	got, err := encrypted.GetExtractedCredential("id-1", "ns")
+	if err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]interface{}{"user": "one"}) {
+		t.Fatalf("expected the migrated credentials got %v", got)
+	}
+
+	// And decrypting them as well.
+	if err := MigrateExtractedCredentials(encrypted, backend, "ns", []string{"id-1"}, nil); err != nil {
+		t.Fatalf("unexpected error - %v", err)
+	}
+	if !reflect.DeepEqual(backend["ns/id-1"], map[string]interface{}{"user": "one"}) {
+		t.Fatalf("expected the plain credentials got %v", backend["ns/id-1"])
+	}
+}
+
+func TestSameCredentialBackend(t *testing.T) {
+	keys := StaticStateKeyProvider{Current: "k1", Keys: map[string][]byte{"k1": []byte("0123456789abcdef")}}
+	memory := memoryExtractedCredential{}
+	secret := NewSecretExtractedCredential()
+	if !sameCredentialBackend(secret, NewEncryptedExtractedCredential(secret, keys)) {
+		t.Fatal("expected the encrypted secrets to be kept in secrets")
+	}
+	if !sameCredentialBackend(memory, NewEncryptedExtractedCredential(memory, keys)) {
+		t.Fatal("expected the encrypted backend to be the same")
+	}
+	if sameCredentialBackend(memory, memoryExtractedCredential{}) {
+		t.Fatal("expected different backends")
+	}
+	if sameCredentialBackend(secret, NewCRDExtractedCredential()) {
+		t.Fatal("expected different backends")
+	}
+}
+
+func TestNewExtractedCredential(t *testing.T) {
+	testCases := []struct {
+		backend     string
+		expected    ExtractedCredential
+		shouldError bool
+	}{
+		{backend: "", expected: defaultExtractedCredential{}},
+		{backend: CredentialBackendSecret, expected: defaultExtractedCredential{}},
+		{backend: CredentialBackendEtcd, expected: etcdExtractedCredential{prefix: defaultEtcdCredentialPrefix}},
+		{backend: CredentialBackendCRD, expected: crdExtractedCredential{}},
+		{backend: "vault", shouldError: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.backend, func(t *testing.T) {
+			ec, err := NewExtractedCredential(tc.backend)
+			if tc.shouldError {
+				if err == nil {
+					t.Fatalf("expected an error for backend %v", tc.backend)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+			if !reflect.DeepEqual(ec, tc.expected) {
+				t.Fatalf("expected %#v got %#v", tc.expected, ec)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"encoding/json"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// CredentialCRDGroup - API group of the extracted credential CRD.
+	CredentialCRDGroup = "automationbroker.io"
+	// CredentialCRDVersion - API version of the extracted credential CRD.
+	CredentialCRDVersion = "v1alpha1"
+	// CredentialCRDResource - plural resource name of the extracted
+	// credential CRD.
+	CredentialCRDResource = "extractedcredentials"
+	// CredentialCRDKind - kind of the extracted credential CRD.
+	CredentialCRDKind = "ExtractedCredential"
+)
+
+// crdCredential - an extracted credential custom resource.
+type crdCredential struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              crdCredentialSpec `json:"spec"`
+}
+
+type crdCredentialSpec struct {
+	Credentials map[string]interface{} `json:"credentials"`
+}
+
+// crdCredentialList - a list of extracted credential custom resources.
+type crdCredentialList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []crdCredential `json:"items"`
+}
+
+// crdRESTClient - returns the rest client for the credential CRD, replaced
+// in tests.
+var crdRESTClient = func() (rest.Interface, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	config := *k8scli.ClientConfig
+	config.GroupVersion = &schema.GroupVersion{Group: CredentialCRDGroup, Version: CredentialCRDVersion}
+	config.APIPath = "/apis"
+	config.ContentType = "application/json"
+	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	return rest.RESTClientFor(&config)
+}
+
+type crdExtractedCredential struct{}
+
+// NewCRDExtractedCredential - Keeps the extracted credentials as
+// ExtractedCredential custom resources. The CRD must be installed in the
+// cluster. Custom resources are not protected like secrets, wrap the backend
+// with NewEncryptedExtractedCredential to keep the credentials confidential.
+func NewCRDExtractedCredential() ExtractedCredential {
+	return crdExtractedCredential{}
+}
+
+func (c crdExtractedCredential) get(client rest.Interface, ID, ns string) (*crdCredential, error) {
+	body, err := client.Get().Namespace(ns).Resource(CredentialCRDResource).Name(ID).Do().Raw()
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, err
+	}
+	cred := &crdCredential{}
+	if err := json.Unmarshal(body, cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+func (c crdExtractedCredential) CreateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+
+	client, err := crdRESTClient()
+	if err != nil {
+		log.Errorf("Unable to get crd client - %v", err)
+		return err
+	}
+	body, err := json.Marshal(crdCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: CredentialCRDGroup + "/" + CredentialCRDVersion,
+			Kind:       CredentialCRDKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: ID, Namespace: ns, Labels: labels},
+		Spec:       crdCredentialSpec{Credentials: extCreds},
+	})
+	if err != nil {
+		return err
+	}
+	err = client.Post().Namespace(ns).Resource(CredentialCRDResource).Body(body).Do().Error()
+	if err != nil {
+		log.Errorf("unable to save extracted credentials - %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c crdExtractedCredential) UpdateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+
+	client, err := crdRESTClient()
+	if err != nil {
+		log.Errorf("Unable to get crd client - %v", err)
+		return err
+	}
+	cred, err := c.get(client, ID, ns)
+	if err != nil {
+		return err
+	}
+	cred.ObjectMeta.Labels = labels
+	cred.Spec.Credentials = extCreds
+	body, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	err = client.Put().Namespace(ns).Resource(CredentialCRDResource).Name(ID).Body(body).Do().Error()
+	if err != nil {
+		log.Errorf("unable to update extracted credentials - %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c crdExtractedCredential) GetExtractedCredential(ID, ns string) (map[string]interface{}, error) {
+	client, err := crdRESTClient()
+	if err != nil {
+		log.Errorf("Unable to get crd client - %v", err)
+		return nil, err
+	}
+	cred, err := c.get(client, ID, ns)
+	if err != nil {
+		if err == ErrCredentialsNotFound {
+			log.Debugf("credentials not found id: %v, namespace: %v", ID, ns)
+		} else {
+			log.Errorf("unable to get extracted credentials - %v", err)
+		}
+		return nil, err
+	}
+	return cred.Spec.Credentials, nil
+}
+
+func (c crdExtractedCredential) DeleteExtractedCredential(ID, ns string) error {
+	client, err := crdRESTClient()
+	if err != nil {
+		log.Errorf("Unable to get crd client - %v", err)
+		return err
+	}
+	err = client.Delete().Namespace(ns).Resource(CredentialCRDResource).Name(ID).Do().Error()
+	if err != nil && !kerror.IsNotFound(err) {
+		log.Errorf("unable to delete extracted credentials - %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c crdExtractedCredential) ListExtractedCredentials(ns string) (map[string]map[string]string, error) {
+	return c.QueryExtractedCredentials(ns, nil)
+}
+
+func (c crdExtractedCredential) QueryExtractedCredentials(ns string, selector map[string]string) (map[string]map[string]string, error) {
+	client, err := crdRESTClient()
+	if err != nil {
+		log.Errorf("Unable to get crd client - %v", err)
+		return nil, err
+	}
+	body, err := client.Get().Namespace(ns).Resource(CredentialCRDResource).
+		Param("labelSelector", labels.SelectorFromSet(labels.Set(selector)).String()).
+		Do().Raw()
+	if err != nil {
+		log.Errorf("unable to list extracted credentials - %v", err)
+		return nil, err
+	}
+	list := crdCredentialList{}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+	creds := map[string]map[string]string{}
+	for _, item := range list.Items {
+		creds[item.ObjectMeta.Name] = credentialListEntry(item.ObjectMeta.Labels, item.ObjectMeta.CreationTimestamp.Time)
+	}
+	return creds, nil
+}
@@ -0,0 +1,174 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	envelopeDataKey   = "encrypted"
+	envelopeKeyKey    = "key"
+	envelopeKeyIDKey  = "key_id"
+	envelopeDataBytes = 32
+)
+
+// notEncryptedError - the backend holds credentials that were not written
+// encrypted, e.g. before the encryption was turned on.
+type notEncryptedError struct {
+	id string
+}
+
+func (e notEncryptedError) Error() string {
+	return fmt.Sprintf("credentials %v are not encrypted", e.id)
+}
+
+type encryptedExtractedCredential struct {
+	backend ExtractedCredential
+	keys    StateKeyProvider
+}
+
+// NewEncryptedExtractedCredential - Encrypts the credentials before they are
+// handed to the backend. Every credential is encrypted with its own data
+// key, the data key is encrypted with the current key of the key provider.
+// Rotating the key provider only requires the old keys to stay available
+// until the credentials are written again.
+func NewEncryptedExtractedCredential(backend ExtractedCredential, keys StateKeyProvider) ExtractedCredential {
+	return encryptedExtractedCredential{backend: backend, keys: keys}
+}
+
+func credentialAdditionalData(ID, ns string) []byte {
+	return []byte(ns + "/" + ID)
+}
+
+func (e encryptedExtractedCredential) encrypt(ID, ns string, extCreds map[string]interface{}) (map[string]interface{}, error) {
+	keyID, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	dataKey := make([]byte, envelopeDataBytes)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(extCreds)
+	if err != nil {
+		return nil, err
+	}
+	// The credentials are bound to their id so they can not be swapped.
+	data, err := sealData(dataKey, plain, credentialAdditionalData(ID, ns))
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := sealData(key, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		envelopeDataKey:  base64.StdEncoding.EncodeToString(data),
+		envelopeKeyKey:   base64.StdEncoding.EncodeToString(wrapped),
+		envelopeKeyIDKey: keyID,
+	}, nil
+}
+
+func (e encryptedExtractedCredential) decrypt(ID, ns string, envelope map[string]interface{}) (map[string]interface{}, error) {
+	field := func(name string) (string, error) {
+		v, ok := envelope[name].(string)
+		if !ok {
+			return "", notEncryptedError{id: ID}
+		}
+		return v, nil
+	}
+	keyID, err := field(envelopeKeyIDKey)
+	if err != nil {
+		return nil, err
+	}
+	encodedKey, err := field(envelopeKeyKey)
+	if err != nil {
+		return nil, err
+	}
+	encodedData, err := field(envelopeDataKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.keys.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := openData(key, wrapped, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the key of credentials %v - %v", ID, err)
+	}
+	data, err := base64.StdEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := openData(dataKey, data, credentialAdditionalData(ID, ns))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt credentials %v - %v", ID, err)
+	}
+	creds := map[string]interface{}{}
+	if err := json.Unmarshal(plain, &creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+func (e encryptedExtractedCredential) CreateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+	envelope, err := e.encrypt(ID, ns, extCreds)
+	if err != nil {
+		return err
+	}
+	return e.backend.CreateExtractedCredential(ID, ns, envelope, labels)
+}
+
+func (e encryptedExtractedCredential) UpdateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+	envelope, err := e.encrypt(ID, ns, extCreds)
+	if err != nil {
+		return err
+	}
+	return e.backend.UpdateExtractedCredential(ID, ns, envelope, labels)
+}
+
+func (e encryptedExtractedCredential) GetExtractedCredential(ID, ns string) (map[string]interface{}, error) {
+	envelope, err := e.backend.GetExtractedCredential(ID, ns)
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(ID, ns, envelope)
+}
+
+func (e encryptedExtractedCredential) DeleteExtractedCredential(ID, ns string) error {
+	return e.backend.DeleteExtractedCredential(ID, ns)
+}
+
+func (e encryptedExtractedCredential) ListExtractedCredentials(ns string) (map[string]map[string]string, error) {
+	return e.backend.ListExtractedCredentials(ns)
+}
+
+func (e encryptedExtractedCredential) QueryExtractedCredentials(ns string, selector map[string]string) (map[string]map[string]string, error) {
+	return e.backend.QueryExtractedCredentials(ns, selector)
+}
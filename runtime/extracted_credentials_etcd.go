@@ -0,0 +1,201 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	etcd "github.com/coreos/etcd/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultEtcdCredentialPrefix = "/extracted_credentials"
+
+// etcdCredential - the value stored for an extracted credential in etcd.
+type etcdCredential struct {
+	Credentials map[string]interface{} `json:"credentials"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Created     time.Time              `json:"created"`
+}
+
+type etcdExtractedCredential struct {
+	kapi   etcd.KeysAPI
+	prefix string
+}
+
+// NewEtcdExtractedCredential - Keeps the extracted credentials in etcd under
+// <prefix>/<namespace>/<id>. The client configured with
+// clients.InitEtcdConfig is used when kapi is nil, the prefix defaults to
+// /extracted_credentials.
+func NewEtcdExtractedCredential(kapi etcd.KeysAPI, prefix string) ExtractedCredential {
+	if prefix == "" {
+		prefix = defaultEtcdCredentialPrefix
+	}
+	return etcdExtractedCredential{kapi: kapi, prefix: prefix}
+}
+
+func (e etcdExtractedCredential) keysAPI() (etcd.KeysAPI, error) {
+	if e.kapi != nil {
+		return e.kapi, nil
+	}
+	client, err := clients.Etcd()
+	if err != nil {
+		return nil, err
+	}
+	return etcd.NewKeysAPI(client), nil
+}
+
+func (e etcdExtractedCredential) key(ID, ns string) string {
+	return path.Join(e.prefix, ns, ID)
+}
+
+func (e etcdExtractedCredential) get(kapi etcd.KeysAPI, ID, ns string) (*etcdCredential, error) {
+	resp, err := kapi.Get(context.Background(), e.key(ID, ns), &etcd.GetOptions{Quorum: true})
+	if err != nil {
+		if etcd.IsKeyNotFound(err) {
+			return nil, ErrCredentialsNotFound
+		}
+		return nil, err
+	}
+	cred := &etcdCredential{}
+	if err := json.Unmarshal([]byte(resp.Node.Value), cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+func (e etcdExtractedCredential) set(kapi etcd.KeysAPI, ID, ns string,
+	cred etcdCredential, prevExist etcd.PrevExistType) error {
+
+	value, err := json.Marshal(cred)
+	if err != nil {
+		return err
+	}
+	_, err = kapi.Set(context.Background(), e.key(ID, ns), string(value), &etcd.SetOptions{PrevExist: prevExist})
+	if err != nil {
+		if etcd.IsKeyNotFound(err) {
+			return ErrCredentialsNotFound
+		}
+		log.Errorf("unable to save extracted credentials - %v", err)
+		return err
+	}
+	return nil
+}
+
+func (e etcdExtractedCredential) CreateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+
+	kapi, err := e.keysAPI()
+	if err != nil {
+		log.Errorf("Unable to get etcd client - %v", err)
+		return err
+	}
+	cred := etcdCredential{Credentials: extCreds, Labels: labels, Created: time.Now()}
+	return e.set(kapi, ID, ns, cred, etcd.PrevNoExist)
+}
+
+func (e etcdExtractedCredential) UpdateExtractedCredential(ID, ns string,
+	extCreds map[string]interface{}, labels map[string]string) error {
+
+	kapi, err := e.keysAPI()
+	if err != nil {
+		log.Errorf("Unable to get etcd client - %v", err)
+		return err
+	}
+	// Keep the creation time of the credentials.
+	cred, err := e.get(kapi, ID, ns)
+	if err != nil {
+		if err != ErrCredentialsNotFound {
+			log.Errorf("unable to get extracted credentials - %v", err)
+		}
+		return err
+	}
+	cred.Credentials = extCreds
+	cred.Labels = labels
+	return e.set(kapi, ID, ns, *cred, etcd.PrevExist)
+}
+
+func (e etcdExtractedCredential) GetExtractedCredential(ID, ns string) (map[string]interface{}, error) {
+	kapi, err := e.keysAPI()
+	if err != nil {
+		log.Errorf("Unable to get etcd client - %v", err)
+		return nil, err
+	}
+	cred, err := e.get(kapi, ID, ns)
+	if err != nil {
+		if err == ErrCredentialsNotFound {
+			log.Debugf("credentials not found id: %v, namespace: %v", ID, ns)
+		} else {
+			log.Errorf("unable to get extracted credentials - %v", err)
+		}
+		return nil, err
+	}
+	return cred.Credentials, nil
+}
+
+func (e etcdExtractedCredential) DeleteExtractedCredential(ID, ns string) error {
+	kapi, err := e.keysAPI()
+	if err != nil {
+		log.Errorf("Unable to get etcd client - %v", err)
+		return err
+	}
+	_, err = kapi.Delete(context.Background(), e.key(ID, ns), &etcd.DeleteOptions{})
+	if err != nil && !etcd.IsKeyNotFound(err) {
+		log.Errorf("unable to delete extracted credentials - %v", err)
+		return err
+	}
+	return nil
+}
+
+func (e etcdExtractedCredential) ListExtractedCredentials(ns string) (map[string]map[string]string, error) {
+	return e.QueryExtractedCredentials(ns, nil)
+}
+
+func (e etcdExtractedCredential) QueryExtractedCredentials(ns string, selector map[string]string) (map[string]map[string]string, error) {
+	kapi, err := e.keysAPI()
+	if err != nil {
+		log.Errorf("Unable to get etcd client - %v", err)
+		return nil, err
+	}
+	creds := map[string]map[string]string{}
+	resp, err := kapi.Get(context.Background(), path.Join(e.prefix, ns), &etcd.GetOptions{Recursive: true, Quorum: true})
+	if err != nil {
+		if etcd.IsKeyNotFound(err) {
+			return creds, nil
+		}
+		log.Errorf("unable to list extracted credentials - %v", err)
+		return nil, err
+	}
+	for _, node := range resp.Node.Nodes {
+		if node.Dir {
+			continue
+		}
+		cred := etcdCredential{}
+		if err := json.Unmarshal([]byte(node.Value), &cred); err != nil {
+			log.Warningf("skipping malformed extracted credentials %v - %v", node.Key, err)
+			continue
+		}
+		if matchesLabels(cred.Labels, selector) {
+			creds[path.Base(node.Key)] = credentialListEntry(cred.Labels, cred.Created)
+		}
+	}
+	return creds, nil
+}
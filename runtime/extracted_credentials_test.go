@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/automationbroker/bundle-lib/clients"
 	"k8s.io/api/core/v1"
@@ -361,3 +362,61 @@ func TestGetExtractedCredential(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryExtractedCredentials(t *testing.T) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	created := metav1.NewTime(time.Unix(1500000000, 0))
+	secret := func(name string, labels map[string]string, data map[string][]byte) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "testing",
+				Labels:            labels,
+				CreationTimestamp: created,
+			},
+			Data: data,
+		}
+	}
+	creds := map[string][]byte{"credentials": []byte(`{"user":"admin"}`)}
+	k8scli.Client = fake.NewSimpleClientset(
+		secret("instance-1", map[string]string{"bundleAction": "provision", "bundleName": "mysql"}, creds),
+		secret("binding-1", map[string]string{"bundleAction": "bind", "bundleName": "mysql"}, creds),
+		secret("other-secret", map[string]string{"bundleName": "mysql"}, map[string][]byte{"token": []byte("x")}),
+	)
+
+	testCases := []struct {
+		name     string
+		selector map[string]string
+		expected map[string]map[string]string
+	}{
+		{
+			name: "list skips secrets without credentials",
+			expected: map[string]map[string]string{
+				"instance-1": {"bundleAction": "provision", "bundleName": "mysql", CredentialCreatedLabel: "1500000000"},
+				"binding-1":  {"bundleAction": "bind", "bundleName": "mysql", CredentialCreatedLabel: "1500000000"},
+			},
+		},
+		{
+			name:     "query by label",
+			selector: map[string]string{"bundleAction": "bind"},
+			expected: map[string]map[string]string{
+				"binding-1": {"bundleAction": "bind", "bundleName": "mysql", CredentialCreatedLabel: "1500000000"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := defaultExtractedCredential{}
+			result, err := d.QueryExtractedCredentials("testing", tc.selector)
+			if err != nil {
+				t.Fatalf("unexpected error - %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("expected %v got %v", tc.expected, result)
+			}
+		})
+	}
+}
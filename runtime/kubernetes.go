@@ -16,12 +16,14 @@
 
 package runtime
 
-type kubernetes struct{}
+import "github.com/automationbroker/bundle-lib/clients"
 
-func (k kubernetes) getRuntime() string {
-	return "kubernetes"
+// kubernetesPlatform - the fallback profile, every cluster is kubernetes.
+var kubernetesPlatform = PlatformProfile{
+	Name:   "kubernetes",
+	Detect: detectKubernetes,
 }
 
-func (k kubernetes) shouldJoinNetworks() (bool, PostSandboxCreate, PostSandboxDestroy) {
-	return false, nil, nil
+func detectKubernetes(*clients.KubernetesClient) (bool, error) {
+	return true, nil
 }
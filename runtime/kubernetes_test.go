@@ -18,19 +18,21 @@ package runtime
 
 import "testing"
 
-func TestKubernetesShouldJoinNetworks(t *testing.T) {
-	k := kubernetes{}
-	s := k.getRuntime()
-	if s != "kubernetes" {
+func TestKubernetesGetRuntime(t *testing.T) {
+	if kubernetesPlatform.runtimeName() != "kubernetes" {
 		t.Fatal("runtime does not match kubernetes")
 	}
 }
 
-func TestKubernetesGetRuntime(t *testing.T) {
-	k := kubernetes{}
+func TestKubernetesShouldJoinNetworks(t *testing.T) {
+	if kubernetesPlatform.JoinNetworks.Run != nil || kubernetesPlatform.IsolateNetworks.Run != nil {
+		t.Fatal("sandbox network hooks were not nil.")
+	}
+}
 
-	jn, postCreateHook, postDestroyHook := k.shouldJoinNetworks()
-	if jn || postCreateHook != nil || postDestroyHook != nil {
-		t.Fatal("should join networks, or sand box hooks were not nil.")
+func TestKubernetesDetect(t *testing.T) {
+	ok, err := kubernetesPlatform.Detect(nil)
+	if !ok || err != nil {
+		t.Fatal("every cluster should be detected as kubernetes")
 	}
 }
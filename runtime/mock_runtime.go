@@ -109,8 +109,17 @@ func (_m *MockRuntime) DeleteState(name string) error {
 }
 
 // DestroySandbox provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
-func (_m *MockRuntime) DestroySandbox(_a0 string, _a1 string, _a2 []string, _a3 string, _a4 bool, _a5 bool) {
-	_m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
+func (_m *MockRuntime) DestroySandbox(_a0 string, _a1 string, _a2 []string, _a3 string, _a4 bool, _a5 bool) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, []string, string, bool, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4, _a5)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
 // ExtractCredentials provides a mock function with given fields: _a0, _a1, _a2
@@ -136,6 +145,29 @@ func (_m *MockRuntime) ExtractCredentials(_a0 string, _a1 string, _a2 int) ([]by
 	return r0, r1
 }
 
+// GetBundleResult provides a mock function with given fields: _a0, _a1
+func (_m *MockRuntime) GetBundleResult(_a0 string, _a1 string) (*BundleResult, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *BundleResult
+	if rf, ok := ret.Get(0).(func(string, string) *BundleResult); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*BundleResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetExtractedCredential provides a mock function with given fields: _a0, _a1
 func (_m *MockRuntime) GetExtractedCredential(_a0 string, _a1 string) (map[string]interface{}, error) {
 	ret := _m.Called(_a0, _a1)
@@ -173,6 +205,29 @@ func (_m *MockRuntime) GetRuntime() string {
 	return r0
 }
 
+// ListExtractedCredentials provides a mock function with given fields: _a0
+func (_m *MockRuntime) ListExtractedCredentials(_a0 string) (map[string]map[string]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 map[string]map[string]string
+	if rf, ok := ret.Get(0).(func(string) map[string]map[string]string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // MasterName provides a mock function with given fields: instanceID
 func (_m *MockRuntime) MasterName(instanceID string) string {
 	ret := _m.Called(instanceID)
@@ -215,6 +270,29 @@ func (_m *MockRuntime) MountLocation() string {
 	return r0
 }
 
+// QueryExtractedCredentials provides a mock function with given fields: _a0, _a1
+func (_m *MockRuntime) QueryExtractedCredentials(_a0 string, _a1 map[string]string) (map[string]map[string]string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 map[string]map[string]string
+	if rf, ok := ret.Get(0).(func(string, map[string]string) map[string]map[string]string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, map[string]string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RunBundle provides a mock function with given fields: _a0
 func (_m *MockRuntime) RunBundle(_a0 ExecutionContext) (ExecutionContext, error) {
 	ret := _m.Called(_a0)
@@ -59,6 +59,52 @@ func (_m *ExtractedCredential) GetExtractedCredential(_a0 string, _a1 string) (m
 	return r0, r1
 }
 
+// ListExtractedCredentials provides a mock function with given fields: _a0
+func (_m *ExtractedCredential) ListExtractedCredentials(_a0 string) (map[string]map[string]string, error) {
+	ret := _m.Called(_a0)
+
+	var r0 map[string]map[string]string
+	if rf, ok := ret.Get(0).(func(string) map[string]map[string]string); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryExtractedCredentials provides a mock function with given fields: _a0, _a1
+func (_m *ExtractedCredential) QueryExtractedCredentials(_a0 string, _a1 map[string]string) (map[string]map[string]string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 map[string]map[string]string
+	if rf, ok := ret.Get(0).(func(string, map[string]string) map[string]map[string]string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, map[string]string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateExtractedCredential provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *ExtractedCredential) UpdateExtractedCredential(_a0 string, _a1 string, _a2 map[string]interface{}, _a3 map[string]string) error {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -17,6 +17,7 @@
 package runtime
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -24,21 +25,58 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/automationbroker/bundle-lib/clients"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/wait"
+	kubeversiontypes "k8s.io/apimachinery/pkg/version"
 )
 
-type openshift struct{}
+// openshiftPlatform - OpenShift identified by the /version/openshift endpoint.
+var openshiftPlatform = PlatformProfile{
+	Name:     "openshift",
+	Priority: 10,
+	Detect:   detectOpenshift,
+}
+
+// openshiftMultitenantPlatform - OpenShift with the multitenant network
+// plugin, the sandbox has to join the networks of the target namespaces.
+var openshiftMultitenantPlatform = PlatformProfile{
+	Name:            "openshift-multitenant",
+	Runtime:         "openshift",
+	Priority:        20,
+	Detect:          detectOpenshiftMultitenant,
+	CheckTargets:    SandboxHook{Name: "check target networks", Required: true, Run: checkPodNetworks},
+	JoinNetworks:    SandboxHook{Name: "join pod networks", Run: addPodNetworks},
+	IsolateNetworks: SandboxHook{Name: "isolate pod networks", Run: isolatePodNetworks},
+}
 
-func (o openshift) getRuntime() string {
-	return "openshift"
+func detectOpenshift(k8scli *clients.KubernetesClient) (bool, error) {
+	restclient := k8scli.Client.CoreV1().RESTClient()
+	body, err := restclient.Get().AbsPath("/version/openshift").Do().Raw()
+	switch {
+	case err == nil:
+		var kubeServerInfo kubeversiontypes.Info
+		err = json.Unmarshal(body, &kubeServerInfo)
+		if err != nil && len(body) > 0 {
+			return false, err
+		}
+		log.Infof("OpenShift version: %v", kubeServerInfo)
+		return true, nil
+	case kapierrors.IsNotFound(err) || kapierrors.IsUnauthorized(err) || kapierrors.IsForbidden(err):
+		return false, nil
+	default:
+		return false, err
+	}
 }
 
-func (o openshift) shouldJoinNetworks() (bool, PostSandboxCreate, PostSandboxDestroy) {
+func detectOpenshiftMultitenant(k8scli *clients.KubernetesClient) (bool, error) {
+	if ok, err := detectOpenshift(k8scli); !ok || err != nil {
+		return ok, err
+	}
 	ocli, err := clients.Openshift()
 	if err != nil {
 		log.Errorf("unable to get openshift client - %v", err)
 		// Defaulting if anything goes wrong to not join the networks.
-		return false, nil, nil
+		return false, nil
 	}
 	pluginName, err := ocli.GetClusterNetworkPlugin()
 	log.Debugf("plugin for the network - %v", pluginName)
@@ -47,18 +85,36 @@ func (o openshift) shouldJoinNetworks() (bool, PostSandboxCreate, PostSandboxDes
 		// or a pure k8s cluster. Therefore making this a notice.
 		log.Debugf("unable to retrieve the network plugin, defaulting to not joining networks - %v", err)
 		// Defaulting to not join the networks.
-		return false, nil, nil
+		return false, nil
 	}
 
 	// Case insensitive check here because want to prepare if things change.
-	if strings.ToLower(pluginName) == "redhat/openshift-ovs-multitenant" {
-		log.Debugf("stating that the pluginname is multitenant - %v", pluginName)
-		return true, addPodNetworks, isolatePodNetworks
+	return strings.ToLower(pluginName) == "redhat/openshift-ovs-multitenant", nil
+}
+
+// checkPodNetworks - rejects targets that are on different networks. A
+// namespace can only join a single network, making the sandbox global would
+// give the bundle access to every project.
+func checkPodNetworks(ctx *SandboxContext) error {
+	if len(ctx.Targets) < 2 {
+		return nil
 	}
-	return false, nil, nil
+	o, err := clients.Openshift()
+	if err != nil {
+		return err
+	}
+	shared, err := shareNetwork(o, ctx.Targets)
+	if err != nil {
+		return err
+	}
+	if !shared {
+		return fmt.Errorf("target namespaces %v are on different networks, the sandbox can only join one", ctx.Targets)
+	}
+	return nil
 }
 
-func addPodNetworks(pod, ns string, targetNS []string, apbRole string) error {
+func addPodNetworks(ctx *SandboxContext) error {
+	ns, targetNS := ctx.Namespace, ctx.Targets
 	log.Debugf("adding pod networks together namespace: %v, target namespaces: %v", ns, targetNS)
 	// Check to make sure that we have a target namespace.
 	if len(targetNS) < 1 {
@@ -90,7 +146,24 @@ func addPodNetworks(pod, ns string, targetNS []string, apbRole string) error {
 	})
 }
 
-func isolatePodNetworks(pod, ns string, targetNS []string) error {
+// shareNetwork - true if all of the target namespaces are on the same network.
+func shareNetwork(o *clients.OpenshiftClient, targetNS []string) (bool, error) {
+	var netID uint32
+	for i, target := range targetNS {
+		netns, err := o.GetNetNamespace(target)
+		if err != nil {
+			return false, err
+		}
+		if i > 0 && netns.NetID != netID {
+			return false, nil
+		}
+		netID = netns.NetID
+	}
+	return true, nil
+}
+
+func isolatePodNetworks(ctx *SandboxContext) error {
+	ns, targetNS := ctx.Namespace, ctx.Targets
 	log.Debugf("adding pod networks together namespace: %v, target namespaces: %v", ns, targetNS)
 	// Check to make sure that we have a target namespace.
 	if len(targetNS) < 1 {
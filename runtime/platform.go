@@ -0,0 +1,171 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+// PodSettings - Defaults of a platform that are applied to every bundle pod.
+type PodSettings struct {
+	Annotations     map[string]string
+	NodeSelector    map[string]string
+	Tolerations     []v1.Toleration
+	SecurityContext *v1.PodSecurityContext
+}
+
+// PlatformDetectFunc - Returns true if the cluster the client is connected to
+// is the platform. An error aborts the runtime initialization.
+type PlatformDetectFunc func(*clients.KubernetesClient) (bool, error)
+
+// PlatformProfile - The behavior of the runtime that is specific to a
+// container orchestration platform.
+type PlatformProfile struct {
+	// Name - unique name of the profile.
+	Name string
+	// Runtime - the runtime name passed to bundles in the cluster extra var,
+	// defaults to Name.
+	Runtime string
+	// Priority - profiles with a higher priority are detected first, the
+	// profile registered last wins a tie.
+	Priority int
+	// Detect - identifies the platform.
+	Detect PlatformDetectFunc
+	// CheckTargets - run before the sandbox is created, a required hook
+	// rejects targets the sandbox can not be given access to.
+	CheckTargets SandboxHook
+	// JoinNetworks - run after the sandbox is created to give the sandbox
+	// network access to the target namespaces.
+	JoinNetworks SandboxHook
+	// IsolateNetworks - run after the sandbox is destroyed to revert
+	// JoinNetworks.
+	IsolateNetworks SandboxHook
+	// PodSettings - defaults for the bundle pods.
+	PodSettings PodSettings
+}
+
+func (p PlatformProfile) runtimeName() string {
+	if p.Runtime != "" {
+		return p.Runtime
+	}
+	return p.Name
+}
+
+type platformRegistry struct {
+	mutex    sync.Mutex
+	profiles []PlatformProfile
+}
+
+var platforms = &platformRegistry{
+	profiles: []PlatformProfile{kubernetesPlatform, openshiftPlatform, openshiftMultitenantPlatform},
+}
+
+// RegisterPlatform - Registers a platform profile that is considered by
+// NewRuntime. Registering a profile with the name of an existing profile
+// replaces it.
+func RegisterPlatform(profile PlatformProfile) error {
+	if profile.Name == "" {
+		return fmt.Errorf("platform profile requires a name")
+	}
+	if profile.Detect == nil {
+		return fmt.Errorf("platform profile %v requires a detect function", profile.Name)
+	}
+	platforms.mutex.Lock()
+	defer platforms.mutex.Unlock()
+	for i, p := range platforms.profiles {
+		if p.Name == profile.Name {
+			platforms.profiles = append(platforms.profiles[:i], platforms.profiles[i+1:]...)
+			break
+		}
+	}
+	platforms.profiles = append(platforms.profiles, profile)
+	return nil
+}
+
+// Platforms - Returns the registered platform profiles in the order they
+// are detected.
+func Platforms() []PlatformProfile {
+	platforms.mutex.Lock()
+	defer platforms.mutex.Unlock()
+	profiles := []PlatformProfile{}
+	for i := len(platforms.profiles) - 1; i >= 0; i-- {
+		profiles = append(profiles, platforms.profiles[i])
+	}
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Priority > profiles[j].Priority
+	})
+	return profiles
+}
+
+// detectPlatform - Returns the profile with the given name, or the first
+// profile that detects the cluster when the name is empty.
+func detectPlatform(k8scli *clients.KubernetesClient, name string) (PlatformProfile, error) {
+	for _, profile := range Platforms() {
+		if name != "" {
+			if profile.Name == name {
+				return profile, nil
+			}
+			continue
+		}
+		ok, err := profile.Detect(k8scli)
+		if err != nil {
+			return PlatformProfile{}, err
+		}
+		if ok {
+			log.Infof("Detected platform: %v", profile.Name)
+			return profile, nil
+		}
+	}
+	if name != "" {
+		return PlatformProfile{}, fmt.Errorf("unknown platform %v", name)
+	}
+	return PlatformProfile{}, fmt.Errorf("unable to detect the platform of the cluster")
+}
+
+// applyPodSettings - Applies the platform defaults to the pod, values set on
+// the pod are kept.
+func applyPodSettings(pod *v1.Pod, settings *PodSettings) {
+	if settings == nil {
+		return
+	}
+	if len(settings.Annotations) > 0 && pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	for k, v := range settings.Annotations {
+		if _, ok := pod.Annotations[k]; !ok {
+			pod.Annotations[k] = v
+		}
+	}
+	if len(settings.NodeSelector) > 0 && pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = map[string]string{}
+	}
+	for k, v := range settings.NodeSelector {
+		if _, ok := pod.Spec.NodeSelector[k]; !ok {
+			pod.Spec.NodeSelector[k] = v
+		}
+	}
+	pod.Spec.Tolerations = append(pod.Spec.Tolerations, settings.Tolerations...)
+	if pod.Spec.SecurityContext == nil && settings.SecurityContext != nil {
+		pod.Spec.SecurityContext = settings.SecurityContext.DeepCopy()
+	}
+}
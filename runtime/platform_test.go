@@ -0,0 +1,153 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func detectAs(ok bool, err error) PlatformDetectFunc {
+	return func(*clients.KubernetesClient) (bool, error) {
+		return ok, err
+	}
+}
+
+func TestRegisterPlatform(t *testing.T) {
+	saved := platforms.profiles
+	defer func() { platforms.profiles = saved }()
+
+	if err := RegisterPlatform(PlatformProfile{Detect: detectAs(true, nil)}); err == nil {
+		t.Fatal("expected an error registering a profile without a name")
+	}
+	if err := RegisterPlatform(PlatformProfile{Name: "ovn"}); err == nil {
+		t.Fatal("expected an error registering a profile without detection")
+	}
+
+	testCases := []struct {
+		name     string
+		profiles []PlatformProfile
+		platform string
+		expected string
+		runtime  string
+		wantErr  bool
+	}{
+		{
+			name: "higher priority is detected first",
+			profiles: []PlatformProfile{
+				{Name: "ovn", Runtime: "openshift", Priority: 30, Detect: detectAs(true, nil)},
+			},
+			expected: "ovn",
+			runtime:  "openshift",
+		},
+		{
+			name: "last registered wins a tie with kubernetes",
+			profiles: []PlatformProfile{
+				{Name: "kind", Detect: detectAs(true, nil)},
+			},
+			expected: "kind",
+			runtime:  "kind",
+		},
+		{
+			name: "profile not detected falls back to kubernetes",
+			profiles: []PlatformProfile{
+				{Name: "kind", Priority: 30, Detect: detectAs(false, nil)},
+			},
+			expected: "kubernetes",
+			runtime:  "kubernetes",
+		},
+		{
+			name: "detection error",
+			profiles: []PlatformProfile{
+				{Name: "broken", Priority: 30, Detect: detectAs(false, fmt.Errorf("boom"))},
+			},
+			wantErr: true,
+		},
+		{
+			name: "configured platform skips detection",
+			profiles: []PlatformProfile{
+				{Name: "broken", Priority: 30, Detect: detectAs(false, fmt.Errorf("boom"))},
+			},
+			platform: "kubernetes",
+			expected: "kubernetes",
+			runtime:  "kubernetes",
+		},
+		{
+			name:     "unknown configured platform",
+			platform: "unknown",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			platforms.profiles = []PlatformProfile{kubernetesPlatform}
+			for _, p := range tc.profiles {
+				if err := RegisterPlatform(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			profile, err := detectPlatform(nil, tc.platform)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if profile.Name != tc.expected || profile.runtimeName() != tc.runtime {
+				t.Fatalf("expected platform %v with runtime %v got %v with runtime %v",
+					tc.expected, tc.runtime, profile.Name, profile.runtimeName())
+			}
+		})
+	}
+}
+
+func TestApplyPodSettings(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"kept": "pod"},
+		},
+	}
+	applyPodSettings(pod, &PodSettings{
+		Annotations:  map[string]string{"kept": "platform", "added": "platform"},
+		NodeSelector: map[string]string{"role": "bundles"},
+		Tolerations:  []v1.Toleration{{Key: "bundles", Operator: v1.TolerationOpExists}},
+	})
+	if pod.Annotations["kept"] != "pod" || pod.Annotations["added"] != "platform" {
+		t.Fatalf("unexpected annotations: %v", pod.Annotations)
+	}
+	if pod.Spec.NodeSelector["role"] != "bundles" {
+		t.Fatalf("unexpected node selector: %v", pod.Spec.NodeSelector)
+	}
+	if len(pod.Spec.Tolerations) != 1 {
+		t.Fatalf("unexpected tolerations: %v", pod.Spec.Tolerations)
+	}
+	applyPodSettings(pod, nil)
+}
+
+func TestOpenshiftMultitenantCheckTargets(t *testing.T) {
+	// targets on different networks must abort the sandbox
+	if !openshiftMultitenantPlatform.CheckTargets.Required {
+		t.Fatal("checking the target networks should be required")
+	}
+	// a single target always shares its network
+	ctx := newSandboxContext("pod", "sandbox", []string{"target"}, "edit", nil)
+	if err := checkPodNetworks(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -59,10 +59,17 @@ type ExecutionContext struct {
 	Policy      string
 	ProxyConfig *ProxyConfig
 	Metadata    map[string]string
-	// StateName the name of the configmap that holds the state for the bundle
+	// StateName the name of the secrets that hold the state for the bundle
 	StateName string
 	// StateLocation the location in the pod that the state will be mounted
 	StateLocation string
+	// StateWritable mounts the state writable, the files written by the
+	// bundle are collected into a configmap named after the pod.
+	StateWritable bool
+	// Runtime the runtime version of the bundle, 0 when it is not known
+	Runtime int
+	// PodSettings the defaults of the platform for the bundle pod
+	PodSettings *PodSettings
 }
 
 // RunBundleFunc - method that defines how to run a bundle
@@ -80,7 +87,19 @@ func defaultRunBundle(extContext ExecutionContext) (ExecutionContext, error) {
 	if err != nil {
 		return extContext, err
 	}
-	volumes, volumeMounts := buildVolumeSpecs(extContext.Secrets, extContext.StateName)
+	var stateSource, readOnlyState *v1.VolumeSource
+	if extContext.StateName != "" {
+		source, err := sandboxStateVolumeSource(extContext.StateName, extContext.Location)
+		if err != nil {
+			return extContext, fmt.Errorf("unable to mount state %s - %v", extContext.StateName, err)
+		}
+		stateSource = &source
+	}
+	if !extContext.StateWritable {
+		// the state is seeded into the writable mount by addWritableState
+		readOnlyState = stateSource
+	}
+	volumes, volumeMounts := buildVolumeSpecs(extContext.Secrets, extContext.StateName, readOnlyState)
 
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -108,6 +127,11 @@ func defaultRunBundle(extContext ExecutionContext) (ExecutionContext, error) {
 		},
 	}
 
+	if extContext.StateWritable {
+		addWritableState(pod, extContext, stateSource)
+	}
+	applyPodSettings(pod, extContext.PodSettings)
+
 	log.Infof(fmt.Sprintf("Creating pod %q in the %s namespace", pod.Name, extContext.Location))
 	_, err = k8scli.Client.CoreV1().Pods(extContext.Location).Create(pod)
 
@@ -130,7 +154,7 @@ func checkPullPolicy(policy string) (v1.PullPolicy, error) {
 	return value, nil
 }
 
-func buildVolumeSpecs(secrets []string, stateName string) ([]v1.Volume, []v1.VolumeMount) {
+func buildVolumeSpecs(secrets []string, stateName string, stateSource *v1.VolumeSource) ([]v1.Volume, []v1.VolumeMount) {
 	var optional bool
 	var mountName string
 	volumes := []v1.Volume{}
@@ -154,21 +178,15 @@ func buildVolumeSpecs(secrets []string, stateName string) ([]v1.Volume, []v1.Vol
 			ReadOnly:  true,
 		})
 	}
-	if stateName != "" {
+	if stateSource != nil {
 		volumeMounts = append(volumeMounts, v1.VolumeMount{
 			Name:      stateName,
 			MountPath: Provider.MountLocation(),
 			ReadOnly:  true,
 		})
 		volumes = append(volumes, v1.Volume{
-			Name: stateName,
-			VolumeSource: v1.VolumeSource{
-				ConfigMap: &v1.ConfigMapVolumeSource{
-					LocalObjectReference: v1.LocalObjectReference{
-						Name: stateName,
-					},
-				},
-			},
+			Name:         stateName,
+			VolumeSource: *stateSource,
 		})
 	}
 	return volumes, volumeMounts
@@ -194,7 +212,7 @@ func createPodEnv(executionContext ExecutionContext) []v1.EnvVar {
 		},
 	}
 
-	if executionContext.StateName != "" {
+	if executionContext.StateName != "" || executionContext.StateWritable {
 		podEnv = append(podEnv, v1.EnvVar{
 			Name:  "BUNDLE_STATE_LOCATION",
 			Value: executionContext.StateLocation,
@@ -190,15 +190,25 @@ func TestDefaultRunBundle(t *testing.T) {
 				if len(pod.Spec.Volumes) != 1 {
 					t.Fatalf("expected 1 volume but got %v ", len(pod.Spec.Volumes))
 				}
-				if pod.Spec.Volumes[0].ConfigMap == nil {
-					t.Fatalf("expected the volume to be a configmap but was nil")
+				projected := pod.Spec.Volumes[0].Projected
+				if projected == nil {
+					t.Fatalf("expected the volume to be projected from the state secrets but was nil")
 				}
-				if pod.Spec.Volumes[0].ConfigMap.Name != pod.Name {
-					t.Fatalf("expected the configmap name to match the pod name but it was %s ", pod.Spec.Volumes[0].ConfigMap.Name)
+				if len(projected.Sources) != 2 {
+					t.Fatalf("expected every part of the state to be mounted but got %v ", projected.Sources)
+				}
+				if projected.Sources[0].Secret.Name != pod.Name || projected.Sources[1].Secret.Name != stateChunkName(pod.Name, 0, 1) {
+					t.Fatalf("expected the secrets of the state to be mounted but got %v ", projected.Sources)
 				}
 
 			},
-			client: fake.NewSimpleClientset(),
+			client: fake.NewSimpleClientset(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bundle-test-state",
+					Namespace: "test-bundle-test",
+					Labels:    map[string]string{StateChunksLabel: "2"},
+				},
+			}),
 		},
 		{
 			name: "run bundle successfully with a secret mounted",
@@ -38,13 +38,17 @@ var Provider Runtime
 // Configuration - The configuration for the runtime
 type Configuration struct {
 	// PostCreateSandboxHooks - The sandbox hooks that you would like to run.
-	PostCreateSandboxHooks []PostSandboxCreate
+	PostCreateSandboxHooks []SandboxHook
 	// PostDestroySandboxHooks - The sandbox hooks that you would like to run.
-	PostDestroySandboxHooks []PostSandboxDestroy
+	PostDestroySandboxHooks []SandboxHook
 	// PreCreateSandboxHooks - The sandbox hooks that you would like to run.
-	PreCreateSandboxHooks []PreSandboxCreate
+	PreCreateSandboxHooks []SandboxHook
 	// PreDestroySandboxHooks - The sandbox hooks that you would like to run.
-	PreDestroySandboxHooks []PreSandboxDestroy
+	PreDestroySandboxHooks []SandboxHook
+	// PreRunBundleHooks - The hooks to run before the bundle pod is created.
+	PreRunBundleHooks []SandboxHook
+	// PostRunBundleHooks - The hooks to run once the bundle pod has completed.
+	PostRunBundleHooks []SandboxHook
 	// WatchBundle - this is the method that watches the bundle for completion.
 	// The UpdateDescriptionFunc in the default case will call this function when the last description
 	// annotation on the running bundle is changed.
@@ -55,10 +59,30 @@ type Configuration struct {
 	// secrets from a namespace to the executionContext namespace.
 	CopySecretsToNamespace CopySecretsToNamespaceFunc
 	ExtractedCredential
+	// CredentialBackend - name of the built-in backend for the extracted
+	// credentials when ExtractedCredential is not set, defaults to secrets.
+	CredentialBackend string
+	// CredentialKeys - encrypts the extracted credentials with these keys
+	// when set.
+	CredentialKeys StateKeyProvider
 	// StateMountLocation this is where on disk the state will be stored for a bundle
 	StateMountLocation string
 	// StateMasterNamespace the namespace where state created by bundles will be copied to between actions
 	StateMasterNamespace string
+	// StateHistory the number of snapshots of the state of an instance to keep, 0 disables the snapshots
+	StateHistory int
+	// StateStore the backend for the state in the master namespace, defaults to configmaps in StateMasterNamespace
+	StateStore StateStore
+	// StateWritable mounts the state writable in the bundle pods, the files written
+	// by the bundle are persisted when the bundle succeeds. The init container and
+	// the sidecar run the bundle image, it needs sh and tar. Runtime 1 bundles keep
+	// a read only state.
+	StateWritable bool
+	// SandboxPool - the pool of pre-warmed sandboxes, disabled when the size is 0.
+	SandboxPool SandboxPoolConfig
+	// Platform - name of the platform profile to use, the platform is
+	// detected when empty. See RegisterPlatform.
+	Platform string
 }
 
 // Runtime - Abstraction for broker actions
@@ -66,8 +90,9 @@ type Runtime interface {
 	ValidateRuntime() error
 	GetRuntime() string
 	CreateSandbox(string, string, []string, string, map[string]string) (string, string, error)
-	DestroySandbox(string, string, []string, string, bool, bool)
+	DestroySandbox(string, string, []string, string, bool, bool) error
 	ExtractCredentials(string, string, int) ([]byte, error)
+	GetBundleResult(string, string) (*BundleResult, error)
 	ExtractedCredential
 	WatchRunningBundle(string, string, UpdateDescriptionFn) error
 	RunBundle(ExecutionContext) (ExecutionContext, error)
@@ -77,24 +102,22 @@ type Runtime interface {
 
 // Variables for interacting with runtimes
 type provider struct {
-	coe
+	platform PlatformProfile
 	ExtractedCredential
-	postSandboxCreate      []PostSandboxCreate
-	preSandboxCreate       []PreSandboxCreate
-	postSandboxDestroy     []PostSandboxDestroy
-	preSandboxDestroy      []PreSandboxDestroy
+	postSandboxCreate      []SandboxHook
+	preSandboxCreate       []SandboxHook
+	postSandboxDestroy     []SandboxHook
+	preSandboxDestroy      []SandboxHook
+	preRunBundle           []SandboxHook
+	postRunBundle          []SandboxHook
+	sandboxes              *sandboxRegistry
+	pool                   *sandboxPool
 	watchBundle            WatchRunningBundleFunc
 	runBundle              RunBundleFunc
 	copySecretsToNamespace CopySecretsToNamespaceFunc
 	state
 }
 
-// Abstraction for actions that are different between runtimes
-type coe interface {
-	getRuntime() string
-	shouldJoinNetworks() (bool, PostSandboxCreate, PostSandboxDestroy)
-}
-
 // NewRuntime - Initialize provider variable
 // extCreds - You can pass an ExtractedCredential conforming object this will
 // be used to do CRUD operations. If you want to use the default pass nil
@@ -106,33 +129,26 @@ func NewRuntime(config Configuration) {
 		log.Error(err.Error())
 		panic(err.Error())
 	}
-	// Identify which cluster we're using
-	restclient := k8scli.Client.CoreV1().RESTClient()
-	body, err := restclient.Get().AbsPath("/version/openshift").Do().Raw()
-	var cluster coe
-	switch {
-	case err == nil:
-		var kubeServerInfo kubeversiontypes.Info
-		err = json.Unmarshal(body, &kubeServerInfo)
-		if err != nil && len(body) > 0 {
-			log.Error(err.Error())
-			panic(err.Error())
-		}
-		log.Infof("OpenShift version: %v", kubeServerInfo)
-		cluster = newOpenshift()
-	case kapierrors.IsNotFound(err) || kapierrors.IsUnauthorized(err) || kapierrors.IsForbidden(err):
-		cluster = newKubernetes()
-	default:
+	// Identify which platform we're running on
+	platform, err := detectPlatform(k8scli, config.Platform)
+	if err != nil {
 		log.Error(err.Error())
 		panic(err.Error())
 	}
 
 	var c ExtractedCredential
 	if config.ExtractedCredential == nil {
-		c = defaultExtractedCredential{}
+		c, err = NewExtractedCredential(config.CredentialBackend)
+		if err != nil {
+			log.Error(err.Error())
+			panic(err.Error())
+		}
 	} else {
 		c = config.ExtractedCredential
 	}
+	if config.CredentialKeys != nil {
+		c = NewEncryptedExtractedCredential(c, config.CredentialKeys)
+	}
 	// defaults for state
 	if config.StateMasterNamespace == "" {
 		config.StateMasterNamespace = defaultNamespace
@@ -141,7 +157,14 @@ func NewRuntime(config Configuration) {
 		config.StateMountLocation = defaultMountLocation
 	}
 
-	defaultStateManager := state{mountLocation: config.StateMountLocation, nsTarget: config.StateMasterNamespace}
+	defaultStateManager := state{
+		mountLocation: config.StateMountLocation,
+		nsTarget:      config.StateMasterNamespace,
+		history:       config.StateHistory,
+		store:         config.StateStore,
+		writable:      config.StateWritable,
+		collections:   newStateCollections(),
+	}
 	var w WatchRunningBundleFunc
 	if config.WatchBundle != nil {
 		w = config.WatchBundle
@@ -161,12 +184,18 @@ func NewRuntime(config Configuration) {
 		s = defaultCopySecretsToNamespace
 	}
 
-	p := &provider{coe: cluster,
+	p := &provider{platform: platform,
 		ExtractedCredential:    c,
 		watchBundle:            w,
 		runBundle:              r,
 		copySecretsToNamespace: s,
-		state: defaultStateManager,
+		state:                  defaultStateManager,
+		sandboxes:              newSandboxRegistry(),
+		pool:                   newSandboxPool(config.SandboxPool),
+	}
+
+	if p.pool != nil {
+		go p.pool.start()
 	}
 
 	if len(config.PreCreateSandboxHooks) > 0 {
@@ -185,25 +214,28 @@ func NewRuntime(config Configuration) {
 		p.postSandboxDestroy = config.PostDestroySandboxHooks
 	}
 
-	if ok, postCreateHook, postDestroyHook := cluster.shouldJoinNetworks(); ok {
-		log.Debugf("adding posthook to provider now.")
-		if postCreateHook != nil {
-			p.addPostCreateSandbox(postCreateHook)
-		}
-		if postDestroyHook != nil {
-			p.addPostDestroySandbox(postDestroyHook)
-		}
+	if len(config.PreRunBundleHooks) > 0 {
+		p.preRunBundle = config.PreRunBundleHooks
 	}
-	Provider = p
 
-}
+	if len(config.PostRunBundleHooks) > 0 {
+		p.postRunBundle = config.PostRunBundleHooks
+	}
 
-func newOpenshift() coe {
-	return openshift{}
-}
+	if platform.CheckTargets.Run != nil {
+		log.Debugf("adding check targets hook to provider now.")
+		p.addPreCreateSandbox(platform.CheckTargets)
+	}
+	if platform.JoinNetworks.Run != nil {
+		log.Debugf("adding join networks hook to provider now.")
+		p.addPostCreateSandbox(platform.JoinNetworks)
+	}
+	if platform.IsolateNetworks.Run != nil {
+		log.Debugf("adding isolate networks hook to provider now.")
+		p.addPostDestroySandbox(platform.IsolateNetworks)
+	}
+	Provider = p
 
-func newKubernetes() coe {
-	return kubernetes{}
 }
 
 // ValidateRuntime - Translate the broker cluster validation check into specific runtime checks
@@ -258,84 +290,66 @@ func (p provider) CreateSandbox(podName string,
 		return "", "", fmt.Errorf("unable to get target namespaces: %v", err)
 	}
 
+	serviceAccount := podName
 	// If Location is in the targets then we should not create the namespace.
 	if !isNamespaceInTargets(namespace, targets) {
-		// Create namespace.
-		ns := &apicorev1.Namespace{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels:       metadata,
-				GenerateName: namespace,
-			},
-		}
-		ns, err = k8scli.Client.CoreV1().Namespaces().Create(ns)
-		if err != nil {
-			return "", "", err
-		}
-		// Sandbox (i.e Namespace) was created.
-		namespace = ns.ObjectMeta.Name
-
-		// Check to see if there are already namespaces available before
-		// creating ours
-		policies, err := k8scli.Client.NetworkingV1().NetworkPolicies(targets[0]).List(metav1.ListOptions{})
-		if err != nil {
-			return "", "", err
-		}
-
-		// If there are already network policies, let's add one to allow for
-		// communication from the APB pod to the target namespace
-		if len(policies.Items) > 0 {
-			networkPolicy := &networkingv1.NetworkPolicy{
+		if pooled, ok := p.pool.claim(metadata); ok {
+			// Sandbox was claimed from the pool, it comes with a service account.
+			namespace = pooled
+			serviceAccount = SandboxPoolServiceAccount
+		} else {
+			// Create namespace.
+			ns := &apicorev1.Namespace{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: podName,
-				},
-				Spec: networkingv1.NetworkPolicySpec{
-					PodSelector: metav1.LabelSelector{},
-					Ingress: []networkingv1.NetworkPolicyIngressRule{
-						networkingv1.NetworkPolicyIngressRule{
-							From: []networkingv1.NetworkPolicyPeer{
-								networkingv1.NetworkPolicyPeer{
-									NamespaceSelector: metav1.AddLabelToSelector(
-										&metav1.LabelSelector{}, "apb-pod-name", podName),
-								},
-							},
-						},
-					},
+					Labels:       metadata,
+					GenerateName: namespace,
 				},
 			}
-
-			log.Debugf("Creating network policy for pod: %v to grant network access to ns: %v", podName, targets[0])
-			_, err = k8scli.Client.NetworkingV1().NetworkPolicies(targets[0]).Create(networkPolicy)
+			ns, err = k8scli.Client.CoreV1().Namespaces().Create(ns)
 			if err != nil {
-				log.Errorf("unable to create network policy object - %v", err)
 				return "", "", err
 			}
-			log.Debugf("Successfully created network policy for pod: %v to grant network access to ns: %v", podName, targets[0])
-		} else {
-			log.Info("No network policies found. Assuming things are open, skip network policy creation")
+			// Sandbox (i.e Namespace) was created.
+			namespace = ns.ObjectMeta.Name
 		}
 	}
 
-	for i, f := range p.preSandboxCreate {
-		log.Debugf("Running pre create sandbox function: %v", i+1)
-		err := f(podName, namespace, targets, apbRole)
-		if err != nil {
-			// Log the error and continue processing hooks. Expect hook to
-			// clean up after itself.
-			log.Warningf("Pre create sandbox function failed with err: %v", err)
+	// From here on every failure tears the sandbox down, a claimed or
+	// created namespace must not be left behind.
+	ctx := newSandboxContext(podName, namespace, targets, apbRole, metadata)
+	p.sandboxes.add(ctx)
+
+	if !isNamespaceInTargets(namespace, targets) {
+		// Grant the bundle pod network access to every target namespace.
+		for _, target := range targets {
+			err = createTargetNetworkPolicy(podName, target)
+			if err != nil {
+				p.abortSandbox(ctx)
+				return "", "", err
+			}
 		}
 	}
 
-	err = k8scli.CreateServiceAccount(podName, namespace)
+	err = runSandboxHooks("pre create sandbox", p.preSandboxCreate, ctx)
 	if err != nil {
+		p.abortSandbox(ctx)
 		return "", "", err
 	}
 
+	if !p.pool.owns(namespace) {
+		err = k8scli.CreateServiceAccount(podName, namespace)
+		if err != nil {
+			p.abortSandbox(ctx)
+			return "", "", err
+		}
+	}
+
 	log.Debugf("Trying to create apb sandbox: [ %s ], with %s permissions in namespace %s", podName, apbRole, namespace)
 
 	subjects := []rbac.Subject{
 		rbac.Subject{
 			Kind:      "ServiceAccount",
-			Name:      podName,
+			Name:      serviceAccount,
 			Namespace: namespace,
 		},
 	}
@@ -349,6 +363,7 @@ func (p provider) CreateSandbox(podName string,
 	// targetNamespace and namespace are the same
 	err = k8scli.CreateRoleBinding(podName, subjects, namespace, namespace, roleRef)
 	if err != nil {
+		p.abortSandbox(ctx)
 		return "", "", err
 	}
 
@@ -357,6 +372,7 @@ func (p provider) CreateSandbox(podName string,
 		if target != namespace {
 			err = k8scli.CreateRoleBinding(podName, subjects, namespace, target, roleRef)
 			if err != nil {
+				p.abortSandbox(ctx)
 				return "", "", err
 			}
 		}
@@ -366,17 +382,71 @@ func (p provider) CreateSandbox(podName string,
 	metrics.SandboxCreated()
 
 	log.Debug("Running post create sandbox functions if defined.")
-	for i, f := range p.postSandboxCreate {
-		log.Debugf("Running post create sandbox function: %v", i+1)
-		err := f(podName, namespace, targets, apbRole)
-		if err != nil {
-			// Log the error and continue processing hooks. Expect hook to
-			// clean up after itself.
-			log.Warningf("Post create sandbox function failed with err: %v", err)
-		}
+	err = runSandboxHooks("post create sandbox", p.postSandboxCreate, ctx)
+	if err != nil {
+		p.abortSandbox(ctx)
+		return "", "", err
 	}
 
-	return podName, namespace, nil
+	return serviceAccount, namespace, nil
+}
+
+// abortSandbox - tears down a sandbox whose creation failed or was aborted
+// by a required hook. The namespace is kept when it is one of the targets.
+func (p provider) abortSandbox(ctx *SandboxContext) {
+	log.Infof("Aborting creation of sandbox [ %s ] in namespace [ %s ]", ctx.PodName, ctx.Namespace)
+	err := p.DestroySandbox(ctx.PodName, ctx.Namespace, ctx.Targets, "",
+		isNamespaceInTargets(ctx.Namespace, ctx.Targets), false)
+	if err != nil {
+		log.Errorf("unable to clean up aborted sandbox [ %s ] - %v", ctx.PodName, err)
+	}
+}
+
+// createTargetNetworkPolicy - Adds a network policy that allows
+// communication from the APB pod to the target namespace. The policy is only
+// needed when the target already has network policies.
+func createTargetNetworkPolicy(podName, target string) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	// Check to see if there are already network policies available before
+	// creating ours
+	policies, err := k8scli.Client.NetworkingV1().NetworkPolicies(target).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(policies.Items) == 0 {
+		log.Infof("No network policies found in %v. Assuming things are open, skip network policy creation", target)
+		return nil
+	}
+	networkPolicy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: podName,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				networkingv1.NetworkPolicyIngressRule{
+					From: []networkingv1.NetworkPolicyPeer{
+						networkingv1.NetworkPolicyPeer{
+							NamespaceSelector: metav1.AddLabelToSelector(
+								&metav1.LabelSelector{}, "apb-pod-name", podName),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	log.Debugf("Creating network policy for pod: %v to grant network access to ns: %v", podName, target)
+	_, err = k8scli.Client.NetworkingV1().NetworkPolicies(target).Create(networkPolicy)
+	if err != nil {
+		log.Errorf("unable to create network policy object - %v", err)
+		return err
+	}
+	log.Debugf("Successfully created network policy for pod: %v to grant network access to ns: %v", podName, target)
+	return nil
 }
 
 func validateTargets(targets []string) error {
@@ -391,128 +461,182 @@ func validateTargets(targets []string) error {
 	for _, ns := range targets {
 		_, err = k8scli.Client.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
 		if err != nil {
-			return err
+			return fmt.Errorf("target namespace %v - %v", ns, err)
 		}
 	}
 	return nil
 }
 
-// DestroySandbox - Translate the broker DestorySandbox call into cluster resource calls
+// DestroySandbox - Translate the broker DestorySandbox call into cluster resource calls.
+// Every cleanup step is attempted even when an earlier one fails. The errors
+// are returned as a SandboxDestroyError listing the resources left behind.
 func (p provider) DestroySandbox(podName string,
 	namespace string,
 	targets []string,
 	configNamespace string,
 	keepNamespace bool,
-	keepNamespaceOnError bool) {
-
-	for i, f := range p.preSandboxDestroy {
-		log.Debugf("Running pre sandbox destroy:  %v", i+1)
-		err := f(podName, namespace, targets)
-		if err != nil {
-			// Log the error and continue processing hooks. Expect hook to
-			// clean up after itself.
-			log.Warningf("Pre destroy sandbox function failed with err: %v", err)
+	keepNamespaceOnError bool) error {
+
+	ctx := p.sandboxes.get(podName, namespace, targets)
+	defer p.sandboxes.remove(podName, namespace)
+	// A state that was not copied is not waited for, the collection stops
+	// when the pod is deleted.
+	defer p.collections.forget(podName, namespace)
+	destroyErr := &SandboxDestroyError{}
+
+	// Destroy can not be aborted, a failing required hook is only reported.
+	if err := runSandboxHooks("pre destroy sandbox", p.preSandboxDestroy, ctx); err != nil {
+		destroyErr.add(err)
+	}
+	for _, r := range ctx.Resources() {
+		log.Debugf("Deleting sandbox resource %s %s/%s", r.Kind, r.Namespace, r.Name)
+		if err := DeleteSandboxResource(r); err != nil {
+			destroyErr.add(fmt.Errorf("unable to delete sandbox resource %s %s/%s - %v", r.Kind, r.Namespace, r.Name, err), r)
 		}
 	}
 
 	log.Info("Destroying APB sandbox...")
 	if podName == "" {
 		log.Info("Requested destruction of APB sandbox with empty handle, skipping.")
-		return
+		return destroyErr.errOrNil()
 	}
 	k8scli, err := clients.Kubernetes()
 	if err != nil {
 		log.Error("Something went wrong getting kubernetes client")
-		log.Errorf("%s", err.Error())
-		return
+		destroyErr.add(err)
+		return destroyErr.errOrNil()
 	}
 	pod, err := k8scli.Client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
 	if err != nil {
 		log.Errorf("Unable to retrieve pod - %v", err)
 	}
 	if shouldDeleteNamespace(keepNamespace, keepNamespaceOnError, pod, err) {
-		if configNamespace != namespace {
+		if p.pool.owns(namespace) {
+			// Hand the sandbox back to the pool once the teardown is done.
+			defer p.pool.release(namespace)
+		} else if configNamespace != namespace {
 			log.Debugf("Deleting namespace %s", namespace)
-			k8scli.Client.CoreV1().Namespaces().Delete(namespace, &metav1.DeleteOptions{})
-			// This is keeping track of namespaces.
+			err = k8scli.Client.CoreV1().Namespaces().Delete(namespace, &metav1.DeleteOptions{})
+			if err != nil && !kapierrors.IsNotFound(err) {
+				destroyErr.add(fmt.Errorf("unable to delete namespace %s - %v", namespace, err),
+					SandboxResource{Kind: "Namespace", Name: namespace})
+			}
 		} else {
 			// We should not be attempting to run pods in the ASB namespace, if we are, something is seriously wrong.
-			panic(fmt.Errorf("Broker is attempting to delete its own namespace"))
+			destroyErr.add(fmt.Errorf("Broker is attempting to delete its own namespace"))
 		}
-
 	} else {
 		log.Debugf("Keeping namespace alive due to configuration")
+		if p.pool.owns(namespace) {
+			p.pool.keep(namespace)
+		}
 	}
-	log.Debugf("Deleting rolebinding %s, namespace %s", podName, namespace)
-
-	err = k8scli.DeleteRoleBinding(podName, namespace)
-	if err != nil {
-		log.Errorf("Something went wrong trying to destroy the rolebinding! - %v", err)
-		return
-	}
-	log.Infof("Successfully deleted rolebinding %s, namespace %s", podName, namespace)
 
+	rbNamespaces := []string{namespace}
 	for _, target := range targets {
-		log.Debugf("Deleting rolebinding %s, namespace %s", podName, target)
-		err = k8scli.DeleteRoleBinding(podName, target)
-		if err != nil {
-			log.Error("Something went wrong trying to destroy the rolebinding!")
-			return
+		// The rolebinding was only created once when target and namespace are equal.
+		if target != namespace {
+			rbNamespaces = append(rbNamespaces, target)
 		}
-		log.Infof("Successfully deleted rolebinding %s, namespace %s", podName, target)
 	}
-
-	if !isNamespaceInTargets(namespace, targets) {
-		policies, err := k8scli.Client.NetworkingV1().NetworkPolicies(targets[0]).List(metav1.ListOptions{})
-		if err != nil {
-			log.Errorf("Something went wrong trying to determine if we have network policies! - %v", err)
-			return
+	for _, ns := range rbNamespaces {
+		log.Debugf("Deleting rolebinding %s, namespace %s", podName, ns)
+		err = k8scli.DeleteRoleBinding(podName, ns)
+		if err != nil && !kapierrors.IsNotFound(err) {
+			destroyErr.add(fmt.Errorf("unable to delete rolebinding %s in namespace %s - %v", podName, ns, err),
+				SandboxResource{Kind: "RoleBinding", Name: podName, Namespace: ns})
+			continue
 		}
+		log.Infof("Successfully deleted rolebinding %s, namespace %s", podName, ns)
+	}
 
-		// If there are already network policies, we need to clean up the ones we
-		// created to allow communication from the APB pod to the target namespace.
-		if len(policies.Items) > 0 {
-			log.Debugf("Deleting network policy for pod: %v to grant network access to ns: %v", podName, targets[0])
-			// Must clean up the network policy that allowed communication from the APB pod to the target namespace.
-			err = k8scli.Client.NetworkingV1().NetworkPolicies(targets[0]).Delete(podName, &metav1.DeleteOptions{})
-			if err != nil {
-				log.Errorf("unable to delete the network policy object - %v", err)
-				return
+	if !isNamespaceInTargets(namespace, targets) {
+		// Must clean up the network policies that allowed communication from
+		// the APB pod to the target namespaces, they only exist if the target
+		// already had network policies.
+		for _, target := range targets {
+			log.Debugf("Deleting network policy for pod: %v to grant network access to ns: %v", podName, target)
+			err = k8scli.Client.NetworkingV1().NetworkPolicies(target).Delete(podName, &metav1.DeleteOptions{})
+			if err != nil && !kapierrors.IsNotFound(err) {
+				destroyErr.add(fmt.Errorf("unable to delete the network policy object in %v - %v", target, err),
+					SandboxResource{Kind: "NetworkPolicy", Name: podName, Namespace: target})
 			}
-			log.Debugf("Successfully deleted network policy for pod: %v to grant network access to ns: %v", podName, targets[0])
 		}
 	}
 
 	metrics.SandboxDeleted()
 
 	log.Debugf("Running post sandbox destroy hooks")
-	for i, f := range p.postSandboxDestroy {
-		log.Debugf("Running post sandbox destroy:  %v", i+1)
-		err := f(podName, namespace, targets)
-		if err != nil {
-			// Log the error and continue processing hooks. Expect hook to
-			// clean up after itself.
-			log.Warningf("Post destroy sandbox function failed with err: %v", err)
+	if err := runSandboxHooks("post destroy sandbox", p.postSandboxDestroy, ctx); err != nil {
+		destroyErr.add(err)
+	}
+
+	if err := destroyErr.errOrNil(); err != nil {
+		log.Errorf("Sandbox [ %s ] in namespace [ %s ] was not fully destroyed - %v", podName, namespace, err)
+		for _, r := range destroyErr.Leftovers {
+			log.Warningf("Leftover sandbox resource %s %s/%s", r.Kind, r.Namespace, r.Name)
 		}
+		return err
 	}
-	return
+	return nil
 }
 
 // GetRuntime - Return a string value of the runtime
 func (p provider) GetRuntime() string {
-	return p.coe.getRuntime()
+	return p.platform.runtimeName()
 }
 
+// WatchRunningBundle - Watches the bundle pod until completion and runs the
+// post run bundle hooks. A failing required hook fails the action.
 func (p provider) WatchRunningBundle(podName string, namespace string, updateFunc UpdateDescriptionFn) error {
-	return p.watchBundle(podName, namespace, updateFunc)
+	err := p.watchBundle(podName, namespace, updateFunc)
+	return p.bundleFinished(podName, namespace, err)
+}
+
+// bundleFinished - runs the post run bundle hooks once the run of the
+// bundle is over, err is the error of the run. Returns the error of the run
+// or of a failing required hook.
+func (p provider) bundleFinished(podName string, namespace string, err error) error {
+	ctx := p.sandboxes.get(podName, namespace, nil)
+	ctx.Err = err
+	if hookErr := runSandboxHooks("post run bundle", p.postRunBundle, ctx); hookErr != nil && err == nil {
+		return hookErr
+	}
+	return err
 }
 
 func (p provider) CopySecretsToNamespace(ec ExecutionContext, cn string, secrets []string) error {
 	return p.copySecretsToNamespace(ec, cn, secrets)
 }
 
+// RunBundle - Runs the pre run bundle hooks and then the bundle. A failing
+// required hook stops the bundle from being run.
 func (p provider) RunBundle(ec ExecutionContext) (ExecutionContext, error) {
-	return p.runBundle(ec)
+	ctx := p.sandboxes.get(ec.BundleName, ec.Location, ec.Targets)
+	ctx.Image = ec.Image
+	if ctx.Action == "" {
+		ctx.Action = ec.Action
+	}
+	if err := runSandboxHooks("pre run bundle", p.preRunBundle, ctx); err != nil {
+		return ec, err
+	}
+	if ec.PodSettings == nil {
+		settings := p.platform.PodSettings
+		ec.PodSettings = &settings
+	}
+	// A runtime 1 bundle keeps running until its credentials are extracted,
+	// its writable state could not be collected before that.
+	if p.writable && ec.Runtime != 1 {
+		ec.StateWritable = true
+		if ec.StateLocation == "" {
+			ec.StateLocation = p.MountLocation()
+		}
+	}
+	ec, err := p.runBundle(ec)
+	if err == nil && ec.StateWritable {
+		p.collections.start(ec.BundleName, ec.Location)
+	}
+	return ec, err
 }
 
 func shouldDeleteNamespace(keepNamespace bool,
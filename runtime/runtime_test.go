@@ -28,6 +28,7 @@ import (
 	"github.com/automationbroker/bundle-lib/runtime/mocks"
 	apicorev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
@@ -60,12 +61,18 @@ func (f fakeCoreV1) RESTClient() rest.Interface {
 	return f.Interface
 }
 
-func sandboxCreateHook(pod, ns string, targetNS []string, role string) error {
-	return nil
+var sandboxCreateHook = SandboxHook{
+	Name: "create hook",
+	Run: func(ctx *SandboxContext) error {
+		return nil
+	},
 }
 
-func sandboxDestroyHook(pod, ns string, targetNS []string) error {
-	return nil
+var sandboxDestroyHook = SandboxHook{
+	Name: "destroy hook",
+	Run: func(ctx *SandboxContext) error {
+		return nil
+	},
 }
 
 func newRunBundle(ex ExecutionContext) (ExecutionContext, error) {
@@ -260,7 +267,7 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newOpenshift(),
+				platform:               openshiftPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
@@ -277,7 +284,7 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newKubernetes(),
+				platform:               kubernetesPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
@@ -294,7 +301,7 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newKubernetes(),
+				platform:               kubernetesPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
@@ -311,7 +318,7 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newKubernetes(),
+				platform:               kubernetesPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
@@ -329,7 +336,7 @@ func TestNewRuntime(t *testing.T) {
 			shouldPanic: true,
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newKubernetes(),
+				platform:               kubernetesPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
@@ -348,7 +355,7 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newOpenshift(),
+				platform:               openshiftPlatform,
 				ExtractedCredential:    &mocks.ExtractedCredential{},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
@@ -358,8 +365,8 @@ func TestNewRuntime(t *testing.T) {
 		{
 			name: "New Default Openshift Runtime with pre sandbox hooks",
 			config: Configuration{
-				PreCreateSandboxHooks:  []PreSandboxCreate{sandboxCreateHook},
-				PreDestroySandboxHooks: []PreSandboxDestroy{sandboxDestroyHook},
+				PreCreateSandboxHooks:  []SandboxHook{sandboxCreateHook},
+				PreDestroySandboxHooks: []SandboxHook{sandboxDestroyHook},
 			},
 			client: fake.NewSimpleClientset(),
 			response: &http.Response{
@@ -368,10 +375,10 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newOpenshift(),
+				platform:               openshiftPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
-				preSandboxCreate:       []PreSandboxCreate{sandboxCreateHook},
-				preSandboxDestroy:      []PreSandboxDestroy{sandboxDestroyHook},
+				preSandboxCreate:       []SandboxHook{sandboxCreateHook},
+				preSandboxDestroy:      []SandboxHook{sandboxDestroyHook},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
 				copySecretsToNamespace: defaultCopySecretsToNamespace,
@@ -380,8 +387,8 @@ func TestNewRuntime(t *testing.T) {
 		{
 			name: "New Default Openshift Runtime with pre sandbox hooks",
 			config: Configuration{
-				PostCreateSandboxHooks:  []PostSandboxCreate{sandboxCreateHook},
-				PostDestroySandboxHooks: []PostSandboxDestroy{sandboxDestroyHook},
+				PostCreateSandboxHooks:  []SandboxHook{sandboxCreateHook},
+				PostDestroySandboxHooks: []SandboxHook{sandboxDestroyHook},
 			},
 			client: fake.NewSimpleClientset(),
 			response: &http.Response{
@@ -390,10 +397,10 @@ func TestNewRuntime(t *testing.T) {
 			},
 			expectedProvider: &provider{
 				state:                  stateManager,
-				coe:                    newOpenshift(),
+				platform:               openshiftPlatform,
 				ExtractedCredential:    defaultExtractedCredential{},
-				postSandboxCreate:      []PostSandboxCreate{sandboxCreateHook},
-				postSandboxDestroy:     []PostSandboxDestroy{sandboxDestroyHook},
+				postSandboxCreate:      []SandboxHook{sandboxCreateHook},
+				postSandboxDestroy:     []SandboxHook{sandboxDestroyHook},
 				watchBundle:            defaultWatchRunningBundle,
 				runBundle:              defaultRunBundle,
 				copySecretsToNamespace: defaultCopySecretsToNamespace,
@@ -410,7 +417,7 @@ func TestNewRuntime(t *testing.T) {
 				Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"major":"3", "minor": "2"}`))),
 			},
 			expectedProvider: &provider{
-				coe:                 newOpenshift(),
+				platform:            openshiftPlatform,
 				ExtractedCredential: defaultExtractedCredential{},
 			},
 		},
@@ -425,7 +432,7 @@ func TestNewRuntime(t *testing.T) {
 				Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"major":"3", "minor": "2"}`))),
 			},
 			expectedProvider: &provider{
-				coe:                 newOpenshift(),
+				platform:            openshiftPlatform,
 				ExtractedCredential: defaultExtractedCredential{},
 			},
 		},
@@ -440,7 +447,7 @@ func TestNewRuntime(t *testing.T) {
 				Body:       ioutil.NopCloser(bytes.NewReader([]byte(`{"major":"3", "minor": "2"}`))),
 			},
 			expectedProvider: &provider{
-				coe:                 newOpenshift(),
+				platform:            openshiftPlatform,
 				ExtractedCredential: defaultExtractedCredential{},
 			},
 		},
@@ -481,7 +488,7 @@ func TestNewRuntime(t *testing.T) {
 			if len(p.postSandboxCreate) != len(tc.expectedProvider.postSandboxCreate) {
 				t.Fatalf("invalid provider for configuration: %#+v \n\n got: %#+v \n\n exp: %#+v", tc.config, Provider, tc.expectedProvider)
 			}
-			if !reflect.DeepEqual(tc.expectedProvider.coe, p.coe) {
+			if tc.expectedProvider.platform.Name != p.platform.Name {
 				t.Fatalf("invalid provider for configuration: %#+v \n\n got: %#+v \n\n exp: %#+v", tc.config, Provider, tc.expectedProvider)
 			}
 			if !reflect.DeepEqual(tc.expectedProvider.ExtractedCredential, p.ExtractedCredential) {
@@ -491,3 +498,133 @@ func TestNewRuntime(t *testing.T) {
 		})
 	}
 }
+
+func TestDestroySandbox(t *testing.T) {
+	failingResource := SandboxResource{
+		Kind: "Custom",
+		Name: "leftover",
+		Cleanup: func() error {
+			return fmt.Errorf("cleanup failed")
+		},
+	}
+	testCases := []struct {
+		name            string
+		namespace       string
+		configNamespace string
+		resources       []SandboxResource
+		shouldError     bool
+		leftovers       int
+	}{
+		{
+			name:            "destroy sandbox",
+			namespace:       "sandbox",
+			configNamespace: "ansible-service-broker",
+		},
+		{
+			name:            "broker namespace is not deleted",
+			namespace:       "ansible-service-broker",
+			configNamespace: "ansible-service-broker",
+			shouldError:     true,
+		},
+		{
+			name:            "failing resource cleanup is reported as leftover",
+			namespace:       "sandbox",
+			configNamespace: "ansible-service-broker",
+			resources:       []SandboxResource{failingResource},
+			shouldError:     true,
+			leftovers:       1,
+		},
+	}
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("test panic unexpectedly: %#+v", r)
+				}
+			}()
+			k.Client = fake.NewSimpleClientset(&apicorev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: tc.namespace},
+			})
+			p := provider{sandboxes: newSandboxRegistry()}
+			ctx := newSandboxContext("pod", tc.namespace, []string{"target"}, "edit", map[string]string{})
+			for _, r := range tc.resources {
+				ctx.AddResource(r)
+			}
+			p.sandboxes.add(ctx)
+
+			err := p.DestroySandbox("pod", tc.namespace, []string{"target"}, tc.configNamespace, false, false)
+			if tc.shouldError != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.shouldError && !IsSandboxDestroyError(err) {
+				t.Fatalf("expected a sandbox destroy error got: %v", err)
+			}
+			if len(SandboxLeftovers(err)) != tc.leftovers {
+				t.Fatalf("expected %v leftovers got: %v", tc.leftovers, SandboxLeftovers(err))
+			}
+		})
+	}
+}
+
+func TestCreateSandboxFailureRemovesNamespace(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	client := fake.NewSimpleClientset(&apicorev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "target"},
+	})
+	client.PrependReactor("create", "namespaces", func(action clientgotesting.Action) (bool, k8sruntime.Object, error) {
+		ns := action.(clientgotesting.CreateAction).GetObject().(*apicorev1.Namespace)
+		if ns.Name == "" {
+			ns.Name = ns.GenerateName
+		}
+		return false, ns, nil
+	})
+	client.PrependReactor("create", "rolebindings", func(action clientgotesting.Action) (bool, k8sruntime.Object, error) {
+		return true, nil, fmt.Errorf("rolebinding failed")
+	})
+	k.Client = client
+
+	p := provider{sandboxes: newSandboxRegistry()}
+	_, _, err = p.CreateSandbox("pod", "sandbox", []string{"target"}, "edit", map[string]string{})
+	if err == nil {
+		t.Fatal("expected the sandbox creation to fail")
+	}
+	_, err = k.Client.CoreV1().Namespaces().Get("sandbox", metav1.GetOptions{})
+	if !kapierrors.IsNotFound(err) {
+		t.Fatalf("expected the sandbox namespace to be deleted got: %v", err)
+	}
+	_, err = k.Client.CoreV1().Namespaces().Get("target", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the target namespace to be kept got: %v", err)
+	}
+}
+
+func TestCreateTargetNetworkPolicy(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset(&networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "app"},
+	})
+	expected := map[string]int{"app": 2, "data": 0}
+	for target, count := range expected {
+		if err := createTargetNetworkPolicy("pod-name", target); err != nil {
+			t.Fatalf("unable to create network policy in %v: %v", target, err)
+		}
+		list, err := k.Client.NetworkingV1().NetworkPolicies(target).List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("Failed to get list of network policies: %v", err)
+		}
+		if len(list.Items) != count {
+			t.Fatalf("expected %v network policies in %v got %v", count, target, len(list.Items))
+		}
+	}
+}
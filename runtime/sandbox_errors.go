@@ -0,0 +1,65 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// SandboxDestroyError - The errors that occurred while destroying a sandbox
+// and the resources that were left behind because of them.
+type SandboxDestroyError struct {
+	Errors []error
+	// Leftovers - resources that could not be deleted, these can be handed
+	// to DeleteSandboxResource by a garbage collector.
+	Leftovers []SandboxResource
+}
+
+func (e *SandboxDestroyError) Error() string {
+	return fmt.Sprintf("unable to destroy sandbox: %v", utilerrors.NewAggregate(e.Errors))
+}
+
+func (e *SandboxDestroyError) add(err error, leftovers ...SandboxResource) {
+	e.Errors = append(e.Errors, err)
+	e.Leftovers = append(e.Leftovers, leftovers...)
+}
+
+// errOrNil - returns nil when nothing went wrong so that callers can compare
+// the result to nil.
+func (e *SandboxDestroyError) errOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// IsSandboxDestroyError - true if the error was returned by a sandbox
+// teardown that did not complete.
+func IsSandboxDestroyError(err error) bool {
+	_, ok := err.(*SandboxDestroyError)
+	return ok
+}
+
+// SandboxLeftovers - returns the resources a sandbox teardown left behind.
+func SandboxLeftovers(err error) []SandboxResource {
+	if e, ok := err.(*SandboxDestroyError); ok {
+		return e.Leftovers
+	}
+	return nil
+}
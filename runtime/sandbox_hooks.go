@@ -16,72 +16,269 @@
 
 package runtime
 
-// PreSandboxCreate - The pre sand box creation function will be called
-// before the sandbox is created for the bundle. This function should not expect
-// to panic and should fail gracefully by bubbling up the error and cleaning up
-// after itself.
-// Parameters in order of adding to the function.
-// string - pod name is also the svc accounts name.
-// string - namespace of the transient namespace.
-// list of strings - target namespaces.
-// string - abp role.
-// return error.
-type PreSandboxCreate func(string, string, []string, string) error
-
-// AddPostCreateSandbox - Adds a post create sandbox function to the runtime.
-// Once the sandbox is created all of the functions that have been added here
-// will be executed.
-func (p *provider) addPreCreateSandbox(f PreSandboxCreate) {
-	p.preSandboxCreate = append(p.preSandboxCreate, f)
-}
-
-// PostSandboxCreate - The post sand box creation function will be called
-// after the sandbox is created for the APB. This function should not expect
-// to panic and should fail gracefully by bubbling up the error and cleaning up
-// after itself.
-// Parameters in order of adding to the function.
-// string - pod name is also the svc accounts name.
-// string - namespace of the transient namespace.
-// list of strings - target namespaces.
-// string - abp role.
-// return error.
-type PostSandboxCreate func(string, string, []string, string) error
-
-// AddPostCreateSandbox - Adds a post create sandbox function to the runtime.
-// Once the sandbox is created all of the functions that have been added here
+import (
+	"fmt"
+	"sync"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// FQNameLabel - label holding the fully qualified name of the bundle.
+	FQNameLabel = "bundle-fqname"
+	// ActionLabel - label holding the action the bundle is running.
+	ActionLabel = "bundle-action"
+	// PodNameLabel - label holding the name of the bundle pod.
+	PodNameLabel = "bundle-pod-name"
+	// InstanceIDLabel - label holding the id of the service instance.
+	InstanceIDLabel = "bundle-instance-id"
+	// SpecIDLabel - label holding the id of the spec of the service instance.
+	SpecIDLabel = "bundle-spec-id"
+)
+
+// SandboxContext - Information about a sandbox that is handed to the sandbox
+// and bundle hooks.
+type SandboxContext struct {
+	// PodName - name of the bundle pod, this is also the service account name.
+	PodName string
+	// Namespace - namespace the bundle pod runs in.
+	Namespace string
+	// Targets - namespaces the bundle is given access to.
+	Targets []string
+	// Role - cluster role the service account is bound to.
+	Role string
+	// Action - the action the bundle is running.
+	Action string
+	// InstanceID - id of the service instance the action is run for.
+	InstanceID string
+	// SpecID - id of the spec of the service instance.
+	SpecID string
+	// FQName - fully qualified name of the bundle.
+	FQName string
+	// Image - the bundle image, only known once the bundle is run.
+	Image string
+	// Labels - labels that are applied to the sandbox and the bundle pod.
+	Labels map[string]string
+	// Err - error the bundle finished with, only set for the post run
+	// bundle hooks.
+	Err error
+
+	mutex     sync.Mutex
+	resources []SandboxResource
+}
+
+// SandboxResource - A resource created by a hook that the runtime will
+// delete when the sandbox is destroyed.
+type SandboxResource struct {
+	// Kind - ConfigMap, Secret, Service, ServiceAccount, RoleBinding,
+	// NetworkPolicy or Namespace. Any other kind must provide a Cleanup
+	// function.
+	Kind      string
+	Name      string
+	Namespace string
+	// Cleanup - if defined this is called instead of deleting the resource
+	// by kind.
+	Cleanup func() error
+}
+
+// AddResource - Attach a resource to the sandbox so that it is torn down
+// with the sandbox.
+func (s *SandboxContext) AddResource(r SandboxResource) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.resources = append(s.resources, r)
+}
+
+// Resources - Returns the resources attached to the sandbox.
+func (s *SandboxContext) Resources() []SandboxResource {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]SandboxResource{}, s.resources...)
+}
+
+func newSandboxContext(podName, namespace string, targets []string, role string, labels map[string]string) *SandboxContext {
+	return &SandboxContext{
+		PodName:    podName,
+		Namespace:  namespace,
+		Targets:    targets,
+		Role:       role,
+		Action:     labels[ActionLabel],
+		InstanceID: labels[InstanceIDLabel],
+		SpecID:     labels[SpecIDLabel],
+		FQName:     labels[FQNameLabel],
+		Labels:     labels,
+	}
+}
+
+// SandboxHookFunc - The function run by a sandbox hook. The function should
+// not panic and should fail gracefully by bubbling up the error and cleaning
+// up after itself. Anything the function creates that should live as long as
+// the sandbox can be attached with SandboxContext.AddResource.
+type SandboxHookFunc func(*SandboxContext) error
+
+// SandboxHook - A hook that is run at a point in the lifecycle of the sandbox
+// or the bundle pod.
+type SandboxHook struct {
+	// Name - used when reporting the hook.
+	Name string
+	// Required - when true a failure of the hook aborts the action and the
+	// sandbox is cleaned up. Otherwise the failure is logged and ignored.
+	Required bool
+	// Run - the function to run.
+	Run SandboxHookFunc
+}
+
+func (h SandboxHook) name(i int) string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return fmt.Sprintf("%v", i+1)
+}
+
+// runSandboxHooks - Runs the hooks in order. Failures of hooks that are not
+// required are logged and ignored, the first failure of a required hook stops
+// processing and is returned.
+func runSandboxHooks(stage string, hooks []SandboxHook, ctx *SandboxContext) error {
+	for i, h := range hooks {
+		if h.Run == nil {
+			continue
+		}
+		log.Debugf("Running %s hook: %v", stage, h.name(i))
+		err := h.Run(ctx)
+		if err == nil {
+			continue
+		}
+		if h.Required {
+			log.Errorf("Required %s hook %v failed with err: %v", stage, h.name(i), err)
+			return fmt.Errorf("required %s hook %v failed - %v", stage, h.name(i), err)
+		}
+		// Log the error and continue processing hooks. Expect hook to
+		// clean up after itself.
+		log.Warningf("%s hook %v failed with err: %v", stage, h.name(i), err)
+	}
+	return nil
+}
+
+// addPreCreateSandbox - Adds a pre create sandbox hook to the runtime. The
+// hooks are run once the namespace has been created and before the service
+// account and rolebindings are.
+func (p *provider) addPreCreateSandbox(h SandboxHook) {
+	p.preSandboxCreate = append(p.preSandboxCreate, h)
+}
+
+// addPostCreateSandbox - Adds a post create sandbox hook to the runtime.
+// Once the sandbox is created all of the hooks that have been added here
 // will be executed.
-func (p *provider) addPostCreateSandbox(f PostSandboxCreate) {
-	p.postSandboxCreate = append(p.postSandboxCreate, f)
-}
-
-// PreSandboxDestroy - The pre sand box destroy function will be called
-// before the sandbox is destroyed. This could mean the namespace is kept around
-// if the apb failed and configuration conditions are met. This function should not
-// expect to panic and should fail gracefully by bubbling up the error. This
-// function should also not delete the namespace or the pod directly. This
-// will most likely be used to clean up resources in pre/post create sandbox hooks.
-// Parameters:
-// string - pod / svc-account name
-// string - pod transient namespace
-// []string - target namespaces
-type PreSandboxDestroy func(string, string, []string) error
-
-func (p *provider) addPreDestroySandbox(f PreSandboxDestroy) {
-	p.preSandboxDestroy = append(p.preSandboxDestroy, f)
-}
-
-// PostSandboxDestroy - The post sand box destroy function will be called
-// after the sandbox is destroyed. This could mean the namespace is kept around
-// if the apb failed and configuration conditions are met. This function should not
-// expect to panic and should fail gracefully by bubbling up the error. This
-// function should also not delete the namespace or the pod directly. This
-// will most likely be used to clean up resources in pre/post create sandbox hooks.
-// Parameters:
-// string - pod / svc-account name
-// string - pod transient namespace
-// []string - target namespaces
-type PostSandboxDestroy func(string, string, []string) error
-
-func (p *provider) addPostDestroySandbox(f PostSandboxDestroy) {
-	p.postSandboxDestroy = append(p.postSandboxDestroy, f)
+func (p *provider) addPostCreateSandbox(h SandboxHook) {
+	p.postSandboxCreate = append(p.postSandboxCreate, h)
+}
+
+// addPreDestroySandbox - Adds a pre destroy sandbox hook to the runtime.
+// This will most likely be used to clean up resources in pre/post create
+// sandbox hooks. The hooks should not delete the namespace or the pod
+// directly.
+func (p *provider) addPreDestroySandbox(h SandboxHook) {
+	p.preSandboxDestroy = append(p.preSandboxDestroy, h)
+}
+
+// addPostDestroySandbox - Adds a post destroy sandbox hook to the runtime.
+// This could mean the namespace is kept around if the bundle failed and
+// configuration conditions are met.
+func (p *provider) addPostDestroySandbox(h SandboxHook) {
+	p.postSandboxDestroy = append(p.postSandboxDestroy, h)
+}
+
+// addPreRunBundle - Adds a hook that is run before the bundle pod is created.
+func (p *provider) addPreRunBundle(h SandboxHook) {
+	p.preRunBundle = append(p.preRunBundle, h)
+}
+
+// addPostRunBundle - Adds a hook that is run when the bundle pod completes.
+func (p *provider) addPostRunBundle(h SandboxHook) {
+	p.postRunBundle = append(p.postRunBundle, h)
+}
+
+// sandboxRegistry - keeps the context of the sandboxes that are alive so
+// that the hooks and the teardown share the same context.
+type sandboxRegistry struct {
+	mutex    sync.Mutex
+	contexts map[string]*SandboxContext
+}
+
+func newSandboxRegistry() *sandboxRegistry {
+	return &sandboxRegistry{contexts: map[string]*SandboxContext{}}
+}
+
+func sandboxKey(podName, namespace string) string {
+	return fmt.Sprintf("%s/%s", namespace, podName)
+}
+
+func (r *sandboxRegistry) add(ctx *SandboxContext) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.contexts[sandboxKey(ctx.PodName, ctx.Namespace)] = ctx
+}
+
+// get - returns the context of the sandbox, if the sandbox is unknown, for
+// example because the broker was restarted, a context is built from what
+// is known.
+func (r *sandboxRegistry) get(podName, namespace string, targets []string) *SandboxContext {
+	if r != nil {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if ctx, ok := r.contexts[sandboxKey(podName, namespace)]; ok {
+			return ctx
+		}
+	}
+	return newSandboxContext(podName, namespace, targets, "", map[string]string{})
+}
+
+func (r *sandboxRegistry) remove(podName, namespace string) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.contexts, sandboxKey(podName, namespace))
+}
+
+// DeleteSandboxResource - Deletes a resource attached to a sandbox. This can
+// be used to remove the leftovers reported by a SandboxDestroyError.
+func DeleteSandboxResource(r SandboxResource) error {
+	if r.Cleanup != nil {
+		return r.Cleanup()
+	}
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	opts := &metav1.DeleteOptions{}
+	switch r.Kind {
+	case "ConfigMap":
+		err = k8scli.Client.CoreV1().ConfigMaps(r.Namespace).Delete(r.Name, opts)
+	case "Secret":
+		err = k8scli.Client.CoreV1().Secrets(r.Namespace).Delete(r.Name, opts)
+	case "Service":
+		err = k8scli.Client.CoreV1().Services(r.Namespace).Delete(r.Name, opts)
+	case "ServiceAccount":
+		err = k8scli.Client.CoreV1().ServiceAccounts(r.Namespace).Delete(r.Name, opts)
+	case "RoleBinding":
+		err = k8scli.DeleteRoleBinding(r.Name, r.Namespace)
+	case "NetworkPolicy":
+		err = k8scli.Client.NetworkingV1().NetworkPolicies(r.Namespace).Delete(r.Name, opts)
+	case "Namespace":
+		err = k8scli.Client.CoreV1().Namespaces().Delete(r.Name, opts)
+	default:
+		return fmt.Errorf("unable to delete resource of kind %s without a cleanup function", r.Kind)
+	}
+	if err != nil && !kapierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
 }
@@ -16,14 +16,21 @@
 
 package runtime
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
-func createHook(p, n string, targets []string, role string) error {
-	return nil
+var createHook = SandboxHook{
+	Run: func(ctx *SandboxContext) error {
+		return nil
+	},
 }
 
-func destroyHook(p, n string, targets []string) error {
-	return nil
+var destroyHook = SandboxHook{
+	Run: func(ctx *SandboxContext) error {
+		return nil
+	},
 }
 
 func TestAddPreCreateSandbox(t *testing.T) {
@@ -58,3 +65,156 @@ func TestAddPostDestroySandbox(t *testing.T) {
 		t.Fatal("sandbox hooks was not added")
 	}
 }
+
+func TestAddPreRunBundle(t *testing.T) {
+	p := provider{}
+	p.addPreRunBundle(createHook)
+	if len(p.preRunBundle) == 0 {
+		t.Fatal("bundle hooks was not added")
+	}
+}
+
+func TestAddPostRunBundle(t *testing.T) {
+	p := provider{}
+	p.addPostRunBundle(createHook)
+	if len(p.postRunBundle) == 0 {
+		t.Fatal("bundle hooks was not added")
+	}
+}
+
+func TestBundleFinished(t *testing.T) {
+	runErr := fmt.Errorf("bundle failed")
+	var seen error
+	p := provider{}
+	p.addPostRunBundle(SandboxHook{Name: "required", Required: true, Run: func(ctx *SandboxContext) error {
+		seen = ctx.Err
+		return fmt.Errorf("hook failed")
+	}})
+
+	// a failing required hook fails a successful run
+	if err := p.bundleFinished("pod", "ns", nil); err == nil || seen != nil {
+		t.Fatalf("expected the hook to fail the run got: %v", err)
+	}
+	// the hook sees the error of the run, which is returned
+	if err := p.bundleFinished("pod", "ns", runErr); err != runErr || seen != runErr {
+		t.Fatalf("expected the error of the run got: %v", err)
+	}
+}
+
+func TestRunSandboxHooks(t *testing.T) {
+	failing := func(ctx *SandboxContext) error {
+		return fmt.Errorf("hook failed")
+	}
+	testCases := []struct {
+		name        string
+		hooks       []SandboxHook
+		expectedRun int
+		shouldError bool
+	}{
+		{
+			name:        "no hooks",
+			hooks:       []SandboxHook{},
+			expectedRun: 0,
+		},
+		{
+			name: "optional hook failure is ignored",
+			hooks: []SandboxHook{
+				SandboxHook{Name: "optional", Run: failing},
+				createHook,
+			},
+			expectedRun: 2,
+		},
+		{
+			name: "required hook failure stops processing",
+			hooks: []SandboxHook{
+				SandboxHook{Name: "required", Required: true, Run: failing},
+				createHook,
+			},
+			expectedRun: 1,
+			shouldError: true,
+		},
+		{
+			name: "hook without a function is skipped",
+			hooks: []SandboxHook{
+				SandboxHook{Name: "empty", Required: true},
+				createHook,
+			},
+			expectedRun: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			run := 0
+			hooks := []SandboxHook{}
+			for _, h := range tc.hooks {
+				if h.Run != nil {
+					f := h.Run
+					h.Run = func(ctx *SandboxContext) error {
+						run++
+						return f(ctx)
+					}
+				}
+				hooks = append(hooks, h)
+			}
+			err := runSandboxHooks("test", hooks, newSandboxContext("pod", "ns", []string{"target"}, "edit", nil))
+			if tc.shouldError != (err != nil) {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if run != tc.expectedRun {
+				t.Fatalf("expected %v hooks to run, ran %v", tc.expectedRun, run)
+			}
+		})
+	}
+}
+
+func TestSandboxContextResources(t *testing.T) {
+	labels := map[string]string{
+		ActionLabel:     "provision",
+		FQNameLabel:     "fq-name",
+		InstanceIDLabel: "instance-id",
+		SpecIDLabel:     "spec-id",
+	}
+	ctx := newSandboxContext("pod", "ns", []string{"target"}, "edit", labels)
+	if ctx.Action != "provision" || ctx.FQName != "fq-name" || ctx.InstanceID != "instance-id" || ctx.SpecID != "spec-id" {
+		t.Fatalf("context not built from labels: %#v", ctx)
+	}
+	cleaned := false
+	ctx.AddResource(SandboxResource{
+		Kind: "Custom",
+		Name: "custom",
+		Cleanup: func() error {
+			cleaned = true
+			return nil
+		},
+	})
+	if len(ctx.Resources()) != 1 {
+		t.Fatalf("expected 1 resource got %v", len(ctx.Resources()))
+	}
+	for _, r := range ctx.Resources() {
+		if err := DeleteSandboxResource(r); err != nil {
+			t.Fatalf("unable to delete resource: %v", err)
+		}
+	}
+	if !cleaned {
+		t.Fatal("resource cleanup was not called")
+	}
+}
+
+func TestSandboxRegistry(t *testing.T) {
+	r := newSandboxRegistry()
+	ctx := newSandboxContext("pod", "ns", []string{"target"}, "edit", nil)
+	r.add(ctx)
+	if r.get("pod", "ns", nil) != ctx {
+		t.Fatal("expected registered sandbox context")
+	}
+	r.remove("pod", "ns")
+	got := r.get("pod", "ns", []string{"target"})
+	if got == ctx || got.PodName != "pod" || len(got.Targets) != 1 {
+		t.Fatalf("expected a new sandbox context got: %#v", got)
+	}
+	var nilRegistry *sandboxRegistry
+	if nilRegistry.get("pod", "ns", nil) == nil {
+		t.Fatal("expected a context from a nil registry")
+	}
+}
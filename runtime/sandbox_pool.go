@@ -0,0 +1,265 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"github.com/automationbroker/bundle-lib/metrics"
+	log "github.com/sirupsen/logrus"
+	apicorev1 "k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SandboxPoolLabel - label put on the namespaces owned by the sandbox pool.
+	SandboxPoolLabel = "bundle-sandbox-pool"
+	// SandboxPoolServiceAccount - name of the service account created in
+	// every pooled sandbox.
+	SandboxPoolServiceAccount = "bundle-sandbox"
+
+	sandboxPoolReady      = "ready"
+	sandboxPoolClaimed    = "claimed"
+	defaultSandboxPoolPre = "bundle-sandbox-"
+)
+
+// SandboxPoolConfig - Configuration of the pool of pre-warmed sandboxes.
+type SandboxPoolConfig struct {
+	// Size - the number of ready sandboxes to keep, 0 disables the pool.
+	Size int
+	// Prefix - the prefix for the generated namespace names.
+	Prefix string
+}
+
+// sandboxPool - keeps a set of namespaces with a service account that are
+// ready to be claimed as the sandbox of an action.
+type sandboxPool struct {
+	config  SandboxPoolConfig
+	mutex   sync.Mutex
+	ready   []string
+	claimed map[string]bool
+	pending int
+}
+
+func newSandboxPool(config SandboxPoolConfig) *sandboxPool {
+	if config.Size <= 0 {
+		return nil
+	}
+	if config.Prefix == "" {
+		config.Prefix = defaultSandboxPoolPre
+	}
+	return &sandboxPool{config: config, claimed: map[string]bool{}}
+}
+
+// start - adopts the ready sandboxes left by a previous run, deletes the
+// ones it had claimed and fills the pool up to its size.
+func (s *sandboxPool) start() {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		log.Errorf("sandbox pool: unable to get kubernetes client - %v", err)
+		return
+	}
+	s.reap(k8scli)
+	selector := fmt.Sprintf("%s=%s", SandboxPoolLabel, sandboxPoolReady)
+	namespaces, err := k8scli.Client.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Errorf("sandbox pool: unable to list pooled namespaces - %v", err)
+	} else {
+		s.mutex.Lock()
+		for _, ns := range namespaces.Items {
+			s.ready = append(s.ready, ns.Name)
+		}
+		s.mutex.Unlock()
+		log.Infof("sandbox pool: adopted %v ready sandboxes", len(namespaces.Items))
+	}
+	s.fill()
+}
+
+// reap - deletes the sandboxes that were claimed by a previous run. Their
+// actions died with it and nothing else would ever tear them down.
+func (s *sandboxPool) reap(k8scli *clients.KubernetesClient) {
+	selector := fmt.Sprintf("%s=%s", SandboxPoolLabel, sandboxPoolClaimed)
+	namespaces, err := k8scli.Client.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		log.Errorf("sandbox pool: unable to list claimed namespaces - %v", err)
+		return
+	}
+	for _, ns := range namespaces.Items {
+		err = k8scli.Client.CoreV1().Namespaces().Delete(ns.Name, &metav1.DeleteOptions{})
+		if err != nil && !kapierrors.IsNotFound(err) {
+			log.Errorf("sandbox pool: unable to delete claimed sandbox %v - %v", ns.Name, err)
+			continue
+		}
+		log.Infof("sandbox pool: deleted sandbox %v claimed by a previous run", ns.Name)
+	}
+}
+
+// fill - creates sandboxes until the pool has reached its size.
+func (s *sandboxPool) fill() {
+	for {
+		s.mutex.Lock()
+		if len(s.ready)+s.pending >= s.config.Size {
+			metrics.SandboxPoolSize(len(s.ready))
+			s.mutex.Unlock()
+			return
+		}
+		s.pending++
+		s.mutex.Unlock()
+
+		ns, err := s.create()
+
+		s.mutex.Lock()
+		s.pending--
+		if err == nil {
+			s.ready = append(s.ready, ns)
+		}
+		s.mutex.Unlock()
+		if err != nil {
+			log.Errorf("sandbox pool: unable to create sandbox - %v", err)
+			return
+		}
+	}
+}
+
+func (s *sandboxPool) create() (string, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return "", err
+	}
+	ns := &apicorev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:       map[string]string{SandboxPoolLabel: sandboxPoolReady},
+			GenerateName: s.config.Prefix,
+		},
+	}
+	ns, err = k8scli.Client.CoreV1().Namespaces().Create(ns)
+	if err != nil {
+		return "", err
+	}
+	err = k8scli.CreateServiceAccount(SandboxPoolServiceAccount, ns.Name)
+	if err != nil {
+		k8scli.Client.CoreV1().Namespaces().Delete(ns.Name, &metav1.DeleteOptions{})
+		return "", err
+	}
+	log.Debugf("sandbox pool: created sandbox %v", ns.Name)
+	return ns.Name, nil
+}
+
+// claim - takes a ready sandbox out of the pool and labels it for the
+// action. Returns false when the pool has no ready sandbox.
+func (s *sandboxPool) claim(labels map[string]string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mutex.Lock()
+	if len(s.ready) == 0 {
+		s.mutex.Unlock()
+		metrics.SandboxPoolMiss()
+		go s.fill()
+		return "", false
+	}
+	ns := s.ready[0]
+	s.ready = s.ready[1:]
+	s.claimed[ns] = true
+	s.mutex.Unlock()
+	go s.fill()
+
+	if err := setSandboxPoolLabels(ns, sandboxPoolClaimed, labels); err != nil {
+		log.Errorf("sandbox pool: unable to claim sandbox %v - %v", ns, err)
+		s.discard(ns)
+		metrics.SandboxPoolMiss()
+		return "", false
+	}
+	metrics.SandboxPoolHit()
+	log.Debugf("sandbox pool: claimed sandbox %v", ns)
+	return ns, true
+}
+
+// owns - true if the namespace is a claimed sandbox of the pool.
+func (s *sandboxPool) owns(ns string) bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.claimed[ns]
+}
+
+// release - hands a claimed sandbox back to the pool. The sandbox is deleted
+// and replaced, an action can leave any kind of resource behind and the next
+// action, possibly of another tenant, must not inherit them.
+func (s *sandboxPool) release(ns string) {
+	s.discard(ns)
+}
+
+// keep - removes a claimed sandbox from the pool without deleting it, this
+// is used when the namespace is kept around for debugging.
+func (s *sandboxPool) keep(ns string) {
+	s.mutex.Lock()
+	delete(s.claimed, ns)
+	s.mutex.Unlock()
+	if err := setSandboxPoolLabels(ns, "", nil); err != nil {
+		log.Warningf("sandbox pool: unable to remove pool label from %v - %v", ns, err)
+	}
+	go s.fill()
+}
+
+// discard - deletes a sandbox and replaces it.
+func (s *sandboxPool) discard(ns string) {
+	s.mutex.Lock()
+	delete(s.claimed, ns)
+	s.mutex.Unlock()
+	k8scli, err := clients.Kubernetes()
+	if err == nil {
+		err = k8scli.Client.CoreV1().Namespaces().Delete(ns, &metav1.DeleteOptions{})
+	}
+	if err != nil {
+		log.Errorf("sandbox pool: unable to delete sandbox %v - %v", ns, err)
+	}
+	go s.fill()
+}
+
+// setSandboxPoolLabels - replaces the labels of the namespace with the
+// labels given and the pool state. Nil labels keep the current labels of the
+// namespace and an empty state removes the pool label.
+func setSandboxPoolLabels(ns, poolState string, labels map[string]string) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	namespace, err := k8scli.Client.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if labels == nil {
+		labels = namespace.Labels
+	}
+	newLabels := map[string]string{}
+	for k, v := range labels {
+		newLabels[k] = v
+	}
+	delete(newLabels, SandboxPoolLabel)
+	if poolState != "" {
+		newLabels[SandboxPoolLabel] = poolState
+	}
+	namespace.Labels = newLabels
+	_, err = k8scli.Client.CoreV1().Namespaces().Update(namespace)
+	return err
+}
@@ -0,0 +1,173 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNewSandboxPool(t *testing.T) {
+	if newSandboxPool(SandboxPoolConfig{}) != nil {
+		t.Fatal("expected the pool to be disabled")
+	}
+	var disabled *sandboxPool
+	if _, ok := disabled.claim(nil); ok {
+		t.Fatal("claimed a sandbox from a disabled pool")
+	}
+	if disabled.owns("ns") {
+		t.Fatal("disabled pool should not own any namespace")
+	}
+	s := newSandboxPool(SandboxPoolConfig{Size: 2})
+	if s == nil || s.config.Prefix != defaultSandboxPoolPre {
+		t.Fatalf("expected pool with default prefix got: %#v", s)
+	}
+}
+
+func TestSandboxPool(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	pooledNamespace := func() *fake.Clientset {
+		return fake.NewSimpleClientset(&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "pooled",
+				Labels: map[string]string{SandboxPoolLabel: sandboxPoolReady},
+			},
+		})
+	}
+	testCases := []struct {
+		name     string
+		client   *fake.Clientset
+		ready    []string
+		validate func(*sandboxPool) bool
+	}{
+		{
+			name:   "claim from empty pool",
+			client: fake.NewSimpleClientset(),
+			validate: func(s *sandboxPool) bool {
+				_, ok := s.claim(map[string]string{"bundle-action": "provision"})
+				return !ok
+			},
+		},
+		{
+			name:   "claim labels the namespace",
+			client: pooledNamespace(),
+			ready:  []string{"pooled"},
+			validate: func(s *sandboxPool) bool {
+				ns, ok := s.claim(map[string]string{"bundle-action": "provision"})
+				if !ok || ns != "pooled" || !s.owns("pooled") {
+					return false
+				}
+				namespace, err := k.Client.CoreV1().Namespaces().Get("pooled", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return namespace.Labels[SandboxPoolLabel] == sandboxPoolClaimed &&
+					namespace.Labels["bundle-action"] == "provision"
+			},
+		},
+		{
+			name:   "keep removes the namespace from the pool",
+			client: pooledNamespace(),
+			ready:  []string{"pooled"},
+			validate: func(s *sandboxPool) bool {
+				if _, ok := s.claim(map[string]string{"bundle-action": "provision"}); !ok {
+					return false
+				}
+				s.keep("pooled")
+				if s.owns("pooled") {
+					return false
+				}
+				namespace, err := k.Client.CoreV1().Namespaces().Get("pooled", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, pooled := namespace.Labels[SandboxPoolLabel]
+				return !pooled && namespace.Labels["bundle-action"] == "provision"
+			},
+		},
+		{
+			name:   "release deletes the namespace",
+			client: pooledNamespace(),
+			ready:  []string{"pooled"},
+			validate: func(s *sandboxPool) bool {
+				if _, ok := s.claim(nil); !ok {
+					return false
+				}
+				s.release("pooled")
+				if s.owns("pooled") {
+					return false
+				}
+				_, err := k.Client.CoreV1().Namespaces().Get("pooled", metav1.GetOptions{})
+				return kerror.IsNotFound(err)
+			},
+		},
+		{
+			name:   "released sandbox is not claimed again",
+			client: pooledNamespace(),
+			ready:  []string{"pooled"},
+			validate: func(s *sandboxPool) bool {
+				if _, ok := s.claim(nil); !ok {
+					return false
+				}
+				s.release("pooled")
+				ns, ok := s.claim(nil)
+				return !ok && ns == "" && len(s.ready) == 0
+			},
+		},
+		{
+			name: "reap deletes the sandboxes claimed by a previous run",
+			client: fake.NewSimpleClientset(
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "pooled",
+					Labels: map[string]string{SandboxPoolLabel: sandboxPoolReady},
+				}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "leftover",
+					Labels: map[string]string{SandboxPoolLabel: sandboxPoolClaimed},
+				}},
+			),
+			validate: func(s *sandboxPool) bool {
+				s.reap(k)
+				_, err := k.Client.CoreV1().Namespaces().Get("leftover", metav1.GetOptions{})
+				if !kerror.IsNotFound(err) {
+					return false
+				}
+				_, err = k.Client.CoreV1().Namespaces().Get("pooled", metav1.GetOptions{})
+				return err == nil
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k.Client = tc.client
+			// A size of 0 keeps the pool from creating sandboxes in the background.
+			s := &sandboxPool{ready: tc.ready, claimed: map[string]bool{}}
+			if !tc.validate(s) {
+				t.Fatal("validation failed")
+			}
+		})
+	}
+}
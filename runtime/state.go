@@ -18,9 +18,12 @@ package runtime
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 
 	"github.com/automationbroker/bundle-lib/clients"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -36,6 +39,24 @@ type state struct {
 	nsTarget string
 	// mountLocation is where in the pod the state will be mounted
 	mountLocation string
+	// history is the number of snapshots kept of every state, 0 disables
+	// the snapshots
+	history int
+	// store keeps the master state, the state is kept in configmaps in
+	// nsTarget when it is not set
+	store StateStore
+	// writable mounts the state writable in the bundle pods
+	writable bool
+	// collections are the writable states being collected
+	collections *stateCollections
+}
+
+// backend returns the store that keeps the master state
+func (s state) backend() StateStore {
+	if s.store == nil {
+		return NewConfigMapStateStore(s.nsTarget)
+	}
+	return s.store
 }
 
 // StateManager defines an interface for managing state created by service bundles
@@ -48,44 +69,140 @@ type StateManager interface {
 	MountLocation() string
 }
 
-// CopyState copies the state configmap from one namespace to another
+// CopyState copies the state from one namespace to another. The state in the
+// master namespace is kept by the state store. The state is copied into any
+// other namespace as secrets that are mounted into the bundle pod, the state
+// a bundle leaves behind is read from a configmap named after its pod.
 func (s state) CopyState(fromName, toName, fromNS, toNS string) error {
 	log.Debugf("state: copying state from namespace %s to ns %s from name %s to name %s", fromNS, toNS, fromName, toName)
-	k8s, err := clients.Kubernetes()
-	if err != nil {
+	// the state of a pod with a writable state is only there once collected
+	if err := s.collections.wait(fromName, fromNS); err != nil {
 		return err
 	}
-	fromClient := k8s.Client.CoreV1().ConfigMaps(fromNS)
-	toClient := k8s.Client.CoreV1().ConfigMaps(toNS)
-	fromMap, err := fromClient.Get(fromName, metav1.GetOptions{})
+	data, err := s.readState(fromName, fromNS)
 	if err != nil {
-		if kerror.IsNotFound(err) {
-			log.Debug("no state configmap found to copy")
+		if err == ErrStateNotFound {
+			log.Debug("no state found to copy")
 			// can't copy if there is nothing to copy
 			return nil
 		}
 		return err
 	}
-	toMap, err := toClient.Get(toName, metav1.GetOptions{})
+	if toNS == s.nsTarget {
+		obj, err := s.backend().Get(toName)
+		if err == ErrStateNotFound {
+			return s.backend().Put(&StateObject{Name: toName, Data: data})
+		}
+		if err != nil {
+			return err
+		}
+		if obj.Data == nil {
+			obj.Data = map[string]string{}
+		}
+		for k, v := range data {
+			obj.Data[k] = v
+		}
+		return s.backend().Put(obj)
+	}
+
+	return writeSandboxState(toName, toNS, data)
+}
+
+// writeSandboxState writes the state into secrets in the namespace of the
+// bundle pod, the state can hold credentials and is never copied into a
+// configmap. The files are split over as many secrets as it takes to keep
+// every secret below DefaultStateChunkSize, a single file is never split.
+// The first secret is named after the state and labeled with the number of
+// secrets, it is written last so that it only counts secrets that exist.
+func writeSandboxState(name, namespace string, data map[string]string) error {
+	store := NewSecretStateStore(namespace)
+	parts := splitState(data, DefaultStateChunkSize)
+	for i := len(parts) - 1; i >= 0; i-- {
+		obj := &StateObject{Name: sandboxStatePartName(name, i), Data: parts[i]}
+		if i == 0 {
+			obj.Labels = map[string]string{StateChunksLabel: strconv.Itoa(len(parts))}
+		}
+		if err := store.Put(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sandboxStatePartName returns the name of the secret holding a part of the
+// state in the namespace of the bundle pod
+func sandboxStatePartName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return stateChunkName(name, 0, i)
+}
+
+// splitState splits the files of the state into parts of at most size
+// bytes, a file larger than size gets a part of its own
+func splitState(data map[string]string, size int) []map[string]string {
+	keys := []string{}
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []map[string]string{{}}
+	used := 0
+	for _, k := range keys {
+		n := len(k) + len(data[k])
+		if used > 0 && used+n > size {
+			parts = append(parts, map[string]string{})
+			used = 0
+		}
+		parts[len(parts)-1][k] = data[k]
+		used += n
+	}
+	return parts
+}
+
+// sandboxStateVolumeSource returns the volume that mounts all parts of the
+// state copied into the namespace of the bundle pod
+func sandboxStateVolumeSource(name, namespace string) (v1.VolumeSource, error) {
+	obj, err := NewSecretStateStore(namespace).Get(name)
 	if err != nil {
-		if kerror.IsNotFound(err) {
-			fromMap.Namespace = toNS
-			fromMap.Name = toName
-			fromMap.ResourceVersion = ""
-			if _, err := toClient.Create(fromMap); err != nil {
-				return err
-			}
-			return nil
+		return v1.VolumeSource{}, err
+	}
+	parts, _ := stateChunks(obj)
+	if parts == 0 {
+		parts = 1
+	}
+	sources := []v1.VolumeProjection{}
+	for i := 0; i < parts; i++ {
+		sources = append(sources, v1.VolumeProjection{
+			Secret: &v1.SecretProjection{
+				LocalObjectReference: v1.LocalObjectReference{Name: sandboxStatePartName(name, i)},
+			},
+		})
+	}
+	return v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: sources}}, nil
+}
+
+// readState returns the data of the state, ErrStateNotFound if there is none
+func (s state) readState(name, namespace string) (map[string]string, error) {
+	if namespace == s.nsTarget {
+		obj, err := s.backend().Get(name)
+		if err != nil {
+			return nil, err
 		}
-		return err
+		return obj.Data, nil
 	}
-	for k, v := range fromMap.Data {
-		toMap.Data[k] = v
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
 	}
-	if _, err := toClient.Update(toMap); err != nil {
-		return err
+	cm, err := k8s.Client.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, ErrStateNotFound
+		}
+		return nil, err
 	}
-	return nil
+	return cm.Data, nil
 }
 
 // MasterName provides a consistent name for the state object in the master namespace
@@ -95,13 +212,8 @@ func (s state) MasterName(id string) string {
 
 // StateIsPresent checks to see is there an object carrying state for ServiceBundle
 func (s state) StateIsPresent(stateName string) (bool, error) {
-	k8s, err := clients.Kubernetes()
-	if err != nil {
-		return false, err
-	}
-	if _, err := k8s.Client.CoreV1().ConfigMaps(s.nsTarget).Get(stateName, metav1.GetOptions{}); err != nil {
-		fmt.Println("client returned err ", err)
-		if kerror.IsNotFound(err) {
+	if _, err := s.backend().Get(stateName); err != nil {
+		if err == ErrStateNotFound {
 			return false, nil
 		}
 		return false, err
@@ -112,18 +224,12 @@ func (s state) StateIsPresent(stateName string) (bool, error) {
 // DeleteState will remove the state object from the broker namespace
 func (s state) DeleteState(name string) error {
 	log.Debugf("state: deleting master state %s in ns %s", name, s.nsTarget)
-	k8s, err := clients.Kubernetes()
-	if err != nil {
-		return err
-	}
-	if err := k8s.Client.CoreV1().ConfigMaps(s.nsTarget).Delete(name, &metav1.DeleteOptions{}); err != nil {
-		if kerror.IsNotFound(err) {
-			log.Debugf("state: no state configmap found. Nothing to delete")
-			return nil
+	if s.history > 0 {
+		if err := s.deleteStateSnapshots(name); err != nil {
+			log.Warningf("state: unable to delete the snapshots of %s - %v", name, err)
 		}
-		return err
 	}
-	return nil
+	return s.backend().Delete(name)
 }
 
 // MasterNamespace returns the name of the namespace where the master state is stored
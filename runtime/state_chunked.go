@@ -0,0 +1,221 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// StateChunksLabel - label holding the number of chunks the data of a
+	// state object is split into.
+	StateChunksLabel = "bundle-state-chunks"
+	// StateChunkOfLabel - label holding the name of the state object a chunk
+	// belongs to.
+	StateChunkOfLabel = "bundle-state-chunk-of"
+	// StateChunkGenerationLabel - label holding the generation of the chunks
+	// the state object points to. Every Put writes a new generation.
+	StateChunkGenerationLabel = "bundle-state-chunk-generation"
+
+	// DefaultStateChunkSize - stays well below the 1MiB limit of the objects.
+	DefaultStateChunkSize = 512 * 1024
+
+	stateChunkKey = "chunk"
+)
+
+// NewChunkedStateStore - Splits state that is larger than the chunk size
+// over several objects of the store. A chunk size of 0 uses
+// DefaultStateChunkSize.
+func NewChunkedStateStore(store StateStore, chunkSize int) StateStore {
+	if chunkSize <= 0 {
+		chunkSize = DefaultStateChunkSize
+	}
+	return chunkedStateStore{store: store, chunkSize: chunkSize}
+}
+
+type chunkedStateStore struct {
+	store     StateStore
+	chunkSize int
+}
+
+// stateChunkName - the name of a chunk, chunks written before generations
+// were introduced are generation 0.
+func stateChunkName(name string, generation, i int) string {
+	if generation == 0 {
+		return fmt.Sprintf("%s-chunk-%d", name, i)
+	}
+	return fmt.Sprintf("%s-chunk-%d-%d", name, generation, i)
+}
+
+func stateChunks(obj *StateObject) (int, int) {
+	n, _ := strconv.Atoi(obj.Labels[StateChunksLabel])
+	generation, _ := strconv.Atoi(obj.Labels[StateChunkGenerationLabel])
+	return n, generation
+}
+
+// deleteChunks - removes the first chunks of a generation.
+func (c chunkedStateStore) deleteChunks(name string, generation, chunks int) error {
+	for i := 0; i < chunks; i++ {
+		if err := c.store.Delete(stateChunkName(name, generation, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put - writes the chunks of the data as a new generation and then switches
+// the object over to it, a failure leaves the previous state intact. The
+// chunks of the previous generation are removed once the switch is done.
+func (c chunkedStateStore) Put(obj *StateObject) error {
+	oldChunks, oldGeneration := 0, 0
+	if old, err := c.store.Get(obj.Name); err == nil {
+		oldChunks, oldGeneration = stateChunks(old)
+	} else if err != ErrStateNotFound {
+		return err
+	}
+
+	plain, err := json.Marshal(obj.Data)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{}
+	for k, v := range obj.Labels {
+		labels[k] = v
+	}
+	delete(labels, StateChunksLabel)
+	delete(labels, StateChunkGenerationLabel)
+	index := &StateObject{Name: obj.Name, Labels: labels, Data: obj.Data}
+
+	chunks, generation := 0, oldGeneration+1
+	if len(plain) > c.chunkSize {
+		// Encoded so that chunks never split a multi byte character.
+		encoded := base64.StdEncoding.EncodeToString(plain)
+		for start := 0; start < len(encoded); start += c.chunkSize {
+			end := start + c.chunkSize
+			if end > len(encoded) {
+				end = len(encoded)
+			}
+			err := c.store.Put(&StateObject{
+				Name:   stateChunkName(obj.Name, generation, chunks),
+				Labels: map[string]string{StateChunkOfLabel: obj.Name},
+				Data:   map[string]string{stateChunkKey: encoded[start:end]},
+			})
+			chunks++
+			if err != nil {
+				c.discardChunks(obj.Name, generation, chunks)
+				return err
+			}
+		}
+		labels[StateChunksLabel] = strconv.Itoa(chunks)
+		labels[StateChunkGenerationLabel] = strconv.Itoa(generation)
+		index.Data = map[string]string{}
+	}
+
+	if err := c.store.Put(index); err != nil {
+		c.discardChunks(obj.Name, generation, chunks)
+		return err
+	}
+	c.discardChunks(obj.Name, oldGeneration, oldChunks)
+	return nil
+}
+
+// discardChunks - removes chunks that are not used, a chunk that can not be
+// removed is only left behind.
+func (c chunkedStateStore) discardChunks(name string, generation, chunks int) {
+	if err := c.deleteChunks(name, generation, chunks); err != nil {
+		log.Warningf("state: unable to remove unused chunks of %s - %v", name, err)
+	}
+}
+
+// assemble - reads the chunks of the object back into its data.
+func (c chunkedStateStore) assemble(obj *StateObject) (*StateObject, error) {
+	chunks, generation := stateChunks(obj)
+	if chunks == 0 {
+		return obj, nil
+	}
+	var encoded bytes.Buffer
+	for i := 0; i < chunks; i++ {
+		chunk, err := c.store.Get(stateChunkName(obj.Name, generation, i))
+		if err != nil {
+			return nil, fmt.Errorf("unable to get chunk %d of state %v - %v", i, obj.Name, err)
+		}
+		encoded.WriteString(chunk.Data[stateChunkKey])
+	}
+	plain, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, err
+	}
+	labels := map[string]string{}
+	for k, v := range obj.Labels {
+		if k != StateChunksLabel && k != StateChunkGenerationLabel {
+			labels[k] = v
+		}
+	}
+	return &StateObject{Name: obj.Name, Labels: labels, Data: data, Created: obj.Created}, nil
+}
+
+func (c chunkedStateStore) Get(name string) (*StateObject, error) {
+	obj, err := c.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.assemble(obj)
+}
+
+func (c chunkedStateStore) Delete(name string) error {
+	obj, err := c.store.Get(name)
+	if err == ErrStateNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	chunks, generation := stateChunks(obj)
+	if err := c.deleteChunks(name, generation, chunks); err != nil {
+		return err
+	}
+	return c.store.Delete(name)
+}
+
+func (c chunkedStateStore) List(labels map[string]string) ([]StateObject, error) {
+	objects, err := c.store.List(labels)
+	if err != nil {
+		return nil, err
+	}
+	assembled := []StateObject{}
+	for i := range objects {
+		if _, ok := objects[i].Labels[StateChunkOfLabel]; ok {
+			continue
+		}
+		obj, err := c.assemble(&objects[i])
+		if err != nil {
+			return nil, err
+		}
+		assembled = append(assembled, *obj)
+	}
+	return assembled, nil
+}
@@ -0,0 +1,249 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StateKeyLabel - label holding the id of the key the state is
+	// encrypted with.
+	StateKeyLabel = "bundle-state-key"
+	// StateCurrentKeyAnnotation - annotation on the key secret naming the
+	// key used to encrypt new state.
+	StateCurrentKeyAnnotation = "bundle-state-current-key"
+
+	encryptedStateKey = "encrypted"
+)
+
+// StateKeyProvider - Provides the keys the state is encrypted with. Keys must
+// be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+type StateKeyProvider interface {
+	// CurrentKey - returns the id and the key used to encrypt state.
+	CurrentKey() (string, []byte, error)
+	// Key - returns the key with the id, used to decrypt state that was
+	// encrypted with an older key.
+	Key(id string) ([]byte, error)
+}
+
+// StaticStateKeyProvider - A key provider with a fixed set of keys.
+type StaticStateKeyProvider struct {
+	// Current - id of the key used to encrypt state.
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey - returns the current key.
+func (s StaticStateKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := s.Key(s.Current)
+	return s.Current, key, err
+}
+
+// Key - returns the key with the id.
+func (s StaticStateKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := s.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown state key %v", id)
+	}
+	return key, nil
+}
+
+// SecretStateKeyProvider - Reads the keys from a Secret. Every key of the
+// secret data is a key id, the StateCurrentKeyAnnotation names the key used
+// for encryption. The secret is read on every use so keys can be rotated.
+type SecretStateKeyProvider struct {
+	Namespace string
+	Name      string
+}
+
+// CurrentKey - returns the key named by the annotation of the secret.
+func (s SecretStateKeyProvider) CurrentKey() (string, []byte, error) {
+	keys, current, err := s.keys()
+	if err != nil {
+		return "", nil, err
+	}
+	return StaticStateKeyProvider{Current: current, Keys: keys}.CurrentKey()
+}
+
+// Key - returns the key with the id from the secret.
+func (s SecretStateKeyProvider) Key(id string) ([]byte, error) {
+	keys, _, err := s.keys()
+	if err != nil {
+		return nil, err
+	}
+	return StaticStateKeyProvider{Keys: keys}.Key(id)
+}
+
+func (s SecretStateKeyProvider) keys() (map[string][]byte, string, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := k8s.Client.CoreV1().Secrets(s.Namespace).Get(s.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to get state keys - %v", err)
+	}
+	return secret.Data, secret.Annotations[StateCurrentKeyAnnotation], nil
+}
+
+// NewEncryptedStateStore - Encrypts the state before it is handed to the
+// store. The data of an object is encrypted as a whole with AES-GCM, the
+// labels are kept in plain text so that the objects can still be listed.
+func NewEncryptedStateStore(store StateStore, keys StateKeyProvider) StateStore {
+	return encryptedStateStore{store: store, keys: keys}
+}
+
+type encryptedStateStore struct {
+	store StateStore
+	keys  StateKeyProvider
+}
+
+func newStateCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealData - encrypts plain with AES-GCM, the nonce is prepended to the result.
+// The additional data is authenticated but not encrypted.
+func sealData(key, plain, additional []byte) ([]byte, error) {
+	gcm, err := newStateCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, additional), nil
+}
+
+// openData - decrypts data encrypted by sealData.
+func openData(key, sealed, additional []byte) ([]byte, error) {
+	gcm, err := newStateCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("data is too short to be encrypted")
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, additional)
+}
+
+func (e encryptedStateStore) encrypt(obj *StateObject) (*StateObject, error) {
+	id, key, err := e.keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(obj.Data)
+	if err != nil {
+		return nil, err
+	}
+	// The name is authenticated so an object can not be swapped for another.
+	sealed, err := sealData(key, plain, []byte(obj.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{}
+	for k, v := range obj.Labels {
+		labels[k] = v
+	}
+	labels[StateKeyLabel] = id
+	return &StateObject{
+		Name:    obj.Name,
+		Labels:  labels,
+		Data:    map[string]string{encryptedStateKey: base64.StdEncoding.EncodeToString(sealed)},
+		Created: obj.Created,
+	}, nil
+}
+
+func (e encryptedStateStore) decrypt(obj *StateObject) (*StateObject, error) {
+	id := obj.Labels[StateKeyLabel]
+	key, err := e.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(obj.Data[encryptedStateKey])
+	if err != nil {
+		return nil, err
+	}
+	plain, err := openData(key, sealed, []byte(obj.Name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt state %v - %v", obj.Name, err)
+	}
+	data := map[string]string{}
+	if err := json.Unmarshal(plain, &data); err != nil {
+		return nil, err
+	}
+	labels := map[string]string{}
+	for k, v := range obj.Labels {
+		if k != StateKeyLabel {
+			labels[k] = v
+		}
+	}
+	return &StateObject{Name: obj.Name, Labels: labels, Data: data, Created: obj.Created}, nil
+}
+
+func (e encryptedStateStore) Get(name string) (*StateObject, error) {
+	obj, err := e.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(obj)
+}
+
+func (e encryptedStateStore) Put(obj *StateObject) error {
+	encrypted, err := e.encrypt(obj)
+	if err != nil {
+		return err
+	}
+	return e.store.Put(encrypted)
+}
+
+func (e encryptedStateStore) Delete(name string) error {
+	return e.store.Delete(name)
+}
+
+func (e encryptedStateStore) List(labels map[string]string) ([]StateObject, error) {
+	objects, err := e.store.List(labels)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := []StateObject{}
+	for i := range objects {
+		obj, err := e.decrypt(&objects[i])
+		if err != nil {
+			return nil, err
+		}
+		decrypted = append(decrypted, *obj)
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,250 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// StateLabel - label holding the name of the state a snapshot belongs to.
+	StateLabel = "bundle-state"
+	// StateRevisionLabel - label holding the revision of a state snapshot.
+	StateRevisionLabel = "bundle-state-revision"
+	// StateActionLabel - label holding the action a snapshot was taken for.
+	StateActionLabel = "bundle-state-action"
+)
+
+var (
+	// ErrStateSnapshotNotFound - The requested state snapshot does not exist.
+	ErrStateSnapshotNotFound = fmt.Errorf("state snapshot not found")
+	// ErrStateHistoryDisabled - The state manager is not configured to keep
+	// snapshots.
+	ErrStateHistoryDisabled = fmt.Errorf("state history is disabled")
+)
+
+// StateSnapshot - A copy of the state of a service instance taken before an
+// action changed it.
+type StateSnapshot struct {
+	// Name - the name of the state the snapshot belongs to.
+	Name string
+	// Revision - increases with every snapshot of the state.
+	Revision int
+	// Action - the action that ran after the snapshot was taken.
+	Action  string
+	Created time.Time
+	Data    map[string]string
+}
+
+// StateChange - A key whose value differs between two states.
+type StateChange struct {
+	From string
+	To   string
+}
+
+// StateDiff - The differences between a snapshot and the current state.
+type StateDiff struct {
+	Added   map[string]string
+	Removed map[string]string
+	Changed map[string]StateChange
+}
+
+// StateHistory - Implemented by a StateManager that keeps snapshots of the
+// state so that it can be rolled back.
+type StateHistory interface {
+	// SnapshotState - snapshots the current state before the action changes
+	// it. Returns nil when there is no state to snapshot and
+	// ErrStateHistoryDisabled when no snapshots are kept.
+	SnapshotState(name, action string) (*StateSnapshot, error)
+	// ListStateSnapshots - returns the snapshots ordered by revision.
+	ListStateSnapshots(name string) ([]StateSnapshot, error)
+	// DiffStateSnapshot - compares the snapshot to the current state.
+	DiffStateSnapshot(name string, revision int) (StateDiff, error)
+	// RestoreStateSnapshot - replaces the current state with the snapshot.
+	RestoreStateSnapshot(name string, revision int) error
+}
+
+func snapshotName(name string, revision int) string {
+	return fmt.Sprintf("%s-%d", name, revision)
+}
+
+func objectToSnapshot(obj StateObject) StateSnapshot {
+	revision, _ := strconv.Atoi(obj.Labels[StateRevisionLabel])
+	return StateSnapshot{
+		Name:     obj.Labels[StateLabel],
+		Revision: revision,
+		Action:   obj.Labels[StateActionLabel],
+		Created:  obj.Created,
+		Data:     obj.Data,
+	}
+}
+
+// SnapshotState - snapshots the state in the master namespace. A snapshot is
+// only taken when the state changed since the last snapshot, and snapshots
+// past the configured history are removed.
+func (s state) SnapshotState(name, action string) (*StateSnapshot, error) {
+	if s.history <= 0 {
+		return nil, ErrStateHistoryDisabled
+	}
+	current, err := s.backend().Get(name)
+	if err != nil {
+		if err == ErrStateNotFound {
+			log.Debugf("state: no state %s to snapshot", name)
+			return nil, nil
+		}
+		return nil, err
+	}
+	snapshots, err := s.ListStateSnapshots(name)
+	if err != nil {
+		return nil, err
+	}
+	revision := 1
+	if len(snapshots) > 0 {
+		latest := snapshots[len(snapshots)-1]
+		if reflect.DeepEqual(latest.Data, current.Data) {
+			log.Debugf("state: %s unchanged since revision %d", name, latest.Revision)
+			return &latest, nil
+		}
+		revision = latest.Revision + 1
+	}
+	obj := &StateObject{
+		Name: snapshotName(name, revision),
+		Labels: map[string]string{
+			StateLabel:         name,
+			StateRevisionLabel: strconv.Itoa(revision),
+			StateActionLabel:   action,
+		},
+		Data: current.Data,
+	}
+	if err := s.backend().Put(obj); err != nil {
+		return nil, err
+	}
+	log.Debugf("state: took snapshot %d of %s before %s", revision, name, action)
+	snapshot := objectToSnapshot(*obj)
+	snapshot.Created = time.Now()
+	snapshots = append(snapshots, snapshot)
+
+	// Remove the snapshots that are past the history to keep.
+	for len(snapshots) > s.history {
+		old := snapshots[0]
+		snapshots = snapshots[1:]
+		if err := s.backend().Delete(snapshotName(name, old.Revision)); err != nil {
+			log.Warningf("state: unable to remove snapshot %d of %s - %v", old.Revision, name, err)
+		}
+	}
+	return &snapshot, nil
+}
+
+// ListStateSnapshots - returns the snapshots of the state ordered by revision.
+func (s state) ListStateSnapshots(name string) ([]StateSnapshot, error) {
+	objects, err := s.backend().List(map[string]string{StateLabel: name})
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []StateSnapshot{}
+	for _, obj := range objects {
+		snapshots = append(snapshots, objectToSnapshot(obj))
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Revision < snapshots[j].Revision
+	})
+	return snapshots, nil
+}
+
+func (s state) getStateSnapshot(name string, revision int) (*StateSnapshot, error) {
+	obj, err := s.backend().Get(snapshotName(name, revision))
+	if err != nil {
+		if err == ErrStateNotFound {
+			return nil, ErrStateSnapshotNotFound
+		}
+		return nil, err
+	}
+	snapshot := objectToSnapshot(*obj)
+	return &snapshot, nil
+}
+
+// DiffStateSnapshot - compares the snapshot to the current state, keys that
+// are added or changed are the ones the actions after the snapshot wrote.
+func (s state) DiffStateSnapshot(name string, revision int) (StateDiff, error) {
+	diff := StateDiff{
+		Added:   map[string]string{},
+		Removed: map[string]string{},
+		Changed: map[string]StateChange{},
+	}
+	snapshot, err := s.getStateSnapshot(name, revision)
+	if err != nil {
+		return diff, err
+	}
+	current := map[string]string{}
+	obj, err := s.backend().Get(name)
+	switch {
+	case err == nil:
+		current = obj.Data
+	case err != ErrStateNotFound:
+		return diff, err
+	}
+	for k, v := range current {
+		old, ok := snapshot.Data[k]
+		switch {
+		case !ok:
+			diff.Added[k] = v
+		case old != v:
+			diff.Changed[k] = StateChange{From: old, To: v}
+		}
+	}
+	for k, v := range snapshot.Data {
+		if _, ok := current[k]; !ok {
+			diff.Removed[k] = v
+		}
+	}
+	return diff, nil
+}
+
+// RestoreStateSnapshot - replaces the current state with the data of the
+// snapshot, the snapshot is kept.
+func (s state) RestoreStateSnapshot(name string, revision int) error {
+	snapshot, err := s.getStateSnapshot(name, revision)
+	if err != nil {
+		return err
+	}
+	err = s.backend().Put(&StateObject{Name: name, Data: snapshot.Data})
+	if err != nil {
+		return err
+	}
+	log.Infof("state: restored %s to revision %d", name, revision)
+	return nil
+}
+
+// deleteStateSnapshots - removes every snapshot of the state.
+func (s state) deleteStateSnapshots(name string) error {
+	snapshots, err := s.ListStateSnapshots(name)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if err := s.backend().Delete(snapshotName(name, snapshot.Revision)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
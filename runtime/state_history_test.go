@@ -0,0 +1,174 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStateHistory(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	master := func(data map[string]string) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "id-state", Namespace: "nsTarget"},
+			Data:       data,
+		}
+	}
+	setState := func(data map[string]string) {
+		if _, err := k.Client.CoreV1().ConfigMaps("nsTarget").Update(master(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testCases := []struct {
+		name     string
+		s        state
+		client   *fake.Clientset
+		validate func(state) bool
+	}{
+		{
+			name:   "history disabled",
+			s:      state{nsTarget: "nsTarget"},
+			client: fake.NewSimpleClientset(master(map[string]string{"db": "v1"})),
+			validate: func(s state) bool {
+				_, err := s.SnapshotState("id-state", "update")
+				return err == ErrStateHistoryDisabled
+			},
+		},
+		{
+			name:   "no state to snapshot",
+			s:      state{nsTarget: "nsTarget", history: 2},
+			client: fake.NewSimpleClientset(),
+			validate: func(s state) bool {
+				snapshot, err := s.SnapshotState("id-state", "provision")
+				return err == nil && snapshot == nil
+			},
+		},
+		{
+			name:   "unchanged state is not snapshot twice",
+			s:      state{nsTarget: "nsTarget", history: 2},
+			client: fake.NewSimpleClientset(master(map[string]string{"db": "v1"})),
+			validate: func(s state) bool {
+				first, err := s.SnapshotState("id-state", "update")
+				if err != nil {
+					t.Fatal(err)
+				}
+				second, err := s.SnapshotState("id-state", "bind")
+				if err != nil {
+					t.Fatal(err)
+				}
+				snapshots, err := s.ListStateSnapshots("id-state")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return first.Revision == 1 && second.Revision == 1 && len(snapshots) == 1
+			},
+		},
+		{
+			name:   "old snapshots are pruned",
+			s:      state{nsTarget: "nsTarget", history: 2},
+			client: fake.NewSimpleClientset(master(map[string]string{"db": "v1"})),
+			validate: func(s state) bool {
+				for _, v := range []string{"v2", "v3", "v4"} {
+					if _, err := s.SnapshotState("id-state", "update"); err != nil {
+						t.Fatal(err)
+					}
+					setState(map[string]string{"db": v})
+				}
+				snapshots, err := s.ListStateSnapshots("id-state")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return len(snapshots) == 2 && snapshots[0].Revision == 2 &&
+					snapshots[1].Revision == 3 && snapshots[1].Action == "update"
+			},
+		},
+		{
+			name:   "diff and restore snapshot",
+			s:      state{nsTarget: "nsTarget", history: 2},
+			client: fake.NewSimpleClientset(master(map[string]string{"db": "v1", "user": "admin"})),
+			validate: func(s state) bool {
+				snapshot, err := s.SnapshotState("id-state", "update")
+				if err != nil {
+					t.Fatal(err)
+				}
+				setState(map[string]string{"db": "v2", "port": "5432"})
+				diff, err := s.DiffStateSnapshot("id-state", snapshot.Revision)
+				if err != nil {
+					t.Fatal(err)
+				}
+				expected := StateDiff{
+					Added:   map[string]string{"port": "5432"},
+					Removed: map[string]string{"user": "admin"},
+					Changed: map[string]StateChange{"db": {From: "v1", To: "v2"}},
+				}
+				if !reflect.DeepEqual(expected, diff) {
+					t.Fatalf("expected diff %v got %v", expected, diff)
+				}
+				if err := s.RestoreStateSnapshot("id-state", snapshot.Revision); err != nil {
+					t.Fatal(err)
+				}
+				cm, err := k.Client.CoreV1().ConfigMaps("nsTarget").Get("id-state", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				return reflect.DeepEqual(map[string]string{"db": "v1", "user": "admin"}, cm.Data)
+			},
+		},
+		{
+			name:   "restore unknown snapshot",
+			s:      state{nsTarget: "nsTarget", history: 2},
+			client: fake.NewSimpleClientset(master(map[string]string{"db": "v1"})),
+			validate: func(s state) bool {
+				return s.RestoreStateSnapshot("id-state", 5) == ErrStateSnapshotNotFound
+			},
+		},
+		{
+			name:   "delete state removes snapshots",
+			s:      state{nsTarget: "nsTarget", history: 2},
+			client: fake.NewSimpleClientset(master(map[string]string{"db": "v1"})),
+			validate: func(s state) bool {
+				if _, err := s.SnapshotState("id-state", "update"); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.DeleteState("id-state"); err != nil {
+					t.Fatal(err)
+				}
+				_, err := k.Client.CoreV1().ConfigMaps("nsTarget").Get(snapshotName("id-state", 1), metav1.GetOptions{})
+				return kerror.IsNotFound(err)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k.Client = tc.client
+			if !tc.validate(tc.s) {
+				t.Fatal("validation failed")
+			}
+		})
+	}
+}
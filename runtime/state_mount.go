@@ -0,0 +1,347 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// StateContainerName - name of the container that keeps a writable state
+	// mount available until the runtime has collected the state.
+	StateContainerName = "bundle-state"
+	// StateSeedContainerName - name of the init container that copies the
+	// previous state into the writable state mount.
+	StateSeedContainerName = "bundle-state-seed"
+
+	stateVolumeName      = "bundle-state"
+	stateSeedVolumeName  = "bundle-state-seed"
+	stateSeedLocation    = "/etc/apb/state-seed"
+	stateCollectedMarker = ".bundle-state-collected"
+)
+
+// collectStateFunc - collects the writable state of a pod, replaced in tests.
+var collectStateFunc = collectState
+
+// addWritableState - Mounts an emptyDir at the state location of the bundle
+// container. The emptyDir is seeded with the previous state from the seed
+// volume, if any, by an init container and kept by a sidecar until the
+// runtime has collected it. Both run the bundle image, which needs sh and
+// tar.
+func addWritableState(pod *v1.Pod, ec ExecutionContext, seed *v1.VolumeSource) {
+	bundle := &pod.Spec.Containers[0]
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name:         stateVolumeName,
+		VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+	})
+	stateMount := v1.VolumeMount{Name: stateVolumeName, MountPath: ec.StateLocation}
+	bundle.VolumeMounts = append(bundle.VolumeMounts, stateMount)
+
+	if seed != nil {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+			Name:         stateSeedVolumeName,
+			VolumeSource: *seed,
+		})
+		pod.Spec.InitContainers = append(pod.Spec.InitContainers, v1.Container{
+			Name:            StateSeedContainerName,
+			Image:           bundle.Image,
+			ImagePullPolicy: bundle.ImagePullPolicy,
+			// The keys of a projected volume are symlinks, copy their targets.
+			Command: []string{"sh", "-c", fmt.Sprintf("[ -z \"$(ls %s)\" ] || cp -L %s/* %s/",
+				stateSeedLocation, stateSeedLocation, ec.StateLocation)},
+			VolumeMounts: []v1.VolumeMount{
+				stateMount,
+				{Name: stateSeedVolumeName, MountPath: stateSeedLocation, ReadOnly: true},
+			},
+		})
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{
+		Name:            StateContainerName,
+		Image:           bundle.Image,
+		ImagePullPolicy: bundle.ImagePullPolicy,
+		Command: []string{"sh", "-c", fmt.Sprintf(
+			"until [ -f %s ]; do sleep 1; done", path.Join(ec.StateLocation, stateCollectedMarker))},
+		VolumeMounts: []v1.VolumeMount{stateMount},
+	})
+}
+
+// stateCollections - the writable states that are collected in the
+// background, by namespace and pod.
+type stateCollections struct {
+	mutex   sync.Mutex
+	pending map[string]chan error
+}
+
+func newStateCollections() *stateCollections {
+	return &stateCollections{pending: map[string]chan error{}}
+}
+
+// start - collects the writable state of the pod once the bundle container
+// has terminated. This runs next to the watcher of the bundle, whichever it
+// is, because the pod only completes once the state is collected.
+func (c *stateCollections) start(podName, namespace string) {
+	if c == nil {
+		return
+	}
+	done := make(chan error, 1)
+	c.mutex.Lock()
+	c.pending[path.Join(namespace, podName)] = done
+	c.mutex.Unlock()
+	go func() {
+		done <- watchStateCollection(podName, namespace)
+	}()
+}
+
+// wait - waits until the state of the pod is collected, nil when the pod
+// has no writable state.
+func (c *stateCollections) wait(podName, namespace string) error {
+	done := c.forget(podName, namespace)
+	if done == nil {
+		return nil
+	}
+	return <-done
+}
+
+// forget - stops tracking the collection of the pod and returns it.
+func (c *stateCollections) forget(podName, namespace string) chan error {
+	if c == nil {
+		return nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := path.Join(namespace, podName)
+	done := c.pending[key]
+	delete(c.pending, key)
+	return done
+}
+
+// watchStateCollection - watches the pod until its bundle container has
+// terminated and collects its writable state.
+func watchStateCollection(podName, namespace string) error {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve kubernetes client %v", err)
+	}
+	w, err := k8scli.Client.CoreV1().Pods(namespace).Watch(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to watch pod %s in namespace %s error: %v", podName, namespace, err)
+	}
+	defer w.Stop()
+	for podEvent := range w.ResultChan() {
+		pod, ok := podEvent.Object.(*v1.Pod)
+		if !ok || pod.Name != podName {
+			continue
+		}
+		if stateCollectionPending(pod) {
+			return collectStateFunc(pod)
+		}
+		if podEvent.Type == watch.Deleted || pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded {
+			log.Debugf("state: pod [ %s ] completed without a state to collect", podName)
+			return nil
+		}
+	}
+	return fmt.Errorf("stopped watching pod [ %s ] before its state was collected", podName)
+}
+
+// stateCollectionPending - true if the pod has a writable state mount and
+// the bundle container has terminated.
+func stateCollectionPending(pod *v1.Pod) bool {
+	found := false
+	for _, c := range pod.Spec.Containers {
+		if c.Name == StateContainerName {
+			found = true
+		}
+	}
+	if !found {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == BundleContainerName && status.State.Terminated != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// collectState - Reads the files of the writable state mount into a
+// configmap named after the pod, where the state manager copies the state
+// from once the bundle completes. The state is only read when the bundle
+// succeeded. The sidecar is released in any case so that the pod completes.
+func collectState(pod *v1.Pod) error {
+	var location string
+	for _, c := range pod.Spec.Containers {
+		if c.Name == StateContainerName && len(c.VolumeMounts) > 0 {
+			location = c.VolumeMounts[0].MountPath
+		}
+	}
+	defer func() {
+		_, err := execInContainer(pod.Name, pod.Namespace, StateContainerName,
+			[]string{"touch", path.Join(location, stateCollectedMarker)})
+		if err != nil {
+			log.Errorf("unable to release the state of pod [ %s ] - %v", pod.Name, err)
+		}
+	}()
+
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if status.Name == BundleContainerName && terminated != nil && terminated.ExitCode != 0 {
+			log.Debugf("state: bundle [ %s ] failed, not collecting state", pod.Name)
+			return nil
+		}
+	}
+
+	archive, err := execInContainer(pod.Name, pod.Namespace, StateContainerName,
+		[]string{"tar", "-C", location, "-cf", "-", "."})
+	if err != nil {
+		return fmt.Errorf("unable to collect the state of pod [ %s ] - %v", pod.Name, err)
+	}
+	data, err := readStateArchive(archive)
+	if err != nil {
+		return fmt.Errorf("unable to read the state of pod [ %s ] - %v", pod.Name, err)
+	}
+	if len(data) == 0 {
+		log.Debugf("state: bundle [ %s ] did not write any state", pod.Name)
+		return nil
+	}
+	return saveCollectedState(pod.Name, pod.Namespace, data)
+}
+
+// readStateArchive - Returns the files at the top of the tar archive. Files
+// that can not be kept in a configmap are skipped.
+func readStateArchive(archive []byte) (map[string]string, error) {
+	data := map[string]string{}
+	r := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if name == stateCollectedMarker || strings.Contains(name, "/") {
+			continue
+		}
+		if errs := validation.IsConfigMapKey(name); len(errs) > 0 {
+			log.Warningf("state: skipping file %s - %v", name, strings.Join(errs, ", "))
+			continue
+		}
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(content) {
+			log.Warningf("state: skipping file %s - not valid UTF-8", name)
+			continue
+		}
+		data[name] = string(content)
+	}
+}
+
+// saveCollectedState - merges the collected files into the configmap named
+// after the pod, a configmap the bundle created itself is kept.
+func saveCollectedState(name, namespace string, data map[string]string) error {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	client := k8s.Client.CoreV1().ConfigMaps(namespace)
+	cm, err := client.Get(name, metav1.GetOptions{})
+	if err != nil {
+		if !kerror.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       data,
+		})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	for k, v := range data {
+		cm.Data[k] = v
+	}
+	_, err = client.Update(cm)
+	return err
+}
+
+// execInContainer - runs the command in the container and returns stdout.
+func execInContainer(podName, namespace, container string, command []string) ([]byte, error) {
+	k8scli, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	clientConfig := *k8scli.ClientConfig
+	clientConfig.GroupVersion = &v1.SchemeGroupVersion
+	clientConfig.NegotiatedSerializer =
+		serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	clientConfig.APIPath = "/api"
+
+	restClient, err := rest.RESTClientFor(&clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	req := restClient.Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec")
+	req.VersionedParams(&v1.PodExecOptions{
+		Container: container,
+		Command:   command,
+		Stdout:    true,
+		Stderr:    true,
+	}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(&clientConfig, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	err = exec.Stream(remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return nil, fmt.Errorf("%v - %v", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
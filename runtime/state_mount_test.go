@@ -0,0 +1,190 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func TestAddWritableState(t *testing.T) {
+	testCases := []struct {
+		name      string
+		ec        ExecutionContext
+		seed      *v1.VolumeSource
+		podVolume int
+	}{
+		{
+			name:      "new state",
+			ec:        ExecutionContext{StateLocation: defaultMountLocation, StateWritable: true},
+			podVolume: 1,
+		},
+		{
+			name:      "seeded from previous state",
+			ec:        ExecutionContext{StateName: "pod", StateLocation: defaultMountLocation, StateWritable: true},
+			seed:      &v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{}},
+			podVolume: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: BundleContainerName, Image: "image"}},
+				},
+			}
+			addWritableState(pod, tc.ec, tc.seed)
+			if len(pod.Spec.Volumes) != tc.podVolume {
+				t.Fatalf("expected %d volumes got %d", tc.podVolume, len(pod.Spec.Volumes))
+			}
+			if pod.Spec.Volumes[0].EmptyDir == nil {
+				t.Fatal("expected the state to be an emptyDir")
+			}
+			mount := pod.Spec.Containers[0].VolumeMounts[0]
+			if mount.ReadOnly || mount.MountPath != defaultMountLocation {
+				t.Fatalf("expected a writable mount at %s got %v", defaultMountLocation, mount)
+			}
+			if len(pod.Spec.Containers) != 2 || pod.Spec.Containers[1].Name != StateContainerName {
+				t.Fatalf("expected the state sidecar got %v", pod.Spec.Containers)
+			}
+			if (tc.seed != nil) != (len(pod.Spec.InitContainers) == 1) {
+				t.Fatalf("expected seeded %v got init containers %v", tc.seed != nil, pod.Spec.InitContainers)
+			}
+		})
+	}
+}
+
+func TestReadStateArchive(t *testing.T) {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	files := []struct {
+		name     string
+		typeflag byte
+		content  string
+	}{
+		{name: "./", typeflag: tar.TypeDir},
+		{name: "./db", typeflag: tar.TypeReg, content: "postgres"},
+		{name: "./" + stateCollectedMarker, typeflag: tar.TypeReg},
+		{name: "./nested/", typeflag: tar.TypeDir},
+		{name: "./nested/file", typeflag: tar.TypeReg, content: "ignored"},
+		{name: "./bad key", typeflag: tar.TypeReg, content: "ignored"},
+		{name: "./binary", typeflag: tar.TypeReg, content: "\xff\xfe"},
+	}
+	for _, f := range files {
+		err := w.WriteHeader(&tar.Header{Name: f.name, Typeflag: f.typeflag, Mode: 0644, Size: int64(len(f.content))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+
+	data, err := readStateArchive(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"db": "postgres"}
+	if !reflect.DeepEqual(expected, data) {
+		t.Fatalf("expected %v got %v", expected, data)
+	}
+}
+
+func TestSaveCollectedState(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "sandbox"},
+		Data:       map[string]string{"db": "v1", "user": "admin"},
+	})
+	if err := saveCollectedState("pod", "sandbox", map[string]string{"db": "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := k.Client.CoreV1().ConfigMaps("sandbox").Get("pod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"db": "v2", "user": "admin"}
+	if !reflect.DeepEqual(expected, cm.Data) {
+		t.Fatalf("expected %v got %v", expected, cm.Data)
+	}
+}
+
+func TestStateCollections(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	kfake := &fake.Clientset{}
+	podWatch := watch.NewFake()
+	kfake.AddWatchReactor("pods", ktesting.DefaultWatchReactor(podWatch, nil))
+	k.Client = kfake
+
+	collected := 0
+	collectStateFunc = func(pod *v1.Pod) error {
+		collected++
+		return nil
+	}
+	defer func() { collectStateFunc = collectState }()
+
+	c := newStateCollections()
+	// a pod without a writable state is not waited for
+	if err := c.wait("other", "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	spec := v1.PodSpec{
+		Containers: []v1.Container{{Name: BundleContainerName}, {Name: StateContainerName}},
+	}
+	terminated := []v1.ContainerStatus{
+		{Name: BundleContainerName, State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}},
+		{Name: StateContainerName, State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+	}
+	// the state is collected without a watcher of the bundle
+	c.start("test", "test")
+	go func() {
+		for _, pod := range []*v1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Spec: spec, Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: terminated}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: spec, Status: v1.PodStatus{Phase: v1.PodRunning}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: spec, Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: terminated}},
+		} {
+			podWatch.Modify(pod)
+		}
+	}()
+	if err := c.wait("test", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if collected != 1 {
+		t.Fatalf("expected the state to be collected once got %d", collected)
+	}
+	// the collection is only waited for once
+	if err := c.wait("test", "test"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,310 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ErrStateNotFound - The state object does not exist in the store.
+var ErrStateNotFound = fmt.Errorf("state not found")
+
+// StateObject - A state object kept by a StateStore.
+type StateObject struct {
+	Name    string
+	Labels  map[string]string
+	Data    map[string]string
+	Created time.Time
+}
+
+// StateStore - The backend that keeps the master copy of the bundle state
+// and its snapshots. The bundle pod always gets the state as Secrets in its
+// own namespace, the store is only used for the master copy.
+type StateStore interface {
+	// Get - returns ErrStateNotFound if the object does not exist.
+	Get(name string) (*StateObject, error)
+	// Put - creates the object or replaces an existing one.
+	Put(obj *StateObject) error
+	// Delete - deleting an object that does not exist is not an error.
+	Delete(name string) error
+	// List - returns the objects that have all of the labels.
+	List(labels map[string]string) ([]StateObject, error)
+}
+
+// NewConfigMapStateStore - Keeps the state in ConfigMaps, this is the
+// default store.
+func NewConfigMapStateStore(namespace string) StateStore {
+	return configMapStateStore{namespace: namespace}
+}
+
+// NewSecretStateStore - Keeps the state in Secrets.
+func NewSecretStateStore(namespace string) StateStore {
+	return secretStateStore{namespace: namespace}
+}
+
+// NewMemoryStateStore - Keeps the state in memory, this is meant for tests.
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{objects: map[string]StateObject{}}
+}
+
+type configMapStateStore struct {
+	namespace string
+}
+
+func (c configMapStateStore) Get(name string) (*StateObject, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := k8s.Client.CoreV1().ConfigMaps(c.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, ErrStateNotFound
+		}
+		return nil, err
+	}
+	return &StateObject{
+		Name:    cm.Name,
+		Labels:  cm.Labels,
+		Data:    cm.Data,
+		Created: cm.CreationTimestamp.Time,
+	}, nil
+}
+
+func (c configMapStateStore) Put(obj *StateObject) error {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	client := k8s.Client.CoreV1().ConfigMaps(c.namespace)
+	cm, err := client.Get(obj.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerror.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: obj.Name, Namespace: c.namespace, Labels: obj.Labels},
+			Data:       obj.Data,
+		})
+		return err
+	}
+	cm.Labels = obj.Labels
+	cm.Data = obj.Data
+	_, err = client.Update(cm)
+	return err
+}
+
+func (c configMapStateStore) Delete(name string) error {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	err = k8s.Client.CoreV1().ConfigMaps(c.namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (c configMapStateStore) List(l map[string]string) ([]StateObject, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	list, err := k8s.Client.CoreV1().ConfigMaps(c.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(l).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	objects := []StateObject{}
+	for _, cm := range list.Items {
+		objects = append(objects, StateObject{
+			Name:    cm.Name,
+			Labels:  cm.Labels,
+			Data:    cm.Data,
+			Created: cm.CreationTimestamp.Time,
+		})
+	}
+	return objects, nil
+}
+
+type secretStateStore struct {
+	namespace string
+}
+
+func secretToStateObject(secret *v1.Secret) StateObject {
+	data := map[string]string{}
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return StateObject{
+		Name:    secret.Name,
+		Labels:  secret.Labels,
+		Data:    data,
+		Created: secret.CreationTimestamp.Time,
+	}
+}
+
+func stateObjectToSecretData(obj *StateObject) map[string][]byte {
+	data := map[string][]byte{}
+	for k, v := range obj.Data {
+		data[k] = []byte(v)
+	}
+	return data
+}
+
+func (s secretStateStore) Get(name string) (*StateObject, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := k8s.Client.CoreV1().Secrets(s.namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			return nil, ErrStateNotFound
+		}
+		return nil, err
+	}
+	obj := secretToStateObject(secret)
+	return &obj, nil
+}
+
+func (s secretStateStore) Put(obj *StateObject) error {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	client := k8s.Client.CoreV1().Secrets(s.namespace)
+	secret, err := client.Get(obj.Name, metav1.GetOptions{})
+	if err != nil {
+		if !kerror.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: obj.Name, Namespace: s.namespace, Labels: obj.Labels},
+			Data:       stateObjectToSecretData(obj),
+		})
+		return err
+	}
+	secret.Labels = obj.Labels
+	secret.Data = stateObjectToSecretData(obj)
+	_, err = client.Update(secret)
+	return err
+}
+
+func (s secretStateStore) Delete(name string) error {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return err
+	}
+	err = k8s.Client.CoreV1().Secrets(s.namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (s secretStateStore) List(l map[string]string) ([]StateObject, error) {
+	k8s, err := clients.Kubernetes()
+	if err != nil {
+		return nil, err
+	}
+	list, err := k8s.Client.CoreV1().Secrets(s.namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(l).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	objects := []StateObject{}
+	for i := range list.Items {
+		objects = append(objects, secretToStateObject(&list.Items[i]))
+	}
+	return objects, nil
+}
+
+type memoryStateStore struct {
+	mutex   sync.Mutex
+	objects map[string]StateObject
+}
+
+func copyStateObject(obj StateObject) StateObject {
+	c := StateObject{Name: obj.Name, Created: obj.Created, Labels: map[string]string{}, Data: map[string]string{}}
+	for k, v := range obj.Labels {
+		c.Labels[k] = v
+	}
+	for k, v := range obj.Data {
+		c.Data[k] = v
+	}
+	return c
+}
+
+func (m *memoryStateStore) Get(name string) (*StateObject, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	obj, ok := m.objects[name]
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+	c := copyStateObject(obj)
+	return &c, nil
+}
+
+func (m *memoryStateStore) Put(obj *StateObject) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	c := copyStateObject(*obj)
+	if old, ok := m.objects[obj.Name]; ok {
+		c.Created = old.Created
+	} else {
+		c.Created = time.Now()
+	}
+	m.objects[obj.Name] = c
+	return nil
+}
+
+func (m *memoryStateStore) Delete(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.objects, name)
+	return nil
+}
+
+func (m *memoryStateStore) List(l map[string]string) ([]StateObject, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	selector := labels.SelectorFromSet(l)
+	objects := []StateObject{}
+	for _, obj := range m.objects {
+		if selector.Matches(labels.Set(obj.Labels)) {
+			objects = append(objects, copyStateObject(obj))
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+	return objects, nil
+}
@@ -0,0 +1,396 @@
+//
+// Copyright (c) 2018 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/automationbroker/bundle-lib/clients"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStateStore(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	testCases := []struct {
+		name  string
+		store func() StateStore
+	}{
+		{
+			name:  "memory",
+			store: NewMemoryStateStore,
+		},
+		{
+			name:  "configmap",
+			store: func() StateStore { return NewConfigMapStateStore("nsTarget") },
+		},
+		{
+			name:  "secret",
+			store: func() StateStore { return NewSecretStateStore("nsTarget") },
+		},
+		{
+			name: "encrypted",
+			store: func() StateStore {
+				keys := StaticStateKeyProvider{Current: "k1", Keys: map[string][]byte{"k1": []byte("0123456789abcdef")}}
+				return NewEncryptedStateStore(NewMemoryStateStore(), keys)
+			},
+		},
+		{
+			name:  "chunked",
+			store: func() StateStore { return NewChunkedStateStore(NewMemoryStateStore(), 8) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k.Client = fake.NewSimpleClientset()
+			store := tc.store()
+			if _, err := store.Get("id-state"); err != ErrStateNotFound {
+				t.Fatalf("expected ErrStateNotFound got %v", err)
+			}
+			data := map[string]string{"db": "postgres", "password": "changeme"}
+			err := store.Put(&StateObject{Name: "id-state", Labels: map[string]string{StateLabel: "id"}, Data: data})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = store.Put(&StateObject{Name: "other-state", Labels: map[string]string{StateLabel: "other"}, Data: data})
+			if err != nil {
+				t.Fatal(err)
+			}
+			obj, err := store.Get("id-state")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data, obj.Data) {
+				t.Fatalf("expected %v got %v", data, obj.Data)
+			}
+			objects, err := store.List(map[string]string{StateLabel: "id"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(objects) != 1 || objects[0].Name != "id-state" {
+				t.Fatalf("expected only id-state to be listed got %v", objects)
+			}
+			if err := store.Delete("id-state"); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Delete("id-state"); err != nil {
+				t.Fatalf("deleting a missing object failed - %v", err)
+			}
+			if _, err := store.Get("id-state"); err != ErrStateNotFound {
+				t.Fatalf("expected ErrStateNotFound got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStateStore(t *testing.T) {
+	keys := StaticStateKeyProvider{
+		Current: "k1",
+		Keys: map[string][]byte{
+			"k1": []byte("0123456789abcdef"),
+			"k2": []byte("fedcba9876543210fedcba9876543210"),
+		},
+	}
+	backend := NewMemoryStateStore()
+	store := NewEncryptedStateStore(backend, keys)
+	data := map[string]string{"password": "changeme"}
+	if err := store.Put(&StateObject{Name: "id-state", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := backend.Get("id-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Labels[StateKeyLabel] != "k1" || strings.Contains(raw.Data[encryptedStateKey], "changeme") {
+		t.Fatalf("state was not encrypted with k1 - %v", raw)
+	}
+
+	// Rotating the key still decrypts the state written with the old key.
+	keys.Current = "k2"
+	store = NewEncryptedStateStore(backend, keys)
+	obj, err := store.Get("id-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data, obj.Data) {
+		t.Fatalf("expected %v got %v", data, obj.Data)
+	}
+	if _, ok := obj.Labels[StateKeyLabel]; ok {
+		t.Fatal("key label should not be returned")
+	}
+
+	// A wrong key fails instead of returning garbage.
+	wrong := StaticStateKeyProvider{Current: "k1", Keys: map[string][]byte{"k1": []byte("aaaaaaaaaaaaaaaa")}}
+	if _, err := NewEncryptedStateStore(backend, wrong).Get("id-state"); err == nil {
+		t.Fatal("expected decryption with the wrong key to fail")
+	}
+
+	// Swapping the data of two objects is detected.
+	if err := store.Put(&StateObject{Name: "other-state", Data: data}); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := backend.Get("other-state")
+	other.Name = "id-state"
+	backend.Put(other)
+	if _, err := store.Get("id-state"); err == nil {
+		t.Fatal("expected swapped state to fail")
+	}
+}
+
+func TestSecretStateKeyProvider(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset()
+	_, err = k.Client.CoreV1().Secrets("broker").Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "state-keys",
+			Namespace:   "broker",
+			Annotations: map[string]string{StateCurrentKeyAnnotation: "k2"},
+		},
+		Data: map[string][]byte{
+			"k1": []byte("0123456789abcdef"),
+			"k2": []byte("fedcba9876543210"),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := SecretStateKeyProvider{Namespace: "broker", Name: "state-keys"}
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "k2" || string(key) != "fedcba9876543210" {
+		t.Fatalf("unexpected current key %v", id)
+	}
+	if _, err := keys.Key("k3"); err == nil {
+		t.Fatal("expected unknown key to fail")
+	}
+}
+
+func TestChunkedStateStore(t *testing.T) {
+	backend := NewMemoryStateStore()
+	store := NewChunkedStateStore(backend, 16)
+	large := map[string]string{"data": strings.Repeat("x", 100)}
+	if err := store.Put(&StateObject{Name: "id-state", Data: large}); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err := backend.List(map[string]string{StateChunkOfLabel: "id-state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("expected the state to be split got %d chunks", len(chunks))
+	}
+	obj, err := store.Get("id-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(large, obj.Data) {
+		t.Fatalf("expected %v got %v", large, obj.Data)
+	}
+	if _, ok := obj.Labels[StateChunksLabel]; ok {
+		t.Fatal("chunks label should not be returned")
+	}
+	objects, err := store.List(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("expected chunks to be hidden got %d objects", len(objects))
+	}
+
+	// Shrinking the state removes the chunks that are no longer used.
+	small := map[string]string{"a": "b"}
+	if err := store.Put(&StateObject{Name: "id-state", Data: small}); err != nil {
+		t.Fatal(err)
+	}
+	chunks, err = backend.List(map[string]string{StateChunkOfLabel: "id-state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 0 {
+		t.Fatalf("expected the chunks to be removed got %d", len(chunks))
+	}
+	if err := store.Put(&StateObject{Name: "id-state", Data: large}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("id-state"); err != nil {
+		t.Fatal(err)
+	}
+	objects, err = backend.List(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 0 {
+		t.Fatalf("expected delete to remove the chunks got %v", objects)
+	}
+}
+
+// failingStateStore - fails to put the objects with a name in fail.
+type failingStateStore struct {
+	StateStore
+	fail map[string]bool
+}
+
+func (f failingStateStore) Put(obj *StateObject) error {
+	if f.fail[obj.Name] {
+		return fmt.Errorf("unable to put %s", obj.Name)
+	}
+	return f.StateStore.Put(obj)
+}
+
+func TestChunkedStateStoreFailedPut(t *testing.T) {
+	backend := failingStateStore{StateStore: NewMemoryStateStore(), fail: map[string]bool{}}
+	store := NewChunkedStateStore(backend, 16)
+	previous := map[string]string{"data": strings.Repeat("x", 100)}
+	if err := store.Put(&StateObject{Name: "id-state", Data: previous}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := backend.List(map[string]string{StateChunkOfLabel: "id-state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A chunk of the next generation fails, the previous state is kept.
+	backend.fail[stateChunkName("id-state", 2, 3)] = true
+	if err := store.Put(&StateObject{Name: "id-state", Data: map[string]string{"data": strings.Repeat("y", 100)}}); err == nil {
+		t.Fatal("expected the put to fail")
+	}
+	obj, err := store.Get("id-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(previous, obj.Data) {
+		t.Fatalf("expected the previous state got %v", obj.Data)
+	}
+	after, err := backend.List(map[string]string{StateChunkOfLabel: "id-state"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != len(after) {
+		t.Fatalf("expected the chunks of the failed put to be removed got %d chunks", len(after))
+	}
+
+	// The switch to the new generation fails, the previous state is kept.
+	backend.fail = map[string]bool{"id-state": true}
+	if err := store.Put(&StateObject{Name: "id-state", Data: map[string]string{"data": strings.Repeat("z", 100)}}); err == nil {
+		t.Fatal("expected the put to fail")
+	}
+	obj, err = store.Get("id-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(previous, obj.Data) {
+		t.Fatalf("expected the previous state got %v", obj.Data)
+	}
+}
+
+func TestWriteSandboxState(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset()
+	data := map[string]string{
+		"a": strings.Repeat("a", DefaultStateChunkSize-10),
+		"b": strings.Repeat("b", DefaultStateChunkSize-10),
+		"c": "c",
+	}
+	if err := writeSandboxState("pod", "sandbox", data); err != nil {
+		t.Fatal(err)
+	}
+	source, err := sandboxStateVolumeSource("pod", "sandbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.Projected == nil || len(source.Projected.Sources) != 2 {
+		t.Fatalf("expected the state to be split over 2 secrets got %v", source)
+	}
+	mounted := map[string]string{}
+	for _, projection := range source.Projected.Sources {
+		secret, err := k.Client.CoreV1().Secrets("sandbox").Get(projection.Secret.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, value := range secret.Data {
+			mounted[key] = string(value)
+		}
+	}
+	if !reflect.DeepEqual(data, mounted) {
+		t.Fatal("expected every file of the state to be mounted")
+	}
+}
+
+func TestCopyStateToStore(t *testing.T) {
+	k, err := clients.Kubernetes()
+	if err != nil {
+		t.Fail()
+	}
+	k.Client = fake.NewSimpleClientset()
+	_, err = k.Client.CoreV1().ConfigMaps("sandbox").Create(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "id-state", Namespace: "sandbox"},
+		Data:       map[string]string{"db": "v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := state{nsTarget: "nsTarget", store: NewMemoryStateStore()}
+	if err := s.CopyState("id-state", "id-state", "sandbox", "nsTarget"); err != nil {
+		t.Fatal(err)
+	}
+	present, err := s.StateIsPresent("id-state")
+	if err != nil || !present {
+		t.Fatalf("expected the state to be in the store - %v", err)
+	}
+	if _, err := k.Client.CoreV1().ConfigMaps("nsTarget").Get("id-state", metav1.GetOptions{}); err == nil {
+		t.Fatal("state should not be kept in a configmap")
+	}
+
+	// Copying back into a sandbox creates the secret the bundle mounts, the
+	// state is never copied into a configmap.
+	if err := s.CopyState("id-state", "id-state", "nsTarget", "other-sandbox"); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := k.Client.CoreV1().Secrets("other-sandbox").Get("id-state", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(map[string][]byte{"db": []byte("v1")}, secret.Data) {
+		t.Fatalf("unexpected sandbox state %v", secret.Data)
+	}
+	if _, err := k.Client.CoreV1().ConfigMaps("other-sandbox").Get("id-state", metav1.GetOptions{}); err == nil {
+		t.Fatal("state should not be copied into a configmap")
+	}
+	if err := s.DeleteState("id-state"); err != nil {
+		t.Fatal(err)
+	}
+	if present, _ := s.StateIsPresent("id-state"); present {
+		t.Fatal("expected the state to be deleted")
+	}
+}
@@ -135,14 +135,14 @@ func TestState(t *testing.T) {
 					return false
 				}
 
-				tomap, err := k.Client.CoreV1().ConfigMaps("toNS").Get(
+				tosecret, err := k.Client.CoreV1().Secrets("toNS").Get(
 					"to", metav1.GetOptions{})
 				if err != nil {
 					t.Fatalf("state is not present: %v", err)
 					return false
 				}
-				expectedMap := map[string]string{"fields": `{"db": "from"}`}
-				return reflect.DeepEqual(expectedMap, tomap.Data)
+				expectedMap := map[string][]byte{"fields": []byte(`{"db": "from"}`)}
+				return reflect.DeepEqual(expectedMap, tosecret.Data)
 			},
 		},
 		{
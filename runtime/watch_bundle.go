@@ -98,12 +98,25 @@ func defaultWatchRunningBundle(podName string, namespace string, updateFunc Upda
 			if errorPullingImage(podStatus.ContainerStatuses) {
 				return ErrorPodPullErr
 			}
+			// a runtime 3 bundle describes why it failed in its result
+			result, err := readBundleResult(podName, namespace)
+			if err == nil && result != nil && result.Error != nil {
+				return applyBundleResult(podName, result, updateFunc)
+			}
 			return translateExitStatus(podName, podStatus)
 		case apiv1.PodSucceeded:
 			w.Stop()
 			// Check for dashboard_url
 			dashURL := pod.Annotations["apb_dashboard_url"]
 			updateFunc("", dashURL)
+			result, err := readBundleResult(podName, namespace)
+			if err != nil {
+				log.Warningf("unable to read the result of pod [ %s ] - %v", podName, err)
+			} else if result != nil {
+				if err := applyBundleResult(podName, result, updateFunc); err != nil {
+					return err
+				}
+			}
 			log.Debugf("Pod [ %s ] completed", podName)
 			return nil
 		default:
@@ -123,11 +136,9 @@ func errorPullingImage(conds []apiv1.ContainerStatus) bool {
 		log.Warningf("unable to get container status for APB pod")
 		return false
 	}
-	// We should expect only a single container for our APB pod.
-	// If this assumption changes then we may need to update this code.
 	// Basis for the image strings is here:
 	// https://github.com/kubernetes/kubernetes/blob/886e04f1fffbb04faf8a9f9ee141143b2684ae68/pkg/kubelet/images/types.go#L27
-	status := conds[0].State.Waiting
+	status := bundleContainerStatus(conds).State.Waiting
 	if status == nil {
 		return false
 	}
@@ -148,7 +159,7 @@ func translateExitStatus(podName string, podStatus apiv1.PodStatus) error {
 		return fmt.Errorf("Pod [ %s ] failed - Unable to determine exit code - %v", podName, podStatus.Message)
 	}
 
-	status := conds[0].State.Terminated
+	status := bundleContainerStatus(conds).State.Terminated
 	if status == nil {
 		return fmt.Errorf("Pod [ %s ] failed. Unable to determine status - %v", podName, podStatus.Message)
 	}
@@ -169,3 +180,14 @@ func translateExitStatus(podName string, podStatus apiv1.PodStatus) error {
 	log.Warningf("Pod was marked as failed but exit code was 0 - %v", status.Message)
 	return nil
 }
+
+// bundleContainerStatus - returns the status of the bundle container, the pod
+// has a second container when the state is mounted writable.
+func bundleContainerStatus(conds []apiv1.ContainerStatus) apiv1.ContainerStatus {
+	for _, c := range conds {
+		if c.Name == BundleContainerName {
+			return c
+		}
+	}
+	return conds[0]
+}
@@ -174,6 +174,30 @@ func TestWatchPod(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			Name: "should get error when a runtime 3 bundle succeeds with an error result",
+			PodClient: func() (*fake.Clientset, *watch.FakeWatcher) {
+				kfake := fake.NewSimpleClientset(&core1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "test"},
+					Data: map[string][]byte{
+						BundleResultKey: []byte(`{"warnings": ["slow"], "error": {"message": "quota exceeded", "reason": "Quota"}}`),
+					},
+				})
+				podWatch := watch.NewFake()
+				kfake.PrependWatchReactor("pods", ktesting.DefaultWatchReactor(podWatch, nil))
+				return kfake, podWatch
+			},
+			UpdatePodStates: func(watcher *watch.FakeWatcher) {
+				podStates := []*core1.Pod{{
+					ObjectMeta: metav1.ObjectMeta{Name: "test"},
+					Status: core1.PodStatus{
+						Phase: core1.PodSucceeded,
+					},
+				}}
+				podStateUpdater(watcher, podStates)
+			},
+			ExpectError: true,
+		},
 	}
 
 	for _, tc := range cases {